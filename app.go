@@ -36,6 +36,7 @@ type AppConfig struct {
 	AppArgs  []string
 	AppFlags *Flags
 	Params   map[AppParam]interface{}
+	Clock    Clock
 	Debug    bool
 	Verbose  bool
 }
@@ -44,6 +45,7 @@ type AppConfig struct {
 type AppInstance struct {
 	AppFlags   *Flags
 	Logger     Logger
+	Clock      Clock
 	Hardware   Hardware
 	Display    Display
 	Graphics   SurfaceManager
@@ -57,6 +59,7 @@ type AppInstance struct {
 	SPI        SPI
 	PWM        PWM
 	LIRC       LIRC
+	KeyMapper  KeyMapper
 	ClientPool RPCClientPool
 	debug      bool
 	verbose    bool
@@ -211,6 +214,11 @@ func NewAppInstance(config AppConfig) (*AppInstance, error) {
 	this.verbose = config.Verbose
 	this.AppFlags = config.AppFlags
 
+	// Set the clock, which can be replaced with a fake clock in tests
+	if this.Clock = config.Clock; this.Clock == nil {
+		this.Clock = SystemClock
+	}
+
 	// Set up signalling
 	this.sigchan = make(chan os.Signal, 1)
 	signal.Notify(this.sigchan, syscall.SIGTERM, syscall.SIGINT)
@@ -223,6 +231,7 @@ func NewAppInstance(config AppConfig) (*AppInstance, error) {
 
 	// Create module instances
 	var once sync.Once
+	instances := make([]*AppInstance, 0, len(config.Modules))
 	for _, module := range config.Modules {
 		// Report open (once after logger module is created)
 		if this.Logger != nil {
@@ -234,7 +243,9 @@ func NewAppInstance(config AppConfig) (*AppInstance, error) {
 			if this.Logger != nil {
 				this.Logger.Debug2("module.New{ %v }", module)
 			}
-			if driver, err := module.New(this); err != nil {
+			instance := this.moduleInstance(module)
+			instances = append(instances, instance)
+			if driver, err := module.New(instance); err != nil {
 				return nil, err
 			} else if driver == nil {
 				return nil, fmt.Errorf("%v: New: return nil", module.Name)
@@ -247,6 +258,11 @@ func NewAppInstance(config AppConfig) (*AppInstance, error) {
 		}
 	}
 
+	// Modules which keep their instance see modules created after them
+	for _, instance := range instances {
+		this.updateModuleInstance(instance)
+	}
+
 	// report Open() again if it's not been done yet
 	once.Do(func() {
 		this.Logger.Debug("gopi.AppInstance.Open()")
@@ -446,11 +462,15 @@ func (this *AppInstance) WaitForSignal() {
 // WaitForSignalOrTimeout blocks until a signal is caught or
 // timeout occurs and return true if the signal is caught
 func (this *AppInstance) WaitForSignalOrTimeout(timeout time.Duration) bool {
+	expired := make(chan struct{})
+	timer := this.Clock.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+
 	select {
 	case s := <-this.sigchan:
 		this.Logger.Debug2("gopi.AppInstance.WaitForSignalOrTimeout: %v", s)
 		return true
-	case <-time.After(timeout):
+	case <-expired:
 		return false
 	}
 }
@@ -502,6 +522,7 @@ func (this *AppInstance) Close() error {
 	this.SPI = nil
 	this.PWM = nil
 	this.LIRC = nil
+	this.KeyMapper = nil
 	this.ClientPool = nil
 
 	// Return success
@@ -565,6 +586,48 @@ func inModules(modules []*Module, other *Module) bool {
 	return false
 }
 
+// moduleInstance returns the application instance passed to a module when
+// it is created, which has a logger named after the module. The shared
+// instance is not changed, and background tasks are not shared
+func (this *AppInstance) moduleInstance(module *Module) *AppInstance {
+	instance := new(AppInstance)
+	instance.Logger = this.Logger
+	if logger, ok := this.Logger.(StructuredLogger); ok {
+		instance.Logger = logger.Named(module.Identifier())
+	}
+	this.updateModuleInstance(instance)
+	return instance
+}
+
+// updateModuleInstance sets the modules in an instance passed to a module
+// from the shared instance, except for the logger
+func (this *AppInstance) updateModuleInstance(instance *AppInstance) {
+	instance.AppFlags = this.AppFlags
+	instance.Clock = this.Clock
+	instance.Hardware = this.Hardware
+	instance.Display = this.Display
+	instance.Graphics = this.Graphics
+	instance.Sprites = this.Sprites
+	instance.Input = this.Input
+	instance.Fonts = this.Fonts
+	instance.Layout = this.Layout
+	instance.Timer = this.Timer
+	instance.GPIO = this.GPIO
+	instance.I2C = this.I2C
+	instance.SPI = this.SPI
+	instance.PWM = this.PWM
+	instance.LIRC = this.LIRC
+	instance.KeyMapper = this.KeyMapper
+	instance.ClientPool = this.ClientPool
+	instance.debug = this.debug
+	instance.verbose = this.verbose
+	instance.sigchan = this.sigchan
+	instance.modules = this.modules
+	instance.byname = this.byname
+	instance.bytype = this.bytype
+	instance.byorder = this.byorder
+}
+
 func (this *AppInstance) setModuleInstance(module *Module, driver Driver) error {
 	var ok bool
 
@@ -647,6 +710,10 @@ func (this *AppInstance) setModuleInstance(module *Module, driver Driver) error
 		if this.Input, ok = driver.(InputManager); !ok {
 			return fmt.Errorf("Module %v cannot be cast to gopi.InputManager", module)
 		}
+	case MODULE_TYPE_KEYMAP:
+		if this.KeyMapper, ok = driver.(KeyMapper); !ok {
+			return fmt.Errorf("Module %v cannot be cast to gopi.KeyMapper", module)
+		}
 	case MODULE_TYPE_CLIENTPOOL:
 		if this.ClientPool, ok = driver.(RPCClientPool); !ok {
 			return fmt.Errorf("Module %v cannot be cast to gopi.RPCClientPool", module)
@@ -0,0 +1,85 @@
+package gopi_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+	_ "github.com/djthorpe/gopi/sys/timer"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// NAMED LOGGERS
+
+func TestCreateApp_Logger_000(t *testing.T) {
+	// Modules are created with a named logger, which has a level
+	// set from the -log.level flag
+	var named gopi.Logger
+	gopi.RegisterModule(gopi.Module{
+		Name: "test/named",
+		Type: gopi.MODULE_TYPE_OTHER,
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			named = app.Logger
+			return new(namedDriver), nil
+		},
+	})
+	config := gopi.NewAppConfig("test/named")
+	config.AppArgs = []string{"-log.level", "warn,test/named=debug2"}
+	app, err := gopi.NewAppInstance(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	module := gopi.ModuleByName("test/named")
+	if named == nil || named == app.Logger {
+		t.Fatal("Expected named logger")
+	} else if driver, ok := named.(logger.Driver); ok == false {
+		t.Fatal("Expected logger.Driver")
+	} else if driver.Level() != logger.LOG_DEBUG2 {
+		t.Error("Unexpected level", driver.Level())
+	} else if strings.Contains(fmt.Sprint(driver), module.Identifier()) == false {
+		t.Error("Unexpected name", driver)
+	} else if root := app.Logger.(logger.Driver); root.Level() != logger.LOG_WARN {
+		t.Error("Unexpected level", root.Level())
+	}
+}
+
+func TestCreateApp_Logger_001(t *testing.T) {
+	// Modules which keep their instance see modules which are
+	// created after them once the application is created
+	var instance *gopi.AppInstance
+	gopi.RegisterModule(gopi.Module{
+		Name: "test/early",
+		Type: gopi.MODULE_TYPE_OTHER,
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			instance = app
+			return new(namedDriver), nil
+		},
+	})
+	config := gopi.NewAppConfig("test/early", "sys/timer")
+	app, err := gopi.NewAppInstance(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	if instance == nil || instance == app {
+		t.Fatal("Expected module instance")
+	} else if app.Timer == nil || instance.Timer != app.Timer {
+		t.Error("Expected timer, got", instance.Timer)
+	} else if instance.Logger == app.Logger {
+		t.Error("Expected named logger")
+	} else if instance.ModuleInstance("sys/timer") != app.Timer {
+		t.Error("Expected timer module")
+	}
+}
+
+type namedDriver struct{}
+
+func (this *namedDriver) Close() error {
+	return nil
+}
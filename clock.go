@@ -0,0 +1,58 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gopi
+
+import (
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// Clock returns the current time and calls functions after a duration,
+// so that time can be replaced with a fake clock in tests
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// AfterFunc calls a function after a duration, and returns a timer
+	// which can stop or reset the call
+	AfterFunc(duration time.Duration, f func()) ClockTimer
+}
+
+// ClockTimer is returned by a clock to stop or reset a function call
+type ClockTimer interface {
+	// Stop the call, and return false if it has already been stopped
+	// or the function called
+	Stop() bool
+
+	// Reset the call to happen after a duration, and return false if
+	// it had been stopped or the function called
+	Reset(duration time.Duration) bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SYSTEM CLOCK
+
+// systemClock uses the time package, calling functions in their
+// own goroutine
+type systemClock struct{}
+
+var (
+	// SystemClock is the clock used when no other clock is set
+	SystemClock Clock = systemClock{}
+)
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(duration time.Duration, f func()) ClockTimer {
+	return time.AfterFunc(duration, f)
+}
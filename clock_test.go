@@ -0,0 +1,50 @@
+package gopi_test
+
+import (
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/clock"
+)
+
+func TestClock_000(t *testing.T) {
+	// The system clock calls a function after a duration
+	called := make(chan struct{})
+	gopi.SystemClock.AfterFunc(time.Millisecond, func() { close(called) })
+	select {
+	case <-called:
+		break
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for function")
+	}
+}
+
+func TestClock_001(t *testing.T) {
+	// WaitForSignalOrTimeout times out with a fake clock
+	fake := clock.NewFake(time.Now())
+	config := gopi.NewAppConfig()
+	config.Clock = fake
+	app, err := gopi.NewAppInstance(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	result := make(chan bool)
+	go func() {
+		result <- app.WaitForSignalOrTimeout(time.Hour)
+	}()
+	for {
+		select {
+		case signal := <-result:
+			if signal {
+				t.Error("Expected timeout")
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			fake.Advance(time.Minute)
+		}
+	}
+}
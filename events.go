@@ -26,6 +26,30 @@ type Publisher interface {
 	Unsubscribe(<-chan Event)
 }
 
+// PublisherWithOptions is implemented by publishers which can buffer
+// events for each subscriber, and drop or disconnect when the buffer
+// is full rather than blocking
+type PublisherWithOptions interface {
+	Publisher
+
+	// Subscribe to events emitted with a buffer size and overflow policy,
+	// or return ErrBadParameter for an invalid policy
+	SubscribeWithOptions(options SubscribeOptions) (<-chan Event, error)
+
+	// Dropped returns the number of events dropped for a subscriber
+	Dropped(<-chan Event) uint
+}
+
+// SubscribeOptions sets the buffer size and overflow policy for a
+// subscriber. The zero value is an unbuffered subscriber which blocks
+type SubscribeOptions struct {
+	Buffer   uint              // Number of events buffered
+	Overflow SubscribeOverflow // Policy when the buffer is full
+}
+
+// SubscribeOverflow is the policy when a subscriber buffer is full
+type SubscribeOverflow uint
+
 // Event is a generic event which is emitted through a channel
 type Event interface {
 	// Source of the event
@@ -71,6 +95,14 @@ type TimerEvent interface {
 	// The number of fires for the timer event
 	Counter() uint
 
+	// The attempt number, which is the same as the counter except
+	// for backoff timers which have been reset
+	Attempt() uint
+
+	// The delay until the timer fires next, or zero if the timer
+	// does not fire again
+	Delay() time.Duration
+
 	// Cancel the timer which fired this event
 	Cancel()
 }
@@ -118,3 +150,39 @@ type RPCEvent interface {
 	// Service Record
 	ServiceRecord() RPCServiceRecord
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Emit blocks until the subscriber has space for the event
+	SUBSCRIBE_OVERFLOW_BLOCK SubscribeOverflow = iota
+
+	// The oldest buffered event is dropped to make space
+	SUBSCRIBE_OVERFLOW_DROP_OLDEST
+
+	// The event being emitted is dropped
+	SUBSCRIBE_OVERFLOW_DROP_NEWEST
+
+	// The subscriber channel is closed, and the number of dropped
+	// events is kept until unsubscribed
+	SUBSCRIBE_OVERFLOW_DISCONNECT
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (o SubscribeOverflow) String() string {
+	switch o {
+	case SUBSCRIBE_OVERFLOW_BLOCK:
+		return "SUBSCRIBE_OVERFLOW_BLOCK"
+	case SUBSCRIBE_OVERFLOW_DROP_OLDEST:
+		return "SUBSCRIBE_OVERFLOW_DROP_OLDEST"
+	case SUBSCRIBE_OVERFLOW_DROP_NEWEST:
+		return "SUBSCRIBE_OVERFLOW_DROP_NEWEST"
+	case SUBSCRIBE_OVERFLOW_DISCONNECT:
+		return "SUBSCRIBE_OVERFLOW_DISCONNECT"
+	default:
+		return "[?? Invalid SubscribeOverflow value]"
+	}
+}
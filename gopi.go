@@ -46,9 +46,31 @@ type Logger interface {
 	IsDebug() bool
 }
 
+// Structured logging interface, which is implemented by loggers
+// which output key and value pairs and return named loggers
+type StructuredLogger interface {
+	Logger
+
+	// Output structured logging messages, with alternating
+	// key and value pairs
+	Errorw(msg string, kv ...interface{}) error
+	Warnw(msg string, kv ...interface{})
+	Infow(msg string, kv ...interface{})
+	Debugw(msg string, kv ...interface{})
+
+	// Return a logger which adds key and value pairs to
+	// every message
+	With(kv ...interface{}) StructuredLogger
+
+	// Return a named logger, which tags messages with the name
+	// and can have a different level
+	Named(name string) StructuredLogger
+}
+
 // Concrete basic logger
 type logger struct {
 	sync.Mutex
+	fields string
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -108,6 +130,47 @@ func (this *logger) Debug2(format string, v ...interface{}) {
 	defer this.Unlock()
 	log.Printf("Debug: %v", fmt.Sprintf(format, v...))
 }
+func (this *logger) Errorw(msg string, kv ...interface{}) error {
+	this.Lock()
+	defer this.Unlock()
+	err := fmt.Errorf("%v", msg)
+	log.Printf("Error: %v%v%v", msg, this.fields, kvString(kv))
+	return err
+}
+func (this *logger) Warnw(msg string, kv ...interface{}) {
+	this.Lock()
+	defer this.Unlock()
+	log.Printf("Warn: %v%v%v", msg, this.fields, kvString(kv))
+}
+func (this *logger) Infow(msg string, kv ...interface{}) {
+	this.Lock()
+	defer this.Unlock()
+	log.Printf("Info: %v%v%v", msg, this.fields, kvString(kv))
+}
+func (this *logger) Debugw(msg string, kv ...interface{}) {
+	this.Lock()
+	defer this.Unlock()
+	log.Printf("Debug: %v%v%v", msg, this.fields, kvString(kv))
+}
+func (this *logger) With(kv ...interface{}) StructuredLogger {
+	return &logger{fields: this.fields + kvString(kv)}
+}
+func (this *logger) Named(name string) StructuredLogger {
+	return &logger{fields: this.fields + kvString([]interface{}{"name", name})}
+}
 func (this *logger) IsDebug() bool {
 	return true
 }
+
+// kvString returns key and value pairs as " key=value" strings
+func kvString(kv []interface{}) string {
+	str := ""
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 < len(kv) {
+			str += fmt.Sprintf(" %v=%v", kv[i], kv[i+1])
+		} else {
+			str += fmt.Sprintf(" %v=", kv[i])
+		}
+	}
+	return str
+}
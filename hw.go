@@ -195,7 +195,7 @@ type LIRC interface {
 	SetSendCarrierHz(value uint32) error
 	SetSendDutyCycle(value uint32) error
 
-	// Send Pulse Mode, values are in milliseconds
+	// Send Pulse Mode, values are in microseconds
 	PulseSend(values []uint32) error
 }
 
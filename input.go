@@ -32,6 +32,23 @@ type InputManager interface {
 	AddDevice(device InputDevice) error
 }
 
+// KeyMapper maps scancodes from input devices to key codes, so
+// that remote controls and non-standard keyboards can produce
+// standard key events
+type KeyMapper interface {
+	Driver
+
+	// Lookup returns the key code for a device name and scancode,
+	// or KEYCODE_NONE if there is no mapping
+	Lookup(device string, scancode uint32) KeyCode
+
+	// Learn maps a device name and scancode to a key code
+	Learn(device string, scancode uint32, keycode KeyCode) error
+
+	// Forget removes the mapping for a device name and scancode
+	Forget(device string, scancode uint32) error
+}
+
 type InputDevice interface {
 	Driver
 	Publisher
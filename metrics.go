@@ -9,6 +9,7 @@
 package gopi
 
 import (
+	"math"
 	"time"
 )
 
@@ -45,6 +46,21 @@ type Metric interface {
 	FloatValue() float64
 }
 
+// MetricWithStats is implemented by metrics which keep the values
+// recorded over the metric rate period
+type MetricWithStats interface {
+	Metric
+
+	// Return the minimum, maximum and mean values recorded over
+	// the metric rate period, or zero if no values were recorded
+	Min() float64
+	Max() float64
+	Mean() float64
+
+	// Return the number of values recorded over the metric rate period
+	Count() uint
+}
+
 // Metrics returns various metrics for host and
 // custom metrics
 type Metrics interface {
@@ -79,11 +95,117 @@ const (
 )
 
 const (
-	METRIC_TYPE_NONE    MetricType = iota
-	METRIC_TYPE_PURE               // Pure number
-	METRIC_TYPE_CELCIUS            // Temperature
+	METRIC_TYPE_NONE     MetricType = iota
+	METRIC_TYPE_PURE                // Pure number
+	METRIC_TYPE_CELCIUS             // Temperature in degrees Celsius
+	METRIC_TYPE_HUMIDITY            // Relative humidity in percent (0-100)
+	METRIC_TYPE_PRESSURE            // Pressure in Pascals
+	METRIC_TYPE_VOLTAGE             // Voltage in Volts
+	METRIC_TYPE_CURRENT             // Current in Amperes
+	METRIC_TYPE_LUX                 // Illuminance in Lux
+	METRIC_TYPE_RPM                 // Rotational speed in revolutions per minute
+	METRIC_TYPE_BYTES               // Size in bytes
+	METRIC_TYPE_PERCENT             // Percentage
 )
 
+const (
+	// Absolute zero in degrees Celsius
+	METRIC_CELCIUS_ABSOLUTE_ZERO = -273.15
+)
+
+/////////////////////////////////////////////////////////////////////
+// METRIC TYPE METHODS
+
+// Unit returns the canonical unit for values of a metric type, or
+// an empty string for a pure number
+func (t MetricType) Unit() string {
+	switch t {
+	case METRIC_TYPE_CELCIUS:
+		return "°C"
+	case METRIC_TYPE_HUMIDITY:
+		return "%RH"
+	case METRIC_TYPE_PRESSURE:
+		return "Pa"
+	case METRIC_TYPE_VOLTAGE:
+		return "V"
+	case METRIC_TYPE_CURRENT:
+		return "A"
+	case METRIC_TYPE_LUX:
+		return "lx"
+	case METRIC_TYPE_RPM:
+		return "rpm"
+	case METRIC_TYPE_BYTES:
+		return "B"
+	case METRIC_TYPE_PERCENT:
+		return "%"
+	default:
+		return ""
+	}
+}
+
+// Validate returns ErrBadParameter if a value can't be recorded for
+// a metric type, for example a negative number of bytes or a temperature
+// below absolute zero
+func (t MetricType) Validate(value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return ErrBadParameter
+	}
+	switch t {
+	case METRIC_TYPE_NONE:
+		return ErrBadParameter
+	case METRIC_TYPE_CELCIUS:
+		if value < METRIC_CELCIUS_ABSOLUTE_ZERO {
+			return ErrBadParameter
+		}
+	case METRIC_TYPE_HUMIDITY:
+		if value < 0 || value > 100 {
+			return ErrBadParameter
+		}
+	case METRIC_TYPE_PRESSURE, METRIC_TYPE_LUX, METRIC_TYPE_RPM, METRIC_TYPE_BYTES, METRIC_TYPE_PERCENT:
+		if value < 0 {
+			return ErrBadParameter
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// CONVERSIONS
+
+// CelciusToFahrenheit converts a temperature in degrees Celsius
+// to degrees Fahrenheit
+func CelciusToFahrenheit(value float64) float64 {
+	return value*9/5 + 32
+}
+
+// FahrenheitToCelcius converts a temperature in degrees Fahrenheit
+// to degrees Celsius
+func FahrenheitToCelcius(value float64) float64 {
+	return (value - 32) * 5 / 9
+}
+
+// CelciusToKelvin converts a temperature in degrees Celsius to Kelvin
+func CelciusToKelvin(value float64) float64 {
+	return value - METRIC_CELCIUS_ABSOLUTE_ZERO
+}
+
+// KelvinToCelcius converts a temperature in Kelvin to degrees Celsius
+func KelvinToCelcius(value float64) float64 {
+	return value + METRIC_CELCIUS_ABSOLUTE_ZERO
+}
+
+// PascalToHectopascal converts a pressure in Pascals to Hectopascals
+// (millibars)
+func PascalToHectopascal(value float64) float64 {
+	return value / 100
+}
+
+// HectopascalToPascal converts a pressure in Hectopascals (millibars)
+// to Pascals
+func HectopascalToPascal(value float64) float64 {
+	return value * 100
+}
+
 /////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -106,6 +228,22 @@ func (t MetricType) String() string {
 		return "METRIC_TYPE_PURE"
 	case METRIC_TYPE_CELCIUS:
 		return "METRIC_TYPE_CELCIUS"
+	case METRIC_TYPE_HUMIDITY:
+		return "METRIC_TYPE_HUMIDITY"
+	case METRIC_TYPE_PRESSURE:
+		return "METRIC_TYPE_PRESSURE"
+	case METRIC_TYPE_VOLTAGE:
+		return "METRIC_TYPE_VOLTAGE"
+	case METRIC_TYPE_CURRENT:
+		return "METRIC_TYPE_CURRENT"
+	case METRIC_TYPE_LUX:
+		return "METRIC_TYPE_LUX"
+	case METRIC_TYPE_RPM:
+		return "METRIC_TYPE_RPM"
+	case METRIC_TYPE_BYTES:
+		return "METRIC_TYPE_BYTES"
+	case METRIC_TYPE_PERCENT:
+		return "METRIC_TYPE_PERCENT"
 	default:
 		return "[?? Invalid MetricType value]"
 	}
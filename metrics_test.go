@@ -0,0 +1,76 @@
+package gopi_test
+
+import (
+	"math"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// METRIC TYPES
+
+func TestMetrics_000(t *testing.T) {
+	// Every metric type has a name, and all but pure numbers have a unit
+	for mt := gopi.METRIC_TYPE_PURE; mt <= gopi.METRIC_TYPE_PERCENT; mt++ {
+		if mt.String() == gopi.METRIC_TYPE_NONE.String() {
+			t.Error("Missing name for metric type", uint(mt))
+		} else if mt != gopi.METRIC_TYPE_PURE && mt.Unit() == "" {
+			t.Error("Missing unit for", mt)
+		}
+	}
+	if unit := gopi.METRIC_TYPE_PRESSURE.Unit(); unit != "Pa" {
+		t.Error("Unexpected unit", unit)
+	}
+}
+
+func TestMetrics_001(t *testing.T) {
+	// Values are validated per type
+	for _, test := range []struct {
+		t     gopi.MetricType
+		value float64
+		valid bool
+	}{
+		{gopi.METRIC_TYPE_NONE, 0, false},
+		{gopi.METRIC_TYPE_PURE, -10, true},
+		{gopi.METRIC_TYPE_PURE, math.NaN(), false},
+		{gopi.METRIC_TYPE_PURE, math.Inf(1), false},
+		{gopi.METRIC_TYPE_CELCIUS, -40, true},
+		{gopi.METRIC_TYPE_CELCIUS, -300, false},
+		{gopi.METRIC_TYPE_HUMIDITY, 45, true},
+		{gopi.METRIC_TYPE_HUMIDITY, 101, false},
+		{gopi.METRIC_TYPE_PRESSURE, -1, false},
+		{gopi.METRIC_TYPE_VOLTAGE, -5, true},
+		{gopi.METRIC_TYPE_CURRENT, -0.5, true},
+		{gopi.METRIC_TYPE_LUX, -1, false},
+		{gopi.METRIC_TYPE_RPM, 3000, true},
+		{gopi.METRIC_TYPE_BYTES, -1, false},
+		{gopi.METRIC_TYPE_BYTES, 1024, true},
+		{gopi.METRIC_TYPE_PERCENT, 150, true},
+		{gopi.METRIC_TYPE_PERCENT, -1, false},
+	} {
+		if err := test.t.Validate(test.value); test.valid && err != nil {
+			t.Error("Unexpected error for", test.t, test.value, err)
+		} else if test.valid == false && err != gopi.ErrBadParameter {
+			t.Error("Expected ErrBadParameter for", test.t, test.value)
+		}
+	}
+}
+
+func TestMetrics_002(t *testing.T) {
+	// Conversions
+	if f := gopi.CelciusToFahrenheit(100); f != 212 {
+		t.Error("Unexpected value", f)
+	} else if c := gopi.FahrenheitToCelcius(-40); c != -40 {
+		t.Error("Unexpected value", c)
+	} else if k := gopi.CelciusToKelvin(gopi.METRIC_CELCIUS_ABSOLUTE_ZERO); k != 0 {
+		t.Error("Unexpected value", k)
+	} else if c := gopi.KelvinToCelcius(273.15); c != 0 {
+		t.Error("Unexpected value", c)
+	} else if hpa := gopi.PascalToHectopascal(101325); hpa != 1013.25 {
+		t.Error("Unexpected value", hpa)
+	} else if pa := gopi.HectopascalToPascal(1013.25); pa != 101325 {
+		t.Error("Unexpected value", pa)
+	}
+}
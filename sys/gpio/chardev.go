@@ -0,0 +1,328 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type chardev struct {
+	path  string
+	dev   Device
+	name  string
+	label string
+	lines uint32
+	state map[gopi.GPIOPin]*line
+	emit  emitFunc
+
+	sync.Mutex
+}
+
+type line struct {
+	mode   gopi.GPIOMode
+	bias   uint32
+	edge   gopi.GPIOEdge
+	handle Line
+	events Line
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBAL VARIABLES
+
+var (
+	ErrNotOutput = errors.New("Pin is not an output")
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func openChardev(config GPIO, emit emitFunc) (*chardev, error) {
+	this := new(chardev)
+	this.path = filepath.Join(config.DevPath, fmt.Sprintf(GPIO_DEV_NAME, config.Chip))
+	this.state = make(map[gopi.GPIOPin]*line)
+	this.emit = emit
+
+	// Open the device and read the chip information
+	if config.Device != nil {
+		this.dev = config.Device
+	} else if dev, err := OpenDevice(config.DevPath, config.Chip); err != nil {
+		return nil, err
+	} else {
+		this.dev = dev
+	}
+	if name, label, lines, err := this.dev.ChipInfo(); err != nil {
+		this.dev.Close()
+		return nil, fmt.Errorf("%v: %v", this.path, err)
+	} else {
+		this.name = name
+		this.label = label
+		this.lines = lines
+	}
+
+	// Success
+	return this, nil
+}
+
+func (this *chardev) Close() error {
+	this.Lock()
+	defer this.Unlock()
+
+	// Release all the lines
+	for _, l := range this.state {
+		l.release()
+	}
+	this.state = nil
+
+	// Close the device
+	return this.dev.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKEND INTERFACE
+
+func (this *chardev) Pins() []gopi.GPIOPin {
+	pins := make([]gopi.GPIOPin, 0, this.lines)
+	for i := uint32(0); i < this.lines && i < uint32(gopi.GPIO_PIN_NONE); i++ {
+		pins = append(pins, gopi.GPIOPin(i))
+	}
+	return pins
+}
+
+func (this *chardev) ReadPin(pin gopi.GPIOPin) (gopi.GPIOState, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	l, err := this.line(pin)
+	if err != nil {
+		return gopi.GPIO_LOW, err
+	}
+	// Request the line if not already requested
+	if l.handle == nil && l.events == nil {
+		if err := this.request(pin, l, 0); err != nil {
+			return gopi.GPIO_LOW, err
+		}
+	}
+	fh := l.handle
+	if fh == nil {
+		fh = l.events
+	}
+	if value, err := fh.Value(); err != nil {
+		return gopi.GPIO_LOW, err
+	} else if value != 0 {
+		return gopi.GPIO_HIGH, nil
+	} else {
+		return gopi.GPIO_LOW, nil
+	}
+}
+
+func (this *chardev) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) error {
+	this.Lock()
+	defer this.Unlock()
+
+	l, err := this.line(pin)
+	if err != nil {
+		return err
+	} else if l.mode != gopi.GPIO_OUTPUT {
+		return ErrNotOutput
+	}
+	// Request the line with the state as default value, or set the value
+	if l.handle == nil {
+		return this.request(pin, l, uint8(state))
+	}
+	return l.handle.SetValue(uint8(state))
+}
+
+func (this *chardev) GetPinMode(pin gopi.GPIOPin) (gopi.GPIOMode, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if l, err := this.line(pin); err != nil {
+		return gopi.GPIO_NONE, err
+	} else {
+		return l.mode, nil
+	}
+}
+
+func (this *chardev) SetPinMode(pin gopi.GPIOPin, mode gopi.GPIOMode) error {
+	this.Lock()
+	defer this.Unlock()
+
+	l, err := this.line(pin)
+	if err != nil {
+		return err
+	} else if mode == gopi.GPIO_OUTPUT && l.edge != gopi.GPIO_EDGE_NONE {
+		return fmt.Errorf("Cannot set %v to output whilst watching", pin)
+	}
+	l.mode = mode
+	return this.request(pin, l, 0)
+}
+
+func (this *chardev) SetPullMode(pin gopi.GPIOPin, pull gopi.GPIOPull) error {
+	this.Lock()
+	defer this.Unlock()
+
+	l, err := this.line(pin)
+	if err != nil {
+		return err
+	}
+	switch pull {
+	case gopi.GPIO_PULL_OFF:
+		l.bias = GPIOHANDLE_REQUEST_BIAS_DISABLE
+	case gopi.GPIO_PULL_DOWN:
+		l.bias = GPIOHANDLE_REQUEST_BIAS_PULL_DN
+	case gopi.GPIO_PULL_UP:
+		l.bias = GPIOHANDLE_REQUEST_BIAS_PULL_UP
+	default:
+		return gopi.ErrBadParameter
+	}
+	// Bias flags were introduced in Linux 5.5 and older kernels
+	// reject them as invalid, in which case request the line without bias
+	if err := this.request(pin, l, 0); err == syscall.EINVAL {
+		l.bias = 0
+		if err := this.request(pin, l, 0); err != nil {
+			return err
+		}
+		return gopi.ErrNotImplemented
+	} else {
+		return err
+	}
+}
+
+func (this *chardev) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.Lock()
+	defer this.Unlock()
+
+	l, err := this.line(pin)
+	if err != nil {
+		return err
+	} else if edge != gopi.GPIO_EDGE_NONE && l.mode == gopi.GPIO_OUTPUT {
+		return fmt.Errorf("Cannot watch %v whilst output", pin)
+	}
+	l.edge = edge
+	return this.request(pin, l, 0)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *chardev) String() string {
+	return fmt.Sprintf("chardev=%v name=%v label=%v lines=%v", strconv.Quote(this.path), strconv.Quote(this.name), strconv.Quote(this.label), this.lines)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// line returns the state for a pin, reading the line information
+// from the chip when the pin is first used
+func (this *chardev) line(pin gopi.GPIOPin) (*line, error) {
+	if l, exists := this.state[pin]; exists {
+		return l, nil
+	} else if uint32(pin) >= this.lines {
+		return nil, gopi.ErrBadParameter
+	}
+	flags, err := this.dev.LineFlags(uint32(pin))
+	if err != nil {
+		return nil, err
+	}
+	l := &line{mode: gopi.GPIO_INPUT}
+	if flags&GPIOLINE_FLAG_IS_OUT != 0 {
+		l.mode = gopi.GPIO_OUTPUT
+	}
+	this.state[pin] = l
+	return l, nil
+}
+
+// request releases the line and then requests it again as either
+// a line handle or a line event, depending on the line state
+func (this *chardev) request(pin gopi.GPIOPin, l *line, value uint8) error {
+	l.release()
+
+	flags := l.bias
+	if l.mode == gopi.GPIO_OUTPUT {
+		flags |= GPIOHANDLE_REQUEST_OUTPUT
+	} else {
+		flags |= GPIOHANDLE_REQUEST_INPUT
+	}
+
+	// Request line handle
+	if l.edge == gopi.GPIO_EDGE_NONE {
+		if handle, err := this.dev.LineHandle(uint32(pin), flags, value); err != nil {
+			return err
+		} else {
+			l.handle = handle
+			return nil
+		}
+	}
+
+	// Request line event
+	var eventflags uint32
+	switch l.edge {
+	case gopi.GPIO_EDGE_RISING:
+		eventflags = GPIOEVENT_REQUEST_RISING_EDGE
+	case gopi.GPIO_EDGE_FALLING:
+		eventflags = GPIOEVENT_REQUEST_FALLING_EDGE
+	case gopi.GPIO_EDGE_BOTH:
+		eventflags = GPIOEVENT_REQUEST_RISING_EDGE | GPIOEVENT_REQUEST_FALLING_EDGE
+	}
+	if events, err := this.dev.LineEvent(uint32(pin), flags, eventflags); err != nil {
+		return err
+	} else {
+		l.events = events
+	}
+	go this.readEvents(pin, l.events)
+
+	// Success
+	return nil
+}
+
+// readEvents reads edge events until the line is closed
+func (this *chardev) readEvents(pin gopi.GPIOPin, fh Line) {
+	buf := make([]byte, GPIOEVENT_DATA_SIZE)
+	for {
+		if n, err := fh.Read(buf); err != nil {
+			return
+		} else if n != GPIOEVENT_DATA_SIZE {
+			continue
+		}
+		switch binary.LittleEndian.Uint32(buf[8:12]) {
+		case GPIOEVENT_EVENT_RISING_EDGE:
+			this.emit(pin, gopi.GPIO_EDGE_RISING)
+		case GPIOEVENT_EVENT_FALLING_EDGE:
+			this.emit(pin, gopi.GPIO_EDGE_FALLING)
+		}
+	}
+}
+
+// release closes the line handle and line events
+func (l *line) release() {
+	if l.handle != nil {
+		l.handle.Close()
+		l.handle = nil
+	}
+	if l.events != nil {
+		l.events.Close()
+		l.events = nil
+	}
+}
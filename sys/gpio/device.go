@@ -0,0 +1,258 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Device is the GPIO character device ioctl layer, which can be
+// replaced in order to test without /dev/gpiochipN
+type Device interface {
+	// Close the device
+	Close() error
+
+	// ChipInfo returns the chip name, label and number of lines
+	// (GPIO_GET_CHIPINFO_IOCTL)
+	ChipInfo() (string, string, uint32, error)
+
+	// LineFlags returns the flags for a line (GPIO_GET_LINEINFO_IOCTL)
+	LineFlags(offset uint32) (uint32, error)
+
+	// LineHandle requests a line with a default value for outputs
+	// (GPIO_GET_LINEHANDLE_IOCTL)
+	LineHandle(offset uint32, flags uint32, value uint8) (Line, error)
+
+	// LineEvent requests a line which reports edges
+	// (GPIO_GET_LINEEVENT_IOCTL)
+	LineEvent(offset uint32, flags uint32, eventflags uint32) (Line, error)
+}
+
+// Line is a requested line handle or line event
+type Line interface {
+	// Close releases the line, and ends reading events
+	Close() error
+
+	// Value returns the line value (GPIOHANDLE_GET_LINE_VALUES_IOCTL)
+	Value() (uint8, error)
+
+	// SetValue sets the line value (GPIOHANDLE_SET_LINE_VALUES_IOCTL)
+	SetValue(uint8) error
+
+	// Read event data for a line event, which is GPIOEVENT_DATA_SIZE
+	// bytes for each event
+	Read([]byte) (int, error)
+}
+
+type device struct {
+	fh *os.File
+}
+
+type lineFile struct {
+	*os.File
+}
+
+// Structures from linux/gpio.h (ABI version 1)
+type gpiochip_info struct {
+	name  [32]byte
+	label [32]byte
+	lines uint32
+}
+
+type gpioline_info struct {
+	line_offset uint32
+	flags       uint32
+	name        [32]byte
+	consumer    [32]byte
+}
+
+type gpiohandle_request struct {
+	lineoffsets    [GPIOHANDLES_MAX]uint32
+	flags          uint32
+	default_values [GPIOHANDLES_MAX]uint8
+	consumer_label [32]byte
+	lines          uint32
+	fd             int32
+}
+
+type gpiohandle_data struct {
+	values [GPIOHANDLES_MAX]uint8
+}
+
+type gpioevent_request struct {
+	lineoffset     uint32
+	handleflags    uint32
+	eventflags     uint32
+	consumer_label [32]byte
+	fd             int32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIOHANDLES_MAX      = 64
+	GPIO_CONSUMER_LABEL  = "gopi"
+	GPIOEVENT_DATA_SIZE  = 16
+	GPIOLINE_FLAG_IS_OUT = (1 << 1)
+	GPIO_DEV_NAME        = "gpiochip%v"
+)
+
+const (
+	GPIOHANDLE_REQUEST_INPUT        = (1 << 0)
+	GPIOHANDLE_REQUEST_OUTPUT       = (1 << 1)
+	GPIOHANDLE_REQUEST_BIAS_PULL_UP = (1 << 5)
+	GPIOHANDLE_REQUEST_BIAS_PULL_DN = (1 << 6)
+	GPIOHANDLE_REQUEST_BIAS_DISABLE = (1 << 7)
+)
+
+const (
+	GPIOEVENT_REQUEST_RISING_EDGE  = (1 << 0)
+	GPIOEVENT_REQUEST_FALLING_EDGE = (1 << 1)
+	GPIOEVENT_EVENT_RISING_EDGE    = 0x01
+	GPIOEVENT_EVENT_FALLING_EDGE   = 0x02
+)
+
+var (
+	GPIO_GET_CHIPINFO_IOCTL          = ioctlIOR(0xB4, 0x01, unsafe.Sizeof(gpiochip_info{}))
+	GPIO_GET_LINEINFO_IOCTL          = ioctlIOWR(0xB4, 0x02, unsafe.Sizeof(gpioline_info{}))
+	GPIO_GET_LINEHANDLE_IOCTL        = ioctlIOWR(0xB4, 0x03, unsafe.Sizeof(gpiohandle_request{}))
+	GPIO_GET_LINEEVENT_IOCTL         = ioctlIOWR(0xB4, 0x04, unsafe.Sizeof(gpioevent_request{}))
+	GPIOHANDLE_GET_LINE_VALUES_IOCTL = ioctlIOWR(0xB4, 0x08, unsafe.Sizeof(gpiohandle_data{}))
+	GPIOHANDLE_SET_LINE_VALUES_IOCTL = ioctlIOWR(0xB4, 0x09, unsafe.Sizeof(gpiohandle_data{}))
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// OpenDevice opens /dev/gpiochipN for a chip
+func OpenDevice(path string, chip uint) (Device, error) {
+	this := new(device)
+	if path == "" {
+		path = GPIO_DEV_PATH
+	}
+	if fh, err := os.OpenFile(filepath.Join(path, fmt.Sprintf(GPIO_DEV_NAME, chip)), os.O_RDWR|syscall.O_CLOEXEC, 0); err != nil {
+		return nil, err
+	} else {
+		this.fh = fh
+	}
+	return this, nil
+}
+
+func (this *device) Close() error {
+	return this.fh.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE INTERFACE
+
+func (this *device) ChipInfo() (string, string, uint32, error) {
+	var info gpiochip_info
+	if err := ioctl(this.fh.Fd(), GPIO_GET_CHIPINFO_IOCTL, unsafe.Pointer(&info)); err != nil {
+		return "", "", 0, err
+	} else {
+		return cstring(info.name[:]), cstring(info.label[:]), info.lines, nil
+	}
+}
+
+func (this *device) LineFlags(offset uint32) (uint32, error) {
+	info := gpioline_info{line_offset: offset}
+	if err := ioctl(this.fh.Fd(), GPIO_GET_LINEINFO_IOCTL, unsafe.Pointer(&info)); err != nil {
+		return 0, err
+	} else {
+		return info.flags, nil
+	}
+}
+
+func (this *device) LineHandle(offset uint32, flags uint32, value uint8) (Line, error) {
+	req := gpiohandle_request{flags: flags, lines: 1}
+	req.lineoffsets[0] = offset
+	req.default_values[0] = value
+	copy(req.consumer_label[:], GPIO_CONSUMER_LABEL)
+	if err := ioctl(this.fh.Fd(), GPIO_GET_LINEHANDLE_IOCTL, unsafe.Pointer(&req)); err != nil {
+		return nil, err
+	}
+	return &lineFile{os.NewFile(uintptr(req.fd), fmt.Sprintf("%v:%v", this.fh.Name(), offset))}, nil
+}
+
+func (this *device) LineEvent(offset uint32, flags uint32, eventflags uint32) (Line, error) {
+	req := gpioevent_request{lineoffset: offset, handleflags: flags, eventflags: eventflags}
+	copy(req.consumer_label[:], GPIO_CONSUMER_LABEL)
+	if err := ioctl(this.fh.Fd(), GPIO_GET_LINEEVENT_IOCTL, unsafe.Pointer(&req)); err != nil {
+		return nil, err
+	}
+	// Set non-blocking so that closing the file ends reading events
+	if err := syscall.SetNonblock(int(req.fd), true); err != nil {
+		syscall.Close(int(req.fd))
+		return nil, err
+	}
+	return &lineFile{os.NewFile(uintptr(req.fd), fmt.Sprintf("%v:%v", this.fh.Name(), offset))}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LINE INTERFACE
+
+func (this *lineFile) Value() (uint8, error) {
+	var data gpiohandle_data
+	if err := ioctl(this.Fd(), GPIOHANDLE_GET_LINE_VALUES_IOCTL, unsafe.Pointer(&data)); err != nil {
+		return 0, err
+	} else {
+		return data.values[0], nil
+	}
+}
+
+func (this *lineFile) SetValue(value uint8) error {
+	var data gpiohandle_data
+	data.values[0] = value
+	return ioctl(this.Fd(), GPIOHANDLE_SET_LINE_VALUES_IOCTL, unsafe.Pointer(&data))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IOCTL
+
+const (
+	_IOC_WRITE = 1
+	_IOC_READ  = 2
+)
+
+func ioctlIOR(t, nr, size uintptr) uintptr {
+	return (_IOC_READ << 30) | (size << 16) | (t << 8) | nr
+}
+
+func ioctlIOWR(t, nr, size uintptr) uintptr {
+	return ((_IOC_READ | _IOC_WRITE) << 30) | (size << 16) | (t << 8) | nr
+}
+
+func ioctl(fd, cmd uintptr, data unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, uintptr(data)); errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+func cstring(value []byte) string {
+	if i := strings.IndexByte(string(value), 0); i >= 0 {
+		return string(value[:i])
+	} else {
+		return string(value)
+	}
+}
@@ -0,0 +1,218 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// GPIO is the configuration for the Linux GPIO driver. The driver uses
+// the GPIO character device /dev/gpiochipN where available, and falls
+// back to the sysfs interface otherwise
+type GPIO struct {
+	Chip         uint          // GPIO chip number
+	DevPath      string        // Path to character devices (default: /dev)
+	Device       Device        // Character device, or nil to open /dev/gpiochipN
+	SysfsPath    string        // Path to sysfs interface (default: /sys/class/gpio)
+	PollInterval time.Duration // Sample interval for watched sysfs pins (default: 100ms)
+}
+
+type gpio struct {
+	log     gopi.Logger
+	backend backend
+
+	event.Publisher
+}
+
+// backend is implemented by the character device and sysfs
+// interfaces
+type backend interface {
+	Close() error
+	Pins() []gopi.GPIOPin
+	ReadPin(gopi.GPIOPin) (gopi.GPIOState, error)
+	WritePin(gopi.GPIOPin, gopi.GPIOState) error
+	GetPinMode(gopi.GPIOPin) (gopi.GPIOMode, error)
+	SetPinMode(gopi.GPIOPin, gopi.GPIOMode) error
+	SetPullMode(gopi.GPIOPin, gopi.GPIOPull) error
+	Watch(gopi.GPIOPin, gopi.GPIOEdge) error
+}
+
+// emitFunc is called by a backend when an edge is detected
+type emitFunc func(gopi.GPIOPin, gopi.GPIOEdge)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIO_DEV_PATH      = "/dev"
+	GPIO_SYSFS_PATH    = "/sys/class/gpio"
+	GPIO_POLL_INTERVAL = 100 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the GPIO driver
+func (config GPIO) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.gpio.Open{ chip=%v }", config.Chip)
+
+	this := new(gpio)
+	this.log = log
+
+	if config.DevPath == "" {
+		config.DevPath = GPIO_DEV_PATH
+	}
+	if config.SysfsPath == "" {
+		config.SysfsPath = GPIO_SYSFS_PATH
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = GPIO_POLL_INTERVAL
+	}
+
+	// Use the character device, or else fall back to sysfs
+	if backend, err := openChardev(config, this.emit); err == nil {
+		this.backend = backend
+	} else if config.Device != nil {
+		return nil, err
+	} else if backend, err_ := openSysfs(config, this.emit); err_ == nil {
+		log.Debug("sys.gpio.Open: falling back to sysfs: %v", err)
+		this.backend = backend
+	} else {
+		return nil, fmt.Errorf("%v (sysfs: %v)", err, err_)
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the GPIO driver
+func (this *gpio) Close() error {
+	this.log.Debug("sys.gpio.Close{ }")
+
+	// Unsubscribe before closing the backend, which ends emitting to
+	// subscribers which are not receiving, then stop watching
+	this.Publisher.Close()
+	err := this.backend.Close()
+
+	// Blank out instance variables
+	this.backend = nil
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - PINS
+
+// NumberOfPhysicalPins returns zero as nothing is known about
+// the physical layout of the chip
+func (this *gpio) NumberOfPhysicalPins() uint {
+	return 0
+}
+
+// Pins returns the logical pins, which are the line offsets on the chip
+func (this *gpio) Pins() []gopi.GPIOPin {
+	return this.backend.Pins()
+}
+
+// PhysicalPin returns GPIO_PIN_NONE as nothing is known about
+// the physical layout of the chip
+func (this *gpio) PhysicalPin(uint) gopi.GPIOPin {
+	return gopi.GPIO_PIN_NONE
+}
+
+// PhysicalPinForPin returns zero as nothing is known about
+// the physical layout of the chip
+func (this *gpio) PhysicalPinForPin(gopi.GPIOPin) uint {
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - READ AND WRITE
+
+// ReadPin returns the state of a pin, or GPIO_LOW on error
+func (this *gpio) ReadPin(pin gopi.GPIOPin) gopi.GPIOState {
+	if state, err := this.backend.ReadPin(pin); err != nil {
+		this.log.Error("sys.gpio.ReadPin: %v: %v", pin, err)
+		return gopi.GPIO_LOW
+	} else {
+		return state
+	}
+}
+
+// WritePin sets the state of an output pin
+func (this *gpio) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) {
+	this.log.Debug2("sys.gpio.WritePin{ pin=%v state=%v }", pin, state)
+	if err := this.backend.WritePin(pin, state); err != nil {
+		this.log.Error("sys.gpio.WritePin: %v: %v", pin, err)
+	}
+}
+
+// GetPinMode returns GPIO_INPUT or GPIO_OUTPUT, or GPIO_NONE on error
+func (this *gpio) GetPinMode(pin gopi.GPIOPin) gopi.GPIOMode {
+	if mode, err := this.backend.GetPinMode(pin); err != nil {
+		this.log.Error("sys.gpio.GetPinMode: %v: %v", pin, err)
+		return gopi.GPIO_NONE
+	} else {
+		return mode
+	}
+}
+
+// SetPinMode sets the pin to GPIO_INPUT or GPIO_OUTPUT. Alternate
+// functions cannot be set through the kernel interfaces
+func (this *gpio) SetPinMode(pin gopi.GPIOPin, mode gopi.GPIOMode) {
+	this.log.Debug2("sys.gpio.SetPinMode{ pin=%v mode=%v }", pin, mode)
+	if mode != gopi.GPIO_INPUT && mode != gopi.GPIO_OUTPUT {
+		this.log.Error("sys.gpio.SetPinMode: %v: %v", pin, gopi.ErrNotImplemented)
+	} else if err := this.backend.SetPinMode(pin, mode); err != nil {
+		this.log.Error("sys.gpio.SetPinMode: %v: %v", pin, err)
+	}
+}
+
+// SetPullMode sets the pin bias, which requires the character device
+// interface and returns ErrNotImplemented otherwise
+func (this *gpio) SetPullMode(pin gopi.GPIOPin, pull gopi.GPIOPull) error {
+	this.log.Debug2("sys.gpio.SetPullMode{ pin=%v pull=%v }", pin, pull)
+	return this.backend.SetPullMode(pin, pull)
+}
+
+// Watch starts watching a pin for edges, or stops watching when
+// GPIO_EDGE_NONE is passed. Edges are emitted as gopi.GPIOEvent
+func (this *gpio) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.log.Debug2("sys.gpio.Watch{ pin=%v edge=%v }", pin, edge)
+	if edge > gopi.GPIO_EDGE_BOTH {
+		return gopi.ErrBadParameter
+	}
+	return this.backend.Watch(pin, edge)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *gpio) String() string {
+	return fmt.Sprintf("<sys.gpio>{ %v }", this.backend)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *gpio) emit(pin gopi.GPIOPin, edge gopi.GPIOEdge) {
+	this.Emit(event.NewGPIOEvent(this, pin, edge, time.Now()))
+}
@@ -0,0 +1,591 @@
+//go:build linux
+// +build linux
+
+package gpio_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/gpio"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN DRIVER
+
+func TestGPIO_000(t *testing.T) {
+	root := fakeChip(t, 0, 8)
+	defer os.RemoveAll(root)
+
+	if driver, err := openGPIO(root); err != nil {
+		t.Fatal(err)
+	} else {
+		defer driver.Close()
+		if pins := driver.Pins(); len(pins) != 8 {
+			t.Error("Expected 8 pins, got", pins)
+		}
+		if pin := driver.PhysicalPin(1); pin != gopi.GPIO_PIN_NONE {
+			t.Error("Expected GPIO_PIN_NONE, got", pin)
+		}
+		t.Log(driver)
+	}
+}
+
+func TestGPIO_001(t *testing.T) {
+	// Chips are chosen by index in order of base
+	root := fakeChip(t, 504, 8)
+	defer os.RemoveAll(root)
+
+	if driver, err := openGPIO(root); err != nil {
+		t.Fatal(err)
+	} else {
+		defer driver.Close()
+		driver.SetPinMode(3, gopi.GPIO_OUTPUT)
+		if direction := readFile(t, root, "gpio507/direction"); direction != "out" {
+			t.Error("Expected out, got", direction)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE
+
+func TestGPIO_002(t *testing.T) {
+	root := fakeChip(t, 0, 8)
+	defer os.RemoveAll(root)
+
+	driver, err := openGPIO(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	// Writing to an input pin doesn't change the value
+	driver.WritePin(1, gopi.GPIO_HIGH)
+	if value := readFile(t, root, "gpio1/value"); value != "0" {
+		t.Error("Expected 0, got", value)
+	}
+
+	// Set as output, then write
+	driver.SetPinMode(1, gopi.GPIO_OUTPUT)
+	if mode := driver.GetPinMode(1); mode != gopi.GPIO_OUTPUT {
+		t.Error("Expected GPIO_OUTPUT, got", mode)
+	}
+	driver.WritePin(1, gopi.GPIO_HIGH)
+	if value := readFile(t, root, "gpio1/value"); value != "1" {
+		t.Error("Expected 1, got", value)
+	}
+	if state := driver.ReadPin(1); state != gopi.GPIO_HIGH {
+		t.Error("Expected GPIO_HIGH, got", state)
+	}
+	driver.WritePin(1, gopi.GPIO_LOW)
+	if state := driver.ReadPin(1); state != gopi.GPIO_LOW {
+		t.Error("Expected GPIO_LOW, got", state)
+	}
+}
+
+func TestGPIO_003(t *testing.T) {
+	root := fakeChip(t, 0, 8)
+	defer os.RemoveAll(root)
+
+	driver, err := openGPIO(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	// Pull modes are not supported through sysfs
+	if err := driver.SetPullMode(1, gopi.GPIO_PULL_UP); err != gopi.ErrNotImplemented {
+		t.Error("Expected ErrNotImplemented, got", err)
+	}
+	// Pins out of range
+	if err := driver.Watch(8, gopi.GPIO_EDGE_BOTH); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WATCH
+
+func TestGPIO_004(t *testing.T) {
+	root := fakeChip(t, 0, 8)
+	defer os.RemoveAll(root)
+
+	driver, err := openGPIO(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+
+	if err := driver.Watch(2, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	} else if edge := readFile(t, root, "gpio2/edge"); edge != "both" {
+		t.Error("Expected both, got", edge)
+	}
+
+	writeFile(t, root, "gpio2/value", "1")
+	expectEdge(t, events, 2, gopi.GPIO_EDGE_RISING)
+	writeFile(t, root, "gpio2/value", "0")
+	expectEdge(t, events, 2, gopi.GPIO_EDGE_FALLING)
+
+	// Stop watching
+	if err := driver.Watch(2, gopi.GPIO_EDGE_NONE); err != nil {
+		t.Fatal(err)
+	} else if edge := readFile(t, root, "gpio2/edge"); edge != "none" {
+		t.Error("Expected none, got", edge)
+	}
+	writeFile(t, root, "gpio2/value", "1")
+	expectNoEdge(t, events)
+}
+
+func TestGPIO_005(t *testing.T) {
+	root := fakeChip(t, 0, 8)
+	defer os.RemoveAll(root)
+
+	driver, err := openGPIO(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+
+	// Only rising edges are emitted
+	if err := driver.Watch(5, gopi.GPIO_EDGE_RISING); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "gpio5/value", "1")
+	expectEdge(t, events, 5, gopi.GPIO_EDGE_RISING)
+	writeFile(t, root, "gpio5/value", "0")
+	expectNoEdge(t, events)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CHARACTER DEVICE
+
+func TestGPIO_006(t *testing.T) {
+	// The character device reports the chip and line modes
+	dev := newDevice(8)
+	dev.flags[5] = gpio.GPIOLINE_FLAG_IS_OUT
+	driver, err := openDevice(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	if pins := driver.Pins(); len(pins) != 8 {
+		t.Error("Expected 8 pins, got", pins)
+	} else if str := fmt.Sprint(driver); strings.Contains(str, `label="fake"`) == false {
+		t.Error("Unexpected string", str)
+	} else if mode := driver.GetPinMode(4); mode != gopi.GPIO_INPUT {
+		t.Error("Expected GPIO_INPUT, got", mode)
+	} else if mode := driver.GetPinMode(5); mode != gopi.GPIO_OUTPUT {
+		t.Error("Expected GPIO_OUTPUT, got", mode)
+	} else if mode := driver.GetPinMode(8); mode != gopi.GPIO_NONE {
+		t.Error("Expected GPIO_NONE, got", mode)
+	}
+
+	// There is no fall back to sysfs when the device fails
+	dev = newDevice(8)
+	dev.err = syscall.ENOTTY
+	if _, err := openDevice(dev); err == nil {
+		t.Error("Expected error")
+	} else if dev.closed == false {
+		t.Error("Expected device to be closed")
+	}
+}
+
+func TestGPIO_007(t *testing.T) {
+	// Lines are requested with flags for the mode and bias
+	dev := newDevice(8)
+	driver, err := openDevice(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	// Read an input, which requests the line
+	dev.set(1, 1)
+	if state := driver.ReadPin(1); state != gopi.GPIO_HIGH {
+		t.Error("Expected GPIO_HIGH, got", state)
+	} else if req := dev.last(); req.offset != 1 || req.flags != gpio.GPIOHANDLE_REQUEST_INPUT {
+		t.Error("Unexpected request", req)
+	}
+
+	// Writing to an input doesn't change the value
+	driver.WritePin(2, gopi.GPIO_HIGH)
+	if value := dev.get(2); value != 0 {
+		t.Error("Expected 0, got", value)
+	}
+
+	// Set as output and write
+	driver.SetPinMode(2, gopi.GPIO_OUTPUT)
+	if req := dev.last(); req.offset != 2 || req.flags != gpio.GPIOHANDLE_REQUEST_OUTPUT {
+		t.Error("Unexpected request", req)
+	}
+	driver.WritePin(2, gopi.GPIO_HIGH)
+	if value := dev.get(2); value != 1 {
+		t.Error("Expected 1, got", value)
+	} else if state := driver.ReadPin(2); state != gopi.GPIO_HIGH {
+		t.Error("Expected GPIO_HIGH, got", state)
+	}
+
+	// The line is requested again with a bias
+	if err := driver.SetPullMode(3, gopi.GPIO_PULL_UP); err != nil {
+		t.Error(err)
+	} else if req := dev.last(); req.offset != 3 || req.flags != gpio.GPIOHANDLE_REQUEST_INPUT|gpio.GPIOHANDLE_REQUEST_BIAS_PULL_UP {
+		t.Error("Unexpected request", req)
+	} else if dev.open(3) != 1 {
+		t.Error("Expected one line handle, got", dev.open(3))
+	}
+
+	// Kernels without bias flags reject them
+	dev.nobias = true
+	if err := driver.SetPullMode(3, gopi.GPIO_PULL_DOWN); err != gopi.ErrNotImplemented {
+		t.Error("Expected ErrNotImplemented, got", err)
+	} else if req := dev.last(); req.flags != gpio.GPIOHANDLE_REQUEST_INPUT {
+		t.Error("Unexpected request", req)
+	}
+}
+
+func TestGPIO_008(t *testing.T) {
+	// Watched lines are requested as line events
+	dev := newDevice(8)
+	driver, err := openDevice(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+
+	if err := driver.Watch(4, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	} else if req := dev.last(); req.offset != 4 || req.events == false || req.eventflags != gpio.GPIOEVENT_REQUEST_RISING_EDGE|gpio.GPIOEVENT_REQUEST_FALLING_EDGE {
+		t.Error("Unexpected request", req)
+	}
+	dev.edge(4, gpio.GPIOEVENT_EVENT_RISING_EDGE)
+	expectEdge(t, events, 4, gopi.GPIO_EDGE_RISING)
+	dev.edge(4, gpio.GPIOEVENT_EVENT_FALLING_EDGE)
+	expectEdge(t, events, 4, gopi.GPIO_EDGE_FALLING)
+
+	// A watched pin can't be set as output
+	driver.SetPinMode(4, gopi.GPIO_OUTPUT)
+	if mode := driver.GetPinMode(4); mode != gopi.GPIO_INPUT {
+		t.Error("Expected GPIO_INPUT, got", mode)
+	}
+
+	// Stop watching, which closes the line event
+	if err := driver.Watch(4, gopi.GPIO_EDGE_NONE); err != nil {
+		t.Fatal(err)
+	} else if req := dev.last(); req.events {
+		t.Error("Unexpected request", req)
+	} else if dev.open(4) != 1 {
+		t.Error("Expected one line handle, got", dev.open(4))
+	}
+	dev.edge(4, gpio.GPIOEVENT_EVENT_RISING_EDGE)
+	expectNoEdge(t, events)
+}
+
+func TestGPIO_009(t *testing.T) {
+	root := fakeChip(t, 0, 8)
+	defer os.RemoveAll(root)
+
+	driver, err := openGPIO(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A subscriber which doesn't receive doesn't block Close
+	driver.Subscribe()
+	if err := driver.Watch(2, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "gpio2/value", "1")
+	time.Sleep(300 * time.Millisecond)
+	writeFile(t, root, "gpio2/value", "0")
+	time.Sleep(300 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		driver.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for Close")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE CHIP
+
+// fakeChip creates a sysfs-like directory tree with one chip and
+// all pins already exported as inputs
+func fakeChip(t *testing.T, base, ngpio uint) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "gpio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chip := fmt.Sprintf("gpiochip%v", base)
+	writeFile(t, root, "export", "")
+	writeFile(t, root, "unexport", "")
+	writeFile(t, root, filepath.Join(chip, "base"), fmt.Sprint(base))
+	writeFile(t, root, filepath.Join(chip, "ngpio"), fmt.Sprint(ngpio))
+	writeFile(t, root, filepath.Join(chip, "label"), "fake")
+	for pin := base; pin < base+ngpio; pin++ {
+		dir := fmt.Sprintf("gpio%v", pin)
+		writeFile(t, root, filepath.Join(dir, "direction"), "in")
+		writeFile(t, root, filepath.Join(dir, "value"), "0")
+		writeFile(t, root, filepath.Join(dir, "edge"), "none")
+	}
+	return root
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE DEVICE
+
+// device simulates the character device, recording line requests
+type device struct {
+	sync.Mutex
+	lines    uint32
+	flags    map[uint32]uint32
+	values   map[uint32]uint8
+	requests []request
+	handles  []*line
+	err      error
+	nobias   bool
+	closed   bool
+}
+
+type request struct {
+	offset     uint32
+	flags      uint32
+	eventflags uint32
+	events     bool
+}
+
+// line is a requested line, which receives event data when
+// requested as a line event
+type line struct {
+	dev    *device
+	offset uint32
+	events chan []byte
+	done   chan struct{}
+	closed bool
+}
+
+func newDevice(lines uint32) *device {
+	return &device{lines: lines, flags: make(map[uint32]uint32), values: make(map[uint32]uint8)}
+}
+
+func (this *device) Close() error {
+	this.Lock()
+	defer this.Unlock()
+	this.closed = true
+	return nil
+}
+
+func (this *device) ChipInfo() (string, string, uint32, error) {
+	return "gpiochip0", "fake", this.lines, this.err
+}
+
+func (this *device) LineFlags(offset uint32) (uint32, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.flags[offset], nil
+}
+
+func (this *device) LineHandle(offset uint32, flags uint32, value uint8) (gpio.Line, error) {
+	this.Lock()
+	defer this.Unlock()
+	if this.nobias && flags&(gpio.GPIOHANDLE_REQUEST_BIAS_PULL_UP|gpio.GPIOHANDLE_REQUEST_BIAS_PULL_DN|gpio.GPIOHANDLE_REQUEST_BIAS_DISABLE) != 0 {
+		return nil, syscall.EINVAL
+	}
+	this.requests = append(this.requests, request{offset, flags, 0, false})
+	if flags&gpio.GPIOHANDLE_REQUEST_OUTPUT != 0 {
+		this.values[offset] = value
+	}
+	return this.newLine(offset, nil), nil
+}
+
+func (this *device) LineEvent(offset uint32, flags uint32, eventflags uint32) (gpio.Line, error) {
+	this.Lock()
+	defer this.Unlock()
+	this.requests = append(this.requests, request{offset, flags, eventflags, true})
+	return this.newLine(offset, make(chan []byte, 10)), nil
+}
+
+// newLine returns a requested line. Called with the mutex held
+func (this *device) newLine(offset uint32, events chan []byte) *line {
+	l := &line{this, offset, events, make(chan struct{}), false}
+	this.handles = append(this.handles, l)
+	return l
+}
+
+// last returns the last line request
+func (this *device) last() request {
+	this.Lock()
+	defer this.Unlock()
+	if len(this.requests) == 0 {
+		return request{}
+	} else {
+		return this.requests[len(this.requests)-1]
+	}
+}
+
+// open returns the number of lines requested for an offset which
+// have not been closed
+func (this *device) open(offset uint32) int {
+	this.Lock()
+	defer this.Unlock()
+	count := 0
+	for _, l := range this.handles {
+		if l.offset == offset && l.closed == false {
+			count++
+		}
+	}
+	return count
+}
+
+// get and set the value of a line
+func (this *device) get(offset uint32) uint8 {
+	this.Lock()
+	defer this.Unlock()
+	return this.values[offset]
+}
+
+func (this *device) set(offset uint32, value uint8) {
+	this.Lock()
+	defer this.Unlock()
+	this.values[offset] = value
+}
+
+// edge sends event data to line events for an offset
+func (this *device) edge(offset uint32, id uint32) {
+	this.Lock()
+	defer this.Unlock()
+	data := make([]byte, gpio.GPIOEVENT_DATA_SIZE)
+	binary.LittleEndian.PutUint32(data[8:12], id)
+	for _, l := range this.handles {
+		if l.offset == offset && l.events != nil && l.closed == false {
+			l.events <- data
+		}
+	}
+}
+
+func (this *line) Close() error {
+	this.dev.Lock()
+	defer this.dev.Unlock()
+	if this.closed == false {
+		this.closed = true
+		close(this.done)
+	}
+	return nil
+}
+
+func (this *line) Value() (uint8, error) {
+	return this.dev.get(this.offset), nil
+}
+
+func (this *line) SetValue(value uint8) error {
+	this.dev.set(this.offset, value)
+	return nil
+}
+
+func (this *line) Read(buf []byte) (int, error) {
+	select {
+	case data := <-this.events:
+		return copy(buf, data), nil
+	case <-this.done:
+		return 0, io.EOF
+	}
+}
+
+func openDevice(dev gpio.Device) (gopi.GPIO, error) {
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		return nil, err
+	} else if driver, err := gopi.Open(gpio.GPIO{Device: dev}, log.(gopi.Logger)); err != nil {
+		return nil, err
+	} else {
+		return driver.(gopi.GPIO), nil
+	}
+}
+
+func openGPIO(root string) (gopi.GPIO, error) {
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		return nil, err
+	} else if driver, err := gopi.Open(gpio.GPIO{
+		DevPath:      filepath.Join(root, "dev"),
+		SysfsPath:    root,
+		PollInterval: 5 * time.Millisecond,
+	}, log.(gopi.Logger)); err != nil {
+		return nil, err
+	} else {
+		return driver.(gopi.GPIO), nil
+	}
+}
+
+func expectEdge(t *testing.T, events <-chan gopi.Event, pin gopi.GPIOPin, edge gopi.GPIOEdge) {
+	t.Helper()
+	select {
+	case evt := <-events:
+		if evt_, ok := evt.(gopi.GPIOEvent); ok == false {
+			t.Error("Expected GPIOEvent, got", evt)
+		} else if evt_.Pin() != pin || evt_.Edge() != edge {
+			t.Errorf("Expected %v %v, got %v", pin, edge, evt)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timeout waiting for %v %v", pin, edge)
+	}
+}
+
+func expectNoEdge(t *testing.T, events <-chan gopi.Event) {
+	t.Helper()
+	select {
+	case evt := <-events:
+		t.Error("Unexpected event", evt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func readFile(t *testing.T, root, path string) string {
+	t.Helper()
+	if value, err := ioutil.ReadFile(filepath.Join(root, path)); err != nil {
+		t.Fatal(err)
+		return ""
+	} else {
+		return strings.TrimSpace(string(value))
+	}
+}
+
+func writeFile(t *testing.T, root, path, value string) {
+	t.Helper()
+	path = filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
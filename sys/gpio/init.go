@@ -0,0 +1,37 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register GPIO
+	gopi.RegisterModule(gopi.Module{
+		Name: "sys/gpio",
+		Type: gopi.MODULE_TYPE_GPIO,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("gpio.chip", 0, "GPIO chip number")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			chip, _ := app.AppFlags.GetUint("gpio.chip")
+			return gopi.Open(GPIO{
+				Chip: chip,
+			}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,34 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mock
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register simulated GPIO
+	gopi.RegisterModule(gopi.Module{
+		Name: "gpio/mock",
+		Type: gopi.MODULE_TYPE_GPIO,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("gpio.pins", GPIO_DEFAULT_PINS, "Number of simulated GPIO pins")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			pins, _ := app.AppFlags.GetUint("gpio.pins")
+			return gopi.Open(Mock{
+				Pins: pins,
+			}, app.Logger)
+		},
+	})
+}
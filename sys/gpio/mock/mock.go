@@ -0,0 +1,359 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+// Package mock implements a simulated GPIO driver, which keeps pin
+// state in memory so that code which uses gopi.GPIO can be tested
+// without hardware. Input pins are driven by stimuli and every
+// WritePin call is recorded.
+package mock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Mock is the configuration for the simulated GPIO driver
+type Mock struct {
+	Pins     uint           // Number of logical pins (default: 28)
+	Physical []gopi.GPIOPin // Logical pin for each physical pin, from physical pin 1
+}
+
+// GPIO is implemented by the simulated driver and adds methods to
+// drive input pins and inspect writes
+type GPIO interface {
+	gopi.GPIO
+
+	// Drive an input pin to a state immediately
+	Drive(gopi.GPIOPin, gopi.GPIOState)
+
+	// Release an input pin, so the state is determined by the pull mode
+	Release(gopi.GPIOPin)
+
+	// Stimulate schedules stimuli on a pin in the background. Each
+	// stimulus is applied after a delay from the previous one
+	Stimulate(gopi.GPIOPin, ...Stimulus)
+
+	// Wait blocks until all scheduled stimuli have been applied
+	Wait()
+
+	// Writes returns the timeline of WritePin calls
+	Writes() []Write
+}
+
+// Stimulus is a rising or falling edge on an input pin
+type Stimulus struct {
+	After time.Duration
+	Edge  gopi.GPIOEdge
+}
+
+// Write records a WritePin call
+type Write struct {
+	Pin   gopi.GPIOPin
+	State gopi.GPIOState
+	Mode  gopi.GPIOMode // Pin mode at time of write
+	Time  time.Duration // Time since the driver was opened
+}
+
+type mock struct {
+	log      gopi.Logger
+	physical []gopi.GPIOPin
+	pins     []*pin
+	writes   []Write
+	start    time.Time
+	done     chan struct{}
+
+	sync.Mutex
+	sync.WaitGroup
+	event.Publisher
+}
+
+type pin struct {
+	mode   gopi.GPIOMode
+	pull   gopi.GPIOPull
+	edge   gopi.GPIOEdge
+	output gopi.GPIOState
+	input  gopi.GPIOState
+	driven bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIO_DEFAULT_PINS = 28
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the simulated GPIO driver
+func (config Mock) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("gpio.mock.Open{ pins=%v }", config.Pins)
+
+	if config.Pins == 0 {
+		config.Pins = GPIO_DEFAULT_PINS
+	} else if config.Pins > uint(gopi.GPIO_PIN_NONE) {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(mock)
+	this.log = log
+	this.physical = config.Physical
+	this.pins = make([]*pin, config.Pins)
+	for i := range this.pins {
+		this.pins[i] = &pin{mode: gopi.GPIO_INPUT}
+	}
+	this.writes = make([]Write, 0)
+	this.start = time.Now()
+	this.done = make(chan struct{})
+
+	return this, nil
+}
+
+// Close the simulated GPIO driver, abandoning any scheduled stimuli
+func (this *mock) Close() error {
+	this.log.Debug("gpio.mock.Close{ }")
+
+	// Stop stimuli, and unsubscribe before waiting so stimuli which
+	// are emitting to subscribers which are not receiving end
+	close(this.done)
+	this.Publisher.Close()
+	this.WaitGroup.Wait()
+
+	// Blank out instance variables
+	this.pins = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - PINS
+
+func (this *mock) NumberOfPhysicalPins() uint {
+	return uint(len(this.physical))
+}
+
+func (this *mock) Pins() []gopi.GPIOPin {
+	pins := make([]gopi.GPIOPin, len(this.pins))
+	for i := range this.pins {
+		pins[i] = gopi.GPIOPin(i)
+	}
+	return pins
+}
+
+func (this *mock) PhysicalPin(number uint) gopi.GPIOPin {
+	if number == 0 || number > uint(len(this.physical)) {
+		return gopi.GPIO_PIN_NONE
+	} else {
+		return this.physical[number-1]
+	}
+}
+
+func (this *mock) PhysicalPinForPin(logical gopi.GPIOPin) uint {
+	for i, pin := range this.physical {
+		if pin == logical {
+			return uint(i + 1)
+		}
+	}
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - READ AND WRITE
+
+func (this *mock) ReadPin(logical gopi.GPIOPin) gopi.GPIOState {
+	this.Lock()
+	defer this.Unlock()
+
+	if p := this.pin(logical); p == nil {
+		this.log.Error("gpio.mock.ReadPin: %v: %v", logical, gopi.ErrBadParameter)
+		return gopi.GPIO_LOW
+	} else {
+		return p.state()
+	}
+}
+
+func (this *mock) WritePin(logical gopi.GPIOPin, state gopi.GPIOState) {
+	this.log.Debug2("gpio.mock.WritePin{ pin=%v state=%v }", logical, state)
+	this.change(logical, func(p *pin) {
+		this.writes = append(this.writes, Write{logical, state, p.mode, time.Since(this.start)})
+		if p.mode == gopi.GPIO_OUTPUT {
+			p.output = state
+		}
+	})
+}
+
+func (this *mock) GetPinMode(logical gopi.GPIOPin) gopi.GPIOMode {
+	this.Lock()
+	defer this.Unlock()
+
+	if p := this.pin(logical); p == nil {
+		this.log.Error("gpio.mock.GetPinMode: %v: %v", logical, gopi.ErrBadParameter)
+		return gopi.GPIO_NONE
+	} else {
+		return p.mode
+	}
+}
+
+func (this *mock) SetPinMode(logical gopi.GPIOPin, mode gopi.GPIOMode) {
+	this.log.Debug2("gpio.mock.SetPinMode{ pin=%v mode=%v }", logical, mode)
+	this.change(logical, func(p *pin) {
+		p.mode = mode
+	})
+}
+
+func (this *mock) SetPullMode(logical gopi.GPIOPin, pull gopi.GPIOPull) error {
+	this.log.Debug2("gpio.mock.SetPullMode{ pin=%v pull=%v }", logical, pull)
+	if pull > gopi.GPIO_PULL_UP {
+		return gopi.ErrBadParameter
+	}
+	return this.change(logical, func(p *pin) {
+		p.pull = pull
+	})
+}
+
+func (this *mock) Watch(logical gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.log.Debug2("gpio.mock.Watch{ pin=%v edge=%v }", logical, edge)
+	if edge > gopi.GPIO_EDGE_BOTH {
+		return gopi.ErrBadParameter
+	}
+	return this.change(logical, func(p *pin) {
+		p.edge = edge
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - STIMULI
+
+// Rising returns a stimulus which drives a pin high after a delay
+func Rising(after time.Duration) Stimulus {
+	return Stimulus{after, gopi.GPIO_EDGE_RISING}
+}
+
+// Falling returns a stimulus which drives a pin low after a delay
+func Falling(after time.Duration) Stimulus {
+	return Stimulus{after, gopi.GPIO_EDGE_FALLING}
+}
+
+func (this *mock) Drive(logical gopi.GPIOPin, state gopi.GPIOState) {
+	this.log.Debug2("gpio.mock.Drive{ pin=%v state=%v }", logical, state)
+	this.change(logical, func(p *pin) {
+		p.input = state
+		p.driven = true
+	})
+}
+
+func (this *mock) Release(logical gopi.GPIOPin) {
+	this.log.Debug2("gpio.mock.Release{ pin=%v }", logical)
+	this.change(logical, func(p *pin) {
+		p.driven = false
+	})
+}
+
+func (this *mock) Stimulate(logical gopi.GPIOPin, stimuli ...Stimulus) {
+	this.WaitGroup.Add(1)
+	go func() {
+		defer this.WaitGroup.Done()
+		for _, stimulus := range stimuli {
+			select {
+			case <-time.After(stimulus.After):
+				switch stimulus.Edge {
+				case gopi.GPIO_EDGE_RISING:
+					this.Drive(logical, gopi.GPIO_HIGH)
+				case gopi.GPIO_EDGE_FALLING:
+					this.Drive(logical, gopi.GPIO_LOW)
+				default:
+					this.log.Warn("gpio.mock.Stimulate: %v: Ignoring %v", logical, stimulus.Edge)
+				}
+			case <-this.done:
+				return
+			}
+		}
+	}()
+}
+
+func (this *mock) Wait() {
+	this.WaitGroup.Wait()
+}
+
+func (this *mock) Writes() []Write {
+	this.Lock()
+	defer this.Unlock()
+
+	writes := make([]Write, len(this.writes))
+	copy(writes, this.writes)
+	return writes
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *mock) String() string {
+	return fmt.Sprintf("<gpio.mock>{ pins=%v writes=%v }", len(this.pins), len(this.writes))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *mock) pin(logical gopi.GPIOPin) *pin {
+	if int(logical) >= len(this.pins) {
+		return nil
+	} else {
+		return this.pins[logical]
+	}
+}
+
+// change modifies the pin state and emits an event when the
+// state of a watched pin changes
+func (this *mock) change(logical gopi.GPIOPin, fn func(*pin)) error {
+	this.Lock()
+	p := this.pin(logical)
+	if p == nil {
+		this.Unlock()
+		this.log.Error("gpio.mock: %v: %v", logical, gopi.ErrBadParameter)
+		return gopi.ErrBadParameter
+	}
+	before := p.state()
+	fn(p)
+	after, edge := p.state(), p.edge
+	this.Unlock()
+
+	// Emit outside of the lock, as emit blocks on subscribers
+	if before == after {
+		return nil
+	} else if after == gopi.GPIO_HIGH && (edge == gopi.GPIO_EDGE_RISING || edge == gopi.GPIO_EDGE_BOTH) {
+		this.Emit(event.NewGPIOEvent(this, logical, gopi.GPIO_EDGE_RISING, time.Now()))
+	} else if after == gopi.GPIO_LOW && (edge == gopi.GPIO_EDGE_FALLING || edge == gopi.GPIO_EDGE_BOTH) {
+		this.Emit(event.NewGPIOEvent(this, logical, gopi.GPIO_EDGE_FALLING, time.Now()))
+	}
+	return nil
+}
+
+// state returns the output state for output pins, or else the
+// driven state or the state determined by the pull mode
+func (p *pin) state() gopi.GPIOState {
+	switch {
+	case p.mode == gopi.GPIO_OUTPUT:
+		return p.output
+	case p.driven:
+		return p.input
+	case p.pull == gopi.GPIO_PULL_UP:
+		return gopi.GPIO_HIGH
+	default:
+		return gopi.GPIO_LOW
+	}
+}
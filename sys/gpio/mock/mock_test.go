@@ -0,0 +1,198 @@
+package mock_test
+
+import (
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/gpio/mock"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE MODULE
+
+func TestMock_000(t *testing.T) {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("gpio")); err != nil {
+		t.Fatal(err)
+	} else {
+		defer app.Close()
+		if app.GPIO == nil {
+			t.Fatal("app.GPIO == nil")
+		} else if _, ok := app.GPIO.(mock.GPIO); ok == false {
+			t.Fatal("Expected mock.GPIO, got", app.GPIO)
+		} else if pins := app.GPIO.Pins(); len(pins) != mock.GPIO_DEFAULT_PINS {
+			t.Error("Unexpected pins", pins)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PIN MODES
+
+func TestMock_001(t *testing.T) {
+	gpio := openMock(t)
+	defer gpio.Close()
+
+	// Input pins float low, unless pulled up
+	if state := gpio.ReadPin(1); state != gopi.GPIO_LOW {
+		t.Error("Expected GPIO_LOW, got", state)
+	}
+	if err := gpio.SetPullMode(1, gopi.GPIO_PULL_UP); err != nil {
+		t.Error(err)
+	} else if state := gpio.ReadPin(1); state != gopi.GPIO_HIGH {
+		t.Error("Expected GPIO_HIGH, got", state)
+	}
+
+	// Driven pins override the pull mode
+	gpio.Drive(1, gopi.GPIO_LOW)
+	if state := gpio.ReadPin(1); state != gopi.GPIO_LOW {
+		t.Error("Expected GPIO_LOW, got", state)
+	}
+	gpio.Release(1)
+	if state := gpio.ReadPin(1); state != gopi.GPIO_HIGH {
+		t.Error("Expected GPIO_HIGH, got", state)
+	}
+
+	// Out of range
+	if err := gpio.SetPullMode(100, gopi.GPIO_PULL_UP); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestMock_002(t *testing.T) {
+	gpio := openMock(t)
+	defer gpio.Close()
+
+	// Writes to inputs are recorded but ignored
+	gpio.WritePin(2, gopi.GPIO_HIGH)
+	if state := gpio.ReadPin(2); state != gopi.GPIO_LOW {
+		t.Error("Expected GPIO_LOW, got", state)
+	}
+
+	gpio.SetPinMode(2, gopi.GPIO_OUTPUT)
+	gpio.WritePin(2, gopi.GPIO_HIGH)
+	gpio.WritePin(2, gopi.GPIO_LOW)
+	if state := gpio.ReadPin(2); state != gopi.GPIO_LOW {
+		t.Error("Expected GPIO_LOW, got", state)
+	}
+
+	writes := gpio.Writes()
+	if len(writes) != 3 {
+		t.Fatal("Expected three writes, got", writes)
+	}
+	if writes[0].Mode != gopi.GPIO_INPUT || writes[1].Mode != gopi.GPIO_OUTPUT {
+		t.Error("Unexpected modes", writes)
+	}
+	if writes[1].State != gopi.GPIO_HIGH || writes[2].State != gopi.GPIO_LOW {
+		t.Error("Unexpected states", writes)
+	}
+	for i := 1; i < len(writes); i++ {
+		if writes[i].Time < writes[i-1].Time {
+			t.Error("Writes out of order", writes)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STIMULI
+
+func TestMock_003(t *testing.T) {
+	gpio := openMock(t)
+	defer gpio.Close()
+
+	events := gpio.Subscribe()
+	defer gpio.Unsubscribe(events)
+
+	if err := gpio.Watch(4, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	}
+	gpio.Stimulate(4, mock.Rising(10*time.Millisecond), mock.Falling(10*time.Millisecond), mock.Rising(10*time.Millisecond))
+
+	expect := []gopi.GPIOEdge{gopi.GPIO_EDGE_RISING, gopi.GPIO_EDGE_FALLING, gopi.GPIO_EDGE_RISING}
+	for _, edge := range expect {
+		select {
+		case evt := <-events:
+			if evt_ := evt.(gopi.GPIOEvent); evt_.Pin() != 4 || evt_.Edge() != edge {
+				t.Error("Expected", edge, "got", evt)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for", edge)
+		}
+	}
+	gpio.Wait()
+}
+
+func TestMock_004(t *testing.T) {
+	gpio := openMock(t)
+	defer gpio.Close()
+
+	events := gpio.Subscribe()
+	defer gpio.Unsubscribe(events)
+
+	// Only falling edges are emitted, and no edge for an unchanged state
+	if err := gpio.Watch(5, gopi.GPIO_EDGE_FALLING); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		gpio.Drive(5, gopi.GPIO_HIGH)
+		gpio.Drive(5, gopi.GPIO_HIGH)
+		gpio.Drive(5, gopi.GPIO_LOW)
+	}()
+	select {
+	case evt := <-events:
+		if evt.(gopi.GPIOEvent).Edge() != gopi.GPIO_EDGE_FALLING {
+			t.Error("Expected GPIO_EDGE_FALLING, got", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	}
+	select {
+	case evt := <-events:
+		t.Error("Unexpected event", evt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMock_005(t *testing.T) {
+	gpio := openMock(t)
+	gpio.Subscribe()
+
+	// Closing does not wait for a subscriber which never receives
+	if err := gpio.Watch(5, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	}
+	gpio.Stimulate(5, mock.Rising(0), mock.Falling(time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	done := make(chan error)
+	go func() {
+		done <- gpio.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for close")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openMock(t *testing.T) mock.GPIO {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(mock.Mock{Pins: 8}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(mock.GPIO)
+	}
+}
@@ -0,0 +1,428 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type sysfs struct {
+	root     string
+	base     uint
+	ngpio    uint
+	label    string
+	interval time.Duration
+	epfd     int
+	exported []gopi.GPIOPin
+	watching map[gopi.GPIOPin]*watch
+	emit     emitFunc
+
+	sync.Mutex
+	event.Tasks
+}
+
+type watch struct {
+	edge  gopi.GPIOEdge
+	value *os.File
+	state gopi.GPIOState
+}
+
+type sysfschip struct {
+	path  string
+	base  uint
+	ngpio uint
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIO_EXPORT_TIMEOUT = time.Second
+	GPIO_MAX_EVENTS     = 8
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func openSysfs(config GPIO, emit emitFunc) (*sysfs, error) {
+	this := new(sysfs)
+	this.root = config.SysfsPath
+	this.interval = config.PollInterval
+	this.watching = make(map[gopi.GPIOPin]*watch)
+	this.emit = emit
+
+	// Chips are named by base pin number, so enumerate them in
+	// order of base and choose the chip by index
+	if chips, err := sysfsChips(this.root); err != nil {
+		return nil, err
+	} else if config.Chip >= uint(len(chips)) {
+		return nil, fmt.Errorf("%v: chip %v not found", this.root, config.Chip)
+	} else {
+		chip := chips[config.Chip]
+		this.base = chip.base
+		this.ngpio = chip.ngpio
+		this.label, _ = readString(filepath.Join(chip.path, "label"))
+	}
+
+	// Edges are signalled with POLLPRI on the value file
+	if epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC); err != nil {
+		return nil, err
+	} else {
+		this.epfd = epfd
+	}
+
+	// Background task samples watched pins
+	this.Tasks.Start(this.watchTask)
+
+	// Success
+	return this, nil
+}
+
+func (this *sysfs) Close() error {
+	// Stop watching
+	err := this.Tasks.Close()
+
+	this.Lock()
+	defer this.Unlock()
+
+	for pin, w := range this.watching {
+		w.value.Close()
+		delete(this.watching, pin)
+	}
+	syscall.Close(this.epfd)
+
+	// Unexport pins which were exported by this driver
+	for _, pin := range this.exported {
+		if err_ := writeString(filepath.Join(this.root, "unexport"), fmt.Sprint(this.base+uint(pin))); err_ != nil && err == nil {
+			err = err_
+		}
+	}
+	this.exported = nil
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKEND INTERFACE
+
+func (this *sysfs) Pins() []gopi.GPIOPin {
+	pins := make([]gopi.GPIOPin, 0, this.ngpio)
+	for i := uint(0); i < this.ngpio && i < uint(gopi.GPIO_PIN_NONE); i++ {
+		pins = append(pins, gopi.GPIOPin(i))
+	}
+	return pins
+}
+
+func (this *sysfs) ReadPin(pin gopi.GPIOPin) (gopi.GPIOState, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if path, err := this.export(pin); err != nil {
+		return gopi.GPIO_LOW, err
+	} else if value, err := readString(filepath.Join(path, "value")); err != nil {
+		return gopi.GPIO_LOW, err
+	} else {
+		return parseState(value)
+	}
+}
+
+func (this *sysfs) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if path, err := this.export(pin); err != nil {
+		return err
+	} else if direction, err := readString(filepath.Join(path, "direction")); err != nil {
+		return err
+	} else if direction != "out" {
+		return ErrNotOutput
+	} else if state == gopi.GPIO_HIGH {
+		return writeString(filepath.Join(path, "value"), "1")
+	} else {
+		return writeString(filepath.Join(path, "value"), "0")
+	}
+}
+
+func (this *sysfs) GetPinMode(pin gopi.GPIOPin) (gopi.GPIOMode, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if path, err := this.export(pin); err != nil {
+		return gopi.GPIO_NONE, err
+	} else if direction, err := readString(filepath.Join(path, "direction")); err != nil {
+		return gopi.GPIO_NONE, err
+	} else if direction == "out" {
+		return gopi.GPIO_OUTPUT, nil
+	} else {
+		return gopi.GPIO_INPUT, nil
+	}
+}
+
+func (this *sysfs) SetPinMode(pin gopi.GPIOPin, mode gopi.GPIOMode) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if path, err := this.export(pin); err != nil {
+		return err
+	} else if _, exists := this.watching[pin]; exists && mode == gopi.GPIO_OUTPUT {
+		return fmt.Errorf("Cannot set %v to output whilst watching", pin)
+	} else if mode == gopi.GPIO_OUTPUT {
+		return writeString(filepath.Join(path, "direction"), "out")
+	} else {
+		return writeString(filepath.Join(path, "direction"), "in")
+	}
+}
+
+func (this *sysfs) SetPullMode(gopi.GPIOPin, gopi.GPIOPull) error {
+	return gopi.ErrNotImplemented
+}
+
+func (this *sysfs) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.Lock()
+	defer this.Unlock()
+
+	path, err := this.export(pin)
+	if err != nil {
+		return err
+	}
+
+	// Stop watching the pin
+	if w, exists := this.watching[pin]; exists {
+		syscall.EpollCtl(this.epfd, syscall.EPOLL_CTL_DEL, int(w.value.Fd()), nil)
+		w.value.Close()
+		delete(this.watching, pin)
+	}
+
+	// Set the edge
+	if err := writeString(filepath.Join(path, "edge"), sysfsEdge(edge)); err != nil {
+		return err
+	} else if edge == gopi.GPIO_EDGE_NONE {
+		return nil
+	}
+
+	// Open the value file and read the current state
+	w := &watch{edge: edge}
+	if value, err := os.Open(filepath.Join(path, "value")); err != nil {
+		return err
+	} else if state, err := readState(value); err != nil {
+		value.Close()
+		return err
+	} else {
+		w.value = value
+		w.state = state
+	}
+
+	// Register for POLLPRI. Where the value file cannot be polled
+	// the pin is sampled at the poll interval instead
+	evt := syscall.EpollEvent{
+		Events: syscall.EPOLLPRI | syscall.EPOLLERR,
+		Fd:     int32(w.value.Fd()),
+	}
+	syscall.EpollCtl(this.epfd, syscall.EPOLL_CTL_ADD, int(w.value.Fd()), &evt)
+
+	// Success
+	this.watching[pin] = w
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *sysfs) String() string {
+	return fmt.Sprintf("sysfs=%v label=%v base=%v ngpio=%v", strconv.Quote(this.root), strconv.Quote(this.label), this.base, this.ngpio)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+func (this *sysfs) watchTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+
+	events := make([]syscall.EpollEvent, GPIO_MAX_EVENTS)
+	timeout := int(this.interval / time.Millisecond)
+	if timeout < 1 {
+		timeout = 1
+	}
+FOR_LOOP:
+	for {
+		select {
+		case <-stop:
+			break FOR_LOOP
+		default:
+			if _, err := syscall.EpollWait(this.epfd, events, timeout); err != nil && err != syscall.EINTR {
+				return err
+			}
+			this.sample()
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// sample reads the state of each watched pin and emits an event
+// for each edge detected
+func (this *sysfs) sample() {
+	type edge struct {
+		pin  gopi.GPIOPin
+		edge gopi.GPIOEdge
+	}
+	this.Lock()
+	edges := make([]edge, 0, len(this.watching))
+	for pin, w := range this.watching {
+		if state, err := readState(w.value); err != nil || state == w.state {
+			continue
+		} else if w.state = state; state == gopi.GPIO_HIGH && w.edge != gopi.GPIO_EDGE_FALLING {
+			edges = append(edges, edge{pin, gopi.GPIO_EDGE_RISING})
+		} else if state == gopi.GPIO_LOW && w.edge != gopi.GPIO_EDGE_RISING {
+			edges = append(edges, edge{pin, gopi.GPIO_EDGE_FALLING})
+		}
+	}
+	this.Unlock()
+
+	// Emit outside of the lock, as emit blocks on subscribers
+	for _, e := range edges {
+		this.emit(e.pin, e.edge)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// export returns the path to the pin, exporting the pin first if
+// necessary
+func (this *sysfs) export(pin gopi.GPIOPin) (string, error) {
+	if uint(pin) >= this.ngpio {
+		return "", gopi.ErrBadParameter
+	}
+	number := this.base + uint(pin)
+	path := filepath.Join(this.root, fmt.Sprintf("gpio%v", number))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	} else if err := writeString(filepath.Join(this.root, "export"), fmt.Sprint(number)); err != nil {
+		return "", err
+	}
+
+	// Wait for the pin to appear
+	timeout := time.Now().Add(GPIO_EXPORT_TIMEOUT)
+	for time.Now().Before(timeout) {
+		if _, err := os.Stat(filepath.Join(path, "value")); err == nil {
+			this.exported = append(this.exported, pin)
+			return path, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return "", gopi.ErrDeadlineExceeded
+}
+
+func sysfsChips(root string) ([]sysfschip, error) {
+	paths, err := filepath.Glob(filepath.Join(root, "gpiochip*"))
+	if err != nil {
+		return nil, err
+	}
+	chips := make([]sysfschip, 0, len(paths))
+	for _, path := range paths {
+		chip := sysfschip{path: path}
+		if base, err := readUint(filepath.Join(path, "base")); err != nil {
+			return nil, err
+		} else if ngpio, err := readUint(filepath.Join(path, "ngpio")); err != nil {
+			return nil, err
+		} else {
+			chip.base = base
+			chip.ngpio = ngpio
+		}
+		chips = append(chips, chip)
+	}
+	sort.Slice(chips, func(i, j int) bool {
+		return chips[i].base < chips[j].base
+	})
+	return chips, nil
+}
+
+func sysfsEdge(edge gopi.GPIOEdge) string {
+	switch edge {
+	case gopi.GPIO_EDGE_RISING:
+		return "rising"
+	case gopi.GPIO_EDGE_FALLING:
+		return "falling"
+	case gopi.GPIO_EDGE_BOTH:
+		return "both"
+	default:
+		return "none"
+	}
+}
+
+func readState(fh *os.File) (gopi.GPIOState, error) {
+	buf := make([]byte, 2)
+	if n, err := fh.ReadAt(buf, 0); n == 0 && err != nil {
+		return gopi.GPIO_LOW, err
+	} else {
+		return parseState(string(buf[:n]))
+	}
+}
+
+func parseState(value string) (gopi.GPIOState, error) {
+	switch strings.TrimSpace(value) {
+	case "0":
+		return gopi.GPIO_LOW, nil
+	case "1":
+		return gopi.GPIO_HIGH, nil
+	default:
+		return gopi.GPIO_LOW, gopi.ErrUnexpectedResponse
+	}
+}
+
+func readString(path string) (string, error) {
+	if value, err := ioutil.ReadFile(path); err != nil {
+		return "", err
+	} else {
+		return strings.TrimSpace(string(value)), nil
+	}
+}
+
+func readUint(path string) (uint, error) {
+	if value, err := readString(path); err != nil {
+		return 0, err
+	} else if value_, err := strconv.ParseUint(value, 10, 32); err != nil {
+		return 0, err
+	} else {
+		return uint(value_), nil
+	}
+}
+
+func writeString(path, value string) error {
+	if fh, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0); err != nil {
+		return err
+	} else if _, err := fh.WriteString(value); err != nil {
+		fh.Close()
+		return err
+	} else {
+		return fh.Close()
+	}
+}
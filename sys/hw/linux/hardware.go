@@ -0,0 +1,356 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package linux
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+	"github.com/djthorpe/gopi/util/persistence"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Hardware is the configuration for generic Linux hosts, which reads
+// the board model, serial number, uptime and load from procfs and sysfs,
+// and records metrics. Metrics history is persisted to a file
+type Hardware struct {
+	ProcPath string        // Path to procfs (default: /proc)
+	SysPath  string        // Path to sysfs (default: /sys)
+	Path     string        // Path to persist metrics history, or empty
+	Interval time.Duration // Interval for built-in metrics (default: HW_SAMPLE_INTERVAL)
+	Clock    gopi.Clock    // Clock for sampling and persisting metrics (default: gopi.SystemClock)
+}
+
+type hardware struct {
+	log      gopi.Logger
+	procpath string
+	syspath  string
+	path     string
+	interval time.Duration
+	clock    gopi.Clock
+	name     string
+	serial   string
+	start    time.Time
+	metrics  []*metric
+	builtin  []*metric
+	history  map[string]*series
+	done     chan struct{}
+	wait     sync.WaitGroup
+	tasks    event.Tasks
+
+	// File persists the metrics history, and locks the driver
+	persistence.File
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	HW_PROC_PATH    = "/proc"
+	HW_SYS_PATH     = "/sys"
+	HW_NAME_DEFAULT = "linux"
+
+	HW_SAMPLE_INTERVAL = 15 * time.Second
+	HW_METRICS_FILE    = "metrics.json"
+	HW_WRITE_DELTA     = time.Minute
+)
+
+const (
+	// Paths relative to procfs
+	HW_PROC_UPTIME  = "uptime"
+	HW_PROC_LOADAVG = "loadavg"
+	HW_PROC_CPUINFO = "cpuinfo"
+	HW_PROC_DT      = "device-tree"
+
+	// Paths relative to sysfs
+	HW_SYS_DT         = "firmware/devicetree/base"
+	HW_SYS_DMI        = "class/dmi/id"
+	HW_SYS_DRM        = "class/drm"
+	HW_SYS_DRM_GLOB   = "card[0-9]*-*"
+	HW_DT_MODEL       = "model"
+	HW_DT_SERIAL      = "serial-number"
+	HW_DMI_VENDOR     = "sys_vendor"
+	HW_DMI_PRODUCT    = "product_name"
+	HW_DMI_SERIAL     = "product_serial"
+	HW_DMI_UUID       = "product_uuid"
+	HW_CPUINFO_MODEL  = "Model"
+	HW_CPUINFO_SERIAL = "Serial"
+	HW_CPUINFO_NAME   = "model name"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the hardware driver
+func (config Hardware) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.hw.linux.Open{ procpath=%v syspath=%v }", strconv.Quote(config.ProcPath), strconv.Quote(config.SysPath))
+
+	this := new(hardware)
+	this.log = log
+	this.procpath = config.ProcPath
+	if this.procpath == "" {
+		this.procpath = HW_PROC_PATH
+	}
+	this.syspath = config.SysPath
+	if this.syspath == "" {
+		this.syspath = HW_SYS_PATH
+	}
+	this.path = config.Path
+	this.interval = config.Interval
+	if this.interval == 0 {
+		this.interval = HW_SAMPLE_INTERVAL
+	}
+	this.clock = config.Clock
+	if this.clock == nil {
+		this.clock = gopi.SystemClock
+	}
+	this.start = this.clock.Now()
+	this.metrics = make([]*metric, 0)
+	this.builtin = make([]*metric, 0)
+	this.history = make(map[string]*series)
+	this.done = make(chan struct{})
+
+	// The uptime is required, so check procfs exists
+	if _, err := readFile(filepath.Join(this.procpath, HW_PROC_UPTIME)); err != nil {
+		return nil, err
+	}
+
+	// Read the name and serial number, which don't change
+	this.name = this.readName()
+	this.serial = this.readSerial()
+
+	// Read metrics history, then record built-in metrics
+	if err := this.File.Init(this, &this.history, log); err != nil {
+		return nil, err
+	} else if err := this.builtins(); err != nil {
+		this.File.Close()
+		return nil, err
+	} else {
+		this.tasks.Start(this.sampleTask)
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the hardware driver, and stop recording metrics
+func (this *hardware) Close() error {
+	this.log.Debug("sys.hw.linux.Close{ }")
+
+	this.Lock()
+	if this.done == nil {
+		this.Unlock()
+		return nil
+	}
+	close(this.done)
+	this.done = nil
+	this.Unlock()
+
+	// Wait for metrics to stop, then write the history
+	this.wait.Wait()
+	if err := this.tasks.Close(); err != nil {
+		return err
+	}
+	return this.File.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - HARDWARE
+
+// Name returns the board model
+func (this *hardware) Name() string {
+	return this.name
+}
+
+// SerialNumber returns the serial number, or an empty string if
+// the serial number is unknown
+func (this *hardware) SerialNumber() string {
+	return this.serial
+}
+
+// NumberOfDisplays returns the number of display connectors
+func (this *hardware) NumberOfDisplays() uint {
+	if connectors, err := filepath.Glob(filepath.Join(this.syspath, HW_SYS_DRM, HW_SYS_DRM_GLOB)); err != nil {
+		return 0
+	} else {
+		return uint(len(connectors))
+	}
+}
+
+// UptimeHost returns the time since the host was started
+func (this *hardware) UptimeHost() time.Duration {
+	if fields, err := readFields(filepath.Join(this.procpath, HW_PROC_UPTIME), 1); err != nil {
+		this.log.Warn("UptimeHost: %v", err)
+		return 0
+	} else {
+		return time.Duration(fields[0] * float64(time.Second))
+	}
+}
+
+// UptimeApp returns the time since the driver was opened
+func (this *hardware) UptimeApp() time.Duration {
+	return this.clock.Now().Sub(this.start)
+}
+
+// LoadAverage returns the 1, 5 and 15 minute load averages
+func (this *hardware) LoadAverage() (float64, float64, float64) {
+	if fields, err := readFields(filepath.Join(this.procpath, HW_PROC_LOADAVG), 3); err != nil {
+		this.log.Warn("LoadAverage: %v", err)
+		return 0, 0, 0
+	} else {
+		return fields[0], fields[1], fields[2]
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PERSISTENCE CONFIG
+
+func (this *hardware) DefaultFilename() string {
+	return HW_METRICS_FILE
+}
+
+func (this *hardware) WriteDelta() time.Duration {
+	return HW_WRITE_DELTA
+}
+
+func (this *hardware) Path() string {
+	return this.path
+}
+
+func (this *hardware) Indent() bool {
+	return false
+}
+
+func (this *hardware) Clock() gopi.Clock {
+	return this.clock
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *hardware) String() string {
+	return fmt.Sprintf("<sys.hw.linux>{ name=%v serial=%v displays=%v uptime=%v }", strconv.Quote(this.name), strconv.Quote(this.serial), this.NumberOfDisplays(), this.UptimeHost().Truncate(time.Second))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// readName returns the device tree model, the DMI vendor and product
+// name, or the processor model
+func (this *hardware) readName() string {
+	for _, path := range []string{
+		filepath.Join(this.procpath, HW_PROC_DT, HW_DT_MODEL),
+		filepath.Join(this.syspath, HW_SYS_DT, HW_DT_MODEL),
+	} {
+		if model, err := readFile(path); err == nil && model != "" {
+			return model
+		}
+	}
+	product, _ := readFile(filepath.Join(this.syspath, HW_SYS_DMI, HW_DMI_PRODUCT))
+	vendor, _ := readFile(filepath.Join(this.syspath, HW_SYS_DMI, HW_DMI_VENDOR))
+	if product != "" {
+		return strings.TrimSpace(vendor + " " + product)
+	}
+	cpuinfo := this.readCPUInfo()
+	if model := cpuinfo[HW_CPUINFO_MODEL]; model != "" {
+		return model
+	} else if name := cpuinfo[HW_CPUINFO_NAME]; name != "" {
+		return name
+	}
+	return HW_NAME_DEFAULT
+}
+
+// readSerial returns the device tree or processor serial number, or
+// the DMI serial number or UUID
+func (this *hardware) readSerial() string {
+	for _, path := range []string{
+		filepath.Join(this.procpath, HW_PROC_DT, HW_DT_SERIAL),
+		filepath.Join(this.syspath, HW_SYS_DT, HW_DT_SERIAL),
+	} {
+		if serial, err := readFile(path); err == nil && serial != "" {
+			return serial
+		}
+	}
+	if serial := this.readCPUInfo()[HW_CPUINFO_SERIAL]; serial != "" {
+		return serial
+	}
+	for _, name := range []string{HW_DMI_SERIAL, HW_DMI_UUID} {
+		if serial, err := readFile(filepath.Join(this.syspath, HW_SYS_DMI, name)); err == nil && serial != "" {
+			return serial
+		}
+	}
+	return ""
+}
+
+// readCPUInfo returns the first value for each key in /proc/cpuinfo
+func (this *hardware) readCPUInfo() map[string]string {
+	cpuinfo := make(map[string]string)
+	fh, err := os.Open(filepath.Join(this.procpath, HW_PROC_CPUINFO))
+	if err != nil {
+		return cpuinfo
+	}
+	defer fh.Close()
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		if pair := strings.SplitN(scanner.Text(), ":", 2); len(pair) == 2 {
+			key, value := strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+			if _, exists := cpuinfo[key]; exists == false {
+				cpuinfo[key] = value
+			}
+		}
+	}
+	return cpuinfo
+}
+
+// readFile returns the contents of a file without whitespace or
+// the nul terminator used by the device tree
+func readFile(path string) (string, error) {
+	if data, err := ioutil.ReadFile(path); err != nil {
+		return "", err
+	} else {
+		return strings.TrimSpace(strings.TrimRight(string(data), "\x00")), nil
+	}
+}
+
+// readFields returns the first n fields of a file as numbers
+func readFields(path string, n int) ([]float64, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(data)
+	if len(fields) < n {
+		return nil, fmt.Errorf("%v: %v", path, gopi.ErrUnexpectedResponse)
+	}
+	values := make([]float64, n)
+	for i := range values {
+		if value, err := strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		} else {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
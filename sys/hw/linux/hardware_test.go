@@ -0,0 +1,290 @@
+//go:build linux
+// +build linux
+
+package linux_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/hw/linux"
+	fakeclock "github.com/djthorpe/gopi/util/clock"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestHardware_000(t *testing.T) {
+	// Raspberry Pi reports the device tree model and serial number
+	hw := openHardware(t, "testdata/rpi")
+	defer hw.Close()
+
+	if name := hw.Name(); name != "Raspberry Pi 3 Model B Rev 1.2" {
+		t.Errorf("Unexpected name %q", name)
+	} else if serial := hw.SerialNumber(); serial != "00000000a1b2c3d4" {
+		t.Errorf("Unexpected serial number %q", serial)
+	} else if displays := hw.NumberOfDisplays(); displays != 2 {
+		t.Error("Unexpected number of displays", displays)
+	} else if uptime := hw.UptimeHost(); uptime != 12345670*time.Millisecond {
+		t.Error("Unexpected uptime", uptime)
+	} else if l1, l5, l15 := hw.LoadAverage(); l1 != 0.52 || l5 != 0.58 || l15 != 0.59 {
+		t.Error("Unexpected load average", l1, l5, l15)
+	}
+}
+
+func TestHardware_001(t *testing.T) {
+	// PC reports the DMI vendor, product and UUID
+	hw := openHardware(t, "testdata/x86")
+	defer hw.Close()
+
+	if name := hw.Name(); name != "Dell Inc. XPS 13 9370" {
+		t.Errorf("Unexpected name %q", name)
+	} else if serial := hw.SerialNumber(); serial != "4c4c4544-0042-3510-8051-b4c04f4e4d32" {
+		t.Errorf("Unexpected serial number %q", serial)
+	} else if displays := hw.NumberOfDisplays(); displays != 1 {
+		t.Error("Unexpected number of displays", displays)
+	} else if uptime := hw.UptimeHost(); uptime != 6*time.Minute {
+		t.Error("Unexpected uptime", uptime)
+	} else if l1, l5, l15 := hw.LoadAverage(); l1 != 1 || l5 != 2.5 || l15 != 3.75 {
+		t.Error("Unexpected load average", l1, l5, l15)
+	}
+}
+
+func TestHardware_002(t *testing.T) {
+	// Unknown hardware and invalid files
+	hw := openHardware(t, "testdata/none")
+	defer hw.Close()
+
+	if name := hw.Name(); name != linux.HW_NAME_DEFAULT {
+		t.Errorf("Unexpected name %q", name)
+	} else if serial := hw.SerialNumber(); serial != "" {
+		t.Errorf("Unexpected serial number %q", serial)
+	} else if displays := hw.NumberOfDisplays(); displays != 0 {
+		t.Error("Unexpected number of displays", displays)
+	} else if l1, l5, l15 := hw.LoadAverage(); l1 != 0 || l5 != 0 || l15 != 0 {
+		t.Error("Unexpected load average", l1, l5, l15)
+	}
+
+	// Missing procfs
+	if _, err := gopi.Open(linux.Hardware{ProcPath: "testdata/missing"}, openLogger(t)); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestHardware_003(t *testing.T) {
+	hw := openHardware(t, "testdata/rpi")
+	metrics := hw.(gopi.Metrics)
+
+	temp, err := metrics.NewMetricFloat64(gopi.METRIC_TYPE_CELCIUS, gopi.METRIC_RATE_MINUTE, "temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := metrics.NewMetricUint(gopi.METRIC_TYPE_PURE, gopi.METRIC_RATE_HOUR, "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := metrics.NewMetricUint(gopi.METRIC_TYPE_PURE, gopi.METRIC_RATE_HOUR, "count"); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := metrics.NewMetricUint(gopi.METRIC_TYPE_NONE, gopi.METRIC_RATE_HOUR, "none"); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Values are recorded asynchronously, so send twice to ensure
+	// the first value was recorded
+	temp <- 45.5
+	temp <- 45.5
+	count <- 10
+	count <- 10
+	// Built-in metrics for temperature, memory and load come first
+	if all := metrics.Metrics(gopi.METRIC_TYPE_NONE); len(all) != 5 {
+		t.Error("Unexpected metrics", all)
+	} else if temps := metrics.Metrics(gopi.METRIC_TYPE_CELCIUS); len(temps) != 2 {
+		t.Error("Unexpected metrics", temps)
+	} else if temps[0].Name() != linux.METRIC_NAME_TEMPERATURE || temps[0].FloatValue() != 48.312 {
+		t.Error("Unexpected metric", temps[0])
+	} else if temps[1].FloatValue() != 45.5 || temps[1].Unit() != "°C" || temps[1].Name() != "temp" {
+		t.Error("Unexpected metric", temps[1])
+	} else if counts := metrics.Metrics(gopi.METRIC_TYPE_PURE); len(counts) != 2 || counts[1].UintValue() != 10 {
+		t.Error("Unexpected metrics", counts)
+	} else if counts[0].Name() != linux.METRIC_NAME_LOAD || counts[0].FloatValue() != 0.52 {
+		t.Error("Unexpected metric", counts[0])
+	} else if memory := metrics.Metrics(gopi.METRIC_TYPE_BYTES); len(memory) != 1 || memory[0].Name() != linux.METRIC_NAME_MEMORY {
+		t.Error("Unexpected metrics", memory)
+	} else if memory[0].UintValue() != 200000*1024 || memory[0].Unit() != "B" {
+		t.Error("Unexpected metric", memory[0])
+	}
+
+	// Closing the channel or the driver ends recording
+	close(temp)
+	if err := hw.Close(); err != nil {
+		t.Error(err)
+	} else if _, err := metrics.NewMetricUint(gopi.METRIC_TYPE_PURE, gopi.METRIC_RATE_HOUR, "closed"); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+}
+
+func TestHardware_004(t *testing.T) {
+	// Minimum, maximum and mean over a rolling minute
+	clock := fakeclock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	hw := openHardwareWithConfig(t, linux.Hardware{ProcPath: "testdata/none/proc", SysPath: "testdata/none/sys", Interval: time.Hour, Clock: clock})
+	defer hw.Close()
+
+	metrics := hw.(gopi.Metrics)
+	values, err := metrics.NewMetricFloat64(gopi.METRIC_TYPE_CELCIUS, gopi.METRIC_RATE_MINUTE, "temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metric := metrics.Metrics(gopi.METRIC_TYPE_CELCIUS)[0].(gopi.MetricWithStats)
+	for i, value := range []float64{10, 20, 30} {
+		if i > 0 {
+			clock.Advance(20 * time.Second)
+		}
+		values <- value
+		waitCount(t, metric, uint(i+1))
+	}
+	if metric.Min() != 10 || metric.Max() != 30 || metric.Mean() != 20 || metric.FloatValue() != 30 {
+		t.Error("Unexpected metric", metric)
+	}
+
+	// The first value expires after a minute
+	clock.Advance(20 * time.Second)
+	if metric.Count() != 2 || metric.Min() != 20 || metric.Mean() != 25 {
+		t.Error("Unexpected metric", metric)
+	}
+
+	// All values expire, but the last value is kept
+	clock.Advance(time.Hour)
+	if metric.Count() != 0 || metric.Mean() != 0 || metric.FloatValue() != 30 {
+		t.Error("Unexpected metric", metric)
+	}
+}
+
+func TestHardware_005(t *testing.T) {
+	// History is restored on reopen
+	path, err := ioutil.TempDir("", "hardware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	clock := fakeclock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	config := linux.Hardware{ProcPath: "testdata/none/proc", SysPath: "testdata/none/sys", Path: path, Interval: time.Hour, Clock: clock}
+	hw := openHardwareWithConfig(t, config)
+	values, err := hw.(gopi.Metrics).NewMetricUint(gopi.METRIC_TYPE_PURE, gopi.METRIC_RATE_DAY, "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metric := hw.(gopi.Metrics).Metrics(gopi.METRIC_TYPE_PURE)[1].(gopi.MetricWithStats)
+	values <- 5
+	waitCount(t, metric, 1)
+	values <- 15
+	waitCount(t, metric, 2)
+	if err := hw.Close(); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(filepath.Join(path, linux.HW_METRICS_FILE)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen an hour later
+	clock.Advance(time.Hour)
+	hw = openHardwareWithConfig(t, config)
+	defer hw.Close()
+	if _, err := hw.(gopi.Metrics).NewMetricUint(gopi.METRIC_TYPE_PURE, gopi.METRIC_RATE_DAY, "count"); err != nil {
+		t.Fatal(err)
+	} else if metric := hw.(gopi.Metrics).Metrics(gopi.METRIC_TYPE_PURE)[1].(gopi.MetricWithStats); metric.Name() != "count" {
+		t.Error("Unexpected metric", metric)
+	} else if metric.Count() != 2 || metric.Min() != 5 || metric.Max() != 15 || metric.Mean() != 10 || metric.UintValue() != 15 {
+		t.Error("Unexpected metric", metric)
+	}
+
+	// History with a different rate is discarded
+	if _, err := hw.(gopi.Metrics).NewMetricUint(gopi.METRIC_TYPE_PURE, gopi.METRIC_RATE_MINUTE, "load_average"); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestHardware_006(t *testing.T) {
+	// Values which are invalid for the metric type are not recorded
+	hw := openHardware(t, "testdata/none")
+	defer hw.Close()
+
+	metrics := hw.(gopi.Metrics)
+	bytes, err := metrics.NewMetricFloat64(gopi.METRIC_TYPE_BYTES, gopi.METRIC_RATE_MINUTE, "bytes")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := metrics.NewMetricUint(gopi.METRIC_TYPE_PERCENT+1, gopi.METRIC_RATE_MINUTE, "invalid"); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	metric := metrics.Metrics(gopi.METRIC_TYPE_BYTES)[0].(gopi.MetricWithStats)
+	bytes <- -1
+	bytes <- 1024
+	waitCount(t, metric, 1)
+	if metric.Min() != 1024 || metric.Unit() != "B" {
+		t.Error("Unexpected metric", metric)
+	}
+}
+
+func TestHardware_007(t *testing.T) {
+	// Built-in metrics are sampled at intervals of the clock
+	clock := fakeclock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	hw := openHardwareWithConfig(t, linux.Hardware{ProcPath: "testdata/rpi/proc", SysPath: "testdata/rpi/sys", Interval: time.Minute, Clock: clock})
+	defer hw.Close()
+
+	metric := hw.(gopi.Metrics).Metrics(gopi.METRIC_TYPE_PURE)[0].(gopi.MetricWithStats)
+	if metric.Name() != linux.METRIC_NAME_LOAD {
+		t.Fatal("Unexpected metric", metric)
+	}
+	waitCount(t, metric, 1)
+	for i := uint(2); i <= 3; i++ {
+		clock.Advance(time.Minute)
+		waitCount(t, metric, i)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// waitCount waits for values to be recorded by a metric
+func waitCount(t *testing.T, metric gopi.MetricWithStats, count uint) {
+	t.Helper()
+	timeout := time.Now().Add(time.Second)
+	for metric.Count() != count {
+		if time.Now().After(timeout) {
+			t.Fatal("Timeout waiting for count", count, metric)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func openLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.Logger)
+	}
+}
+
+func openHardware(t *testing.T, root string) gopi.Hardware {
+	t.Helper()
+	return openHardwareWithConfig(t, linux.Hardware{ProcPath: root + "/proc", SysPath: root + "/sys"})
+}
+
+func openHardwareWithConfig(t *testing.T, config linux.Hardware) gopi.Hardware {
+	t.Helper()
+	if driver, err := gopi.Open(config, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.Hardware)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package linux
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register hardware
+	gopi.RegisterModule(gopi.Module{
+		Name: "hw/linux",
+		Type: gopi.MODULE_TYPE_HARDWARE,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagString("hw.procfs", HW_PROC_PATH, "Path to procfs")
+			config.AppFlags.FlagString("hw.sysfs", HW_SYS_PATH, "Path to sysfs")
+			config.AppFlags.FlagString("hw.metrics", "", "Path to persist metrics history")
+			config.AppFlags.FlagDuration("hw.interval", HW_SAMPLE_INTERVAL, "Interval for built-in metrics")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			procfs, _ := app.AppFlags.GetString("hw.procfs")
+			sysfs, _ := app.AppFlags.GetString("hw.sysfs")
+			path, _ := app.AppFlags.GetString("hw.metrics")
+			interval, _ := app.AppFlags.GetDuration("hw.interval")
+			return gopi.Open(Hardware{
+				ProcPath: procfs,
+				SysPath:  sysfs,
+				Path:     path,
+				Interval: interval,
+				Clock:    app.Clock,
+			}, app.Logger)
+		},
+	})
+}
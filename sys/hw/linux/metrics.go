@@ -0,0 +1,370 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package linux
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type metric struct {
+	hw     *hardware
+	name   string
+	series *series
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Built-in metrics
+	METRIC_NAME_TEMPERATURE = "cpu_temperature"
+	METRIC_NAME_MEMORY      = "memory_used"
+	METRIC_NAME_LOAD        = "load_average"
+	METRIC_RATE_BUILTIN     = gopi.METRIC_RATE_HOUR
+
+	// Paths relative to procfs and sysfs for built-in metrics
+	HW_PROC_MEMINFO      = "meminfo"
+	HW_SYS_THERMAL       = "class/thermal/thermal_zone0/temp"
+	HW_MEMINFO_TOTAL     = "MemTotal"
+	HW_MEMINFO_FREE      = "MemFree"
+	HW_MEMINFO_AVAILABLE = "MemAvailable"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - METRICS
+
+// NewMetricUint returns a channel on which values are recorded,
+// until the channel is closed or the driver is closed. History for
+// a metric with the same name, type and rate is restored
+func (this *hardware) NewMetricUint(t gopi.MetricType, rate gopi.MetricRate, name string) (chan<- uint, error) {
+	this.log.Debug2("sys.hw.linux.NewMetricUint{ type=%v rate=%v name=%v }", t, rate, strconv.Quote(name))
+
+	metric, done, err := this.newMetric(t, rate, name, true)
+	if err != nil {
+		return nil, err
+	}
+	values := make(chan uint)
+	go func() {
+		defer this.wait.Done()
+		for {
+			select {
+			case value, ok := <-values:
+				if ok == false {
+					return
+				}
+				this.record(metric, float64(value))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return values, nil
+}
+
+// NewMetricFloat64 returns a channel on which values are recorded,
+// until the channel is closed or the driver is closed. History for
+// a metric with the same name, type and rate is restored
+func (this *hardware) NewMetricFloat64(t gopi.MetricType, rate gopi.MetricRate, name string) (chan<- float64, error) {
+	this.log.Debug2("sys.hw.linux.NewMetricFloat64{ type=%v rate=%v name=%v }", t, rate, strconv.Quote(name))
+
+	metric, done, err := this.newMetric(t, rate, name, true)
+	if err != nil {
+		return nil, err
+	}
+	values := make(chan float64)
+	go func() {
+		defer this.wait.Done()
+		for {
+			select {
+			case value, ok := <-values:
+				if ok == false {
+					return
+				}
+				this.record(metric, value)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return values, nil
+}
+
+// Metrics returns the metrics of a type, or all metrics for
+// METRIC_TYPE_NONE
+func (this *hardware) Metrics(t gopi.MetricType) []gopi.Metric {
+	this.Lock()
+	defer this.Unlock()
+
+	metrics := make([]gopi.Metric, 0, len(this.metrics))
+	for _, metric := range this.metrics {
+		if t == gopi.METRIC_TYPE_NONE || t == metric.series.Type {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - METRIC
+
+func (this *metric) Rate() gopi.MetricRate {
+	return this.series.Rate
+}
+
+func (this *metric) Type() gopi.MetricType {
+	return this.series.Type
+}
+
+func (this *metric) Name() string {
+	return this.name
+}
+
+func (this *metric) Unit() string {
+	return this.series.Type.Unit()
+}
+
+func (this *metric) UintValue() uint {
+	if value := this.FloatValue(); value < 0 {
+		return 0
+	} else {
+		return uint(value)
+	}
+}
+
+func (this *metric) FloatValue() float64 {
+	this.hw.Lock()
+	defer this.hw.Unlock()
+	return this.series.Last
+}
+
+func (this *metric) Min() float64 {
+	_, min, _, _ := this.stats()
+	return min
+}
+
+func (this *metric) Max() float64 {
+	_, _, max, _ := this.stats()
+	return max
+}
+
+func (this *metric) Mean() float64 {
+	_, _, _, mean := this.stats()
+	return mean
+}
+
+func (this *metric) Count() uint {
+	count, _, _, _ := this.stats()
+	return count
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *metric) String() string {
+	count, min, max, mean := this.stats()
+	return fmt.Sprintf("<sys.hw.linux.metric>{ name=%v type=%v rate=%v last=%v%v count=%v min=%v max=%v mean=%v }", strconv.Quote(this.name), this.Type(), this.Rate(), this.FloatValue(), this.Unit(), count, min, max, mean)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// sampleTask records the built-in metrics at an interval, which is
+// timed by the clock
+func (this *hardware) sampleTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+	tick := make(chan struct{}, 1)
+	timer := this.clock.AfterFunc(this.interval, func() {
+		select {
+		case tick <- struct{}{}:
+		default:
+		}
+	})
+	defer timer.Stop()
+
+	this.sample()
+FOR_LOOP:
+	for {
+		select {
+		case <-tick:
+			timer.Reset(this.interval)
+			this.sample()
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// newMetric adds a metric with a unique name, and returns the channel
+// which is closed when the driver is closed. When task is true, the
+// caller records values in the background until the channel is closed
+func (this *hardware) newMetric(t gopi.MetricType, rate gopi.MetricRate, name string, task bool) (*metric, <-chan struct{}, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if t == gopi.METRIC_TYPE_NONE || t > gopi.METRIC_TYPE_PERCENT || rate == gopi.METRIC_RATE_NONE || rate > gopi.METRIC_RATE_DAY || name == "" {
+		return nil, nil, gopi.ErrBadParameter
+	} else if this.done == nil {
+		return nil, nil, gopi.ErrOutOfOrder
+	}
+	for _, metric := range this.metrics {
+		if metric.name == name {
+			return nil, nil, gopi.ErrBadParameter
+		}
+	}
+
+	// Restore history, or create a new series
+	s, exists := this.history[name]
+	if exists == false || s == nil || s.Type != t || s.Rate != rate {
+		s = newSeries(t, rate)
+		this.history[name] = s
+	}
+	metric := &metric{this, name, s}
+	this.metrics = append(this.metrics, metric)
+	if task {
+		this.wait.Add(1)
+	}
+	return metric, this.done, nil
+}
+
+// record adds a value to a metric at the current time, ignoring
+// values which are invalid for the metric type
+func (this *hardware) record(metric *metric, value float64) {
+	if err := metric.series.Type.Validate(value); err != nil {
+		this.log.Warn("sys.hw.linux: %v: Invalid value %v%v", metric.name, value, metric.Unit())
+		return
+	}
+	this.Lock()
+	metric.series.add(this.clock.Now(), value)
+	this.Unlock()
+	this.SetModified()
+}
+
+// stats returns the count, min, max and mean at the current time
+func (this *metric) stats() (uint, float64, float64, float64) {
+	this.hw.Lock()
+	defer this.hw.Unlock()
+	return this.series.stats(this.hw.clock.Now())
+}
+
+// builtins adds the built-in metrics which can be read
+func (this *hardware) builtins() error {
+	builtins := map[string]gopi.MetricType{
+		METRIC_NAME_LOAD: gopi.METRIC_TYPE_PURE,
+	}
+	if _, err := this.readTemperature(); err == nil {
+		builtins[METRIC_NAME_TEMPERATURE] = gopi.METRIC_TYPE_CELCIUS
+	}
+	if _, err := this.readMemoryUsed(); err == nil {
+		builtins[METRIC_NAME_MEMORY] = gopi.METRIC_TYPE_BYTES
+	}
+	for _, name := range []string{METRIC_NAME_TEMPERATURE, METRIC_NAME_MEMORY, METRIC_NAME_LOAD} {
+		if t, exists := builtins[name]; exists {
+			if metric, _, err := this.newMetric(t, METRIC_RATE_BUILTIN, name, false); err != nil {
+				return err
+			} else {
+				this.builtin = append(this.builtin, metric)
+			}
+		}
+	}
+	return nil
+}
+
+// sample records the built-in metrics
+func (this *hardware) sample() {
+	for _, metric := range this.builtin {
+		var value float64
+		var err error
+		switch metric.name {
+		case METRIC_NAME_TEMPERATURE:
+			value, err = this.readTemperature()
+		case METRIC_NAME_MEMORY:
+			value, err = this.readMemoryUsed()
+		case METRIC_NAME_LOAD:
+			value, err = this.readLoadAverage()
+		}
+		if err != nil {
+			this.log.Warn("sys.hw.linux: %v: %v", metric.name, err)
+		} else {
+			this.record(metric, value)
+		}
+	}
+}
+
+// readLoadAverage returns the one minute load average
+func (this *hardware) readLoadAverage() (float64, error) {
+	if value, err := readFields(filepath.Join(this.procpath, HW_PROC_LOADAVG), 1); err != nil {
+		return 0, err
+	} else {
+		return value[0], nil
+	}
+}
+
+// readTemperature returns the CPU temperature in Celsius
+func (this *hardware) readTemperature() (float64, error) {
+	if value, err := readFields(filepath.Join(this.syspath, HW_SYS_THERMAL), 1); err != nil {
+		return 0, err
+	} else {
+		return value[0] / 1000, nil
+	}
+}
+
+// readMemoryUsed returns the memory used in bytes
+func (this *hardware) readMemoryUsed() (float64, error) {
+	fh, err := os.Open(filepath.Join(this.procpath, HW_PROC_MEMINFO))
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	meminfo := make(map[string]float64)
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 {
+			if value, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				meminfo[strings.TrimSuffix(fields[0], ":")] = value * 1024
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	total, exists := meminfo[HW_MEMINFO_TOTAL]
+	if exists == false {
+		return 0, fmt.Errorf("%v: %v", HW_PROC_MEMINFO, gopi.ErrUnexpectedResponse)
+	} else if available, exists := meminfo[HW_MEMINFO_AVAILABLE]; exists {
+		return total - available, nil
+	} else if free, exists := meminfo[HW_MEMINFO_FREE]; exists {
+		return total - free, nil
+	} else {
+		return 0, fmt.Errorf("%v: %v", HW_PROC_MEMINFO, gopi.ErrUnexpectedResponse)
+	}
+}
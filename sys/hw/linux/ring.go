@@ -0,0 +1,110 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package linux
+
+import (
+	"math"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// series is the persisted history of a metric, as a ring buffer of
+// buckets which together cover the period of the metric rate
+type series struct {
+	Type    gopi.MetricType `json:"type"`
+	Rate    gopi.MetricRate `json:"rate"`
+	Last    float64         `json:"last"`
+	Buckets []bucket        `json:"buckets"`
+}
+
+// bucket aggregates the values recorded within a bucket width
+type bucket struct {
+	Time  time.Time `json:"time"`
+	Count uint      `json:"count"`
+	Sum   float64   `json:"sum"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	METRIC_BUCKETS_MINUTE = 60 // One second buckets
+	METRIC_BUCKETS_HOUR   = 60 // One minute buckets
+	METRIC_BUCKETS_DAY    = 24 // One hour buckets
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func newSeries(t gopi.MetricType, rate gopi.MetricRate) *series {
+	_, n := bucketWidth(rate)
+	return &series{Type: t, Rate: rate, Buckets: make([]bucket, n)}
+}
+
+// add records a value at a time
+func (this *series) add(ts time.Time, value float64) {
+	width, n := bucketWidth(this.Rate)
+	if len(this.Buckets) != n {
+		this.Buckets = make([]bucket, n)
+	}
+	start := ts.Truncate(width)
+	b := &this.Buckets[int(start.UnixNano()/int64(width))%n]
+	if b.Time.Equal(start) == false {
+		*b = bucket{Time: start, Min: value, Max: value}
+	}
+	b.Count++
+	b.Sum += value
+	b.Min = math.Min(b.Min, value)
+	b.Max = math.Max(b.Max, value)
+	this.Last = value
+}
+
+// stats returns the number of values and the minimum, maximum and
+// mean values over the period which ends at a time
+func (this *series) stats(ts time.Time) (uint, float64, float64, float64) {
+	width, n := bucketWidth(this.Rate)
+	earliest := ts.Truncate(width).Add(-width * time.Duration(n-1))
+	count, sum, min, max := uint(0), float64(0), math.Inf(1), math.Inf(-1)
+	for _, b := range this.Buckets {
+		if b.Count == 0 || b.Time.Before(earliest) || b.Time.After(ts) {
+			continue
+		}
+		count += b.Count
+		sum += b.Sum
+		min = math.Min(min, b.Min)
+		max = math.Max(max, b.Max)
+	}
+	if count == 0 {
+		return 0, 0, 0, 0
+	} else {
+		return count, min, max, sum / float64(count)
+	}
+}
+
+// bucketWidth returns the width and number of buckets for a rate
+func bucketWidth(rate gopi.MetricRate) (time.Duration, int) {
+	switch rate {
+	case gopi.METRIC_RATE_MINUTE:
+		return time.Minute / METRIC_BUCKETS_MINUTE, METRIC_BUCKETS_MINUTE
+	case gopi.METRIC_RATE_HOUR:
+		return time.Hour / METRIC_BUCKETS_HOUR, METRIC_BUCKETS_HOUR
+	case gopi.METRIC_RATE_DAY:
+		return 24 * time.Hour / METRIC_BUCKETS_DAY, METRIC_BUCKETS_DAY
+	default:
+		return time.Minute, 1
+	}
+}
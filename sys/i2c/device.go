@@ -0,0 +1,195 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package i2c
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Device is the i2c-dev ioctl layer, which can be replaced in
+// order to test without /dev/i2c-N
+type Device interface {
+	// Close the device
+	Close() error
+
+	// SetSlave sets the slave address (I2C_SLAVE)
+	SetSlave(addr uint8) error
+
+	// Funcs returns the adapter functionality (I2C_FUNCS)
+	Funcs() (Func, error)
+
+	// SMBus performs a transaction (I2C_SMBUS)
+	SMBus(rw uint8, command uint8, size uint32, data *SMBusData) error
+}
+
+// Func is the adapter functionality bitmask
+type Func uint32
+
+// SMBusData is the union i2c_smbus_data, which holds a byte,
+// a word in host byte order or a block where the first byte
+// is the block length
+type SMBusData [I2C_SMBUS_BLOCK_MAX + 2]byte
+
+type device struct {
+	fh *os.File
+}
+
+type i2c_smbus_ioctl_data struct {
+	rw      uint8
+	command uint8
+	size    uint32
+	data    unsafe.Pointer
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	I2C_SLAVE = 0x0703
+	I2C_FUNCS = 0x0705
+	I2C_SMBUS = 0x0720
+)
+
+const (
+	I2C_SMBUS_WRITE = 0
+	I2C_SMBUS_READ  = 1
+)
+
+const (
+	I2C_SMBUS_QUICK          = 0
+	I2C_SMBUS_BYTE           = 1
+	I2C_SMBUS_BYTE_DATA      = 2
+	I2C_SMBUS_WORD_DATA      = 3
+	I2C_SMBUS_PROC_CALL      = 4
+	I2C_SMBUS_BLOCK_DATA     = 5
+	I2C_SMBUS_I2C_BLOCK_DATA = 8
+	I2C_SMBUS_BLOCK_MAX      = 32
+)
+
+const (
+	I2C_FUNC_SMBUS_QUICK           Func = 0x00010000
+	I2C_FUNC_SMBUS_READ_BYTE       Func = 0x00020000
+	I2C_FUNC_SMBUS_WRITE_BYTE      Func = 0x00040000
+	I2C_FUNC_SMBUS_READ_BYTE_DATA  Func = 0x00080000
+	I2C_FUNC_SMBUS_WRITE_BYTE_DATA Func = 0x00100000
+	I2C_FUNC_SMBUS_READ_WORD_DATA  Func = 0x00200000
+	I2C_FUNC_SMBUS_WRITE_WORD_DATA Func = 0x00400000
+	I2C_FUNC_SMBUS_READ_I2C_BLOCK  Func = 0x04000000
+	I2C_FUNC_SMBUS_WRITE_I2C_BLOCK Func = 0x08000000
+	I2C_FUNC_SMBUS_BYTE_DATA       Func = I2C_FUNC_SMBUS_READ_BYTE_DATA | I2C_FUNC_SMBUS_WRITE_BYTE_DATA
+	I2C_FUNC_SMBUS_WORD_DATA       Func = I2C_FUNC_SMBUS_READ_WORD_DATA | I2C_FUNC_SMBUS_WRITE_WORD_DATA
+)
+
+const (
+	I2C_DEV_PATH = "/dev"
+	I2C_DEV_NAME = "i2c-%v"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// OpenDevice opens /dev/i2c-N for a bus
+func OpenDevice(path string, bus uint) (Device, error) {
+	this := new(device)
+	if path == "" {
+		path = I2C_DEV_PATH
+	}
+	if fh, err := os.OpenFile(filepath.Join(path, fmt.Sprintf(I2C_DEV_NAME, bus)), os.O_RDWR|syscall.O_CLOEXEC, 0); err != nil {
+		return nil, err
+	} else {
+		this.fh = fh
+	}
+	return this, nil
+}
+
+func (this *device) Close() error {
+	return this.fh.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE INTERFACE
+
+func (this *device) SetSlave(addr uint8) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), I2C_SLAVE, uintptr(addr)); errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+func (this *device) Funcs() (Func, error) {
+	var funcs uint64
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), I2C_FUNCS, uintptr(unsafe.Pointer(&funcs))); errno != 0 {
+		return 0, errno
+	} else {
+		return Func(funcs), nil
+	}
+}
+
+func (this *device) SMBus(rw uint8, command uint8, size uint32, data *SMBusData) error {
+	args := i2c_smbus_ioctl_data{
+		rw:      rw,
+		command: command,
+		size:    size,
+		data:    unsafe.Pointer(data),
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), I2C_SMBUS, uintptr(unsafe.Pointer(&args))); errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SMBUS DATA
+
+// Byte returns the byte value
+func (data *SMBusData) Byte() uint8 {
+	return data[0]
+}
+
+// SetByte sets the byte value
+func (data *SMBusData) SetByte(value uint8) {
+	data[0] = value
+}
+
+// Word returns the word value, which is stored in host byte order
+func (data *SMBusData) Word() uint16 {
+	return *(*uint16)(unsafe.Pointer(&data[0]))
+}
+
+// SetWord sets the word value in host byte order
+func (data *SMBusData) SetWord(value uint16) {
+	*(*uint16)(unsafe.Pointer(&data[0])) = value
+}
+
+// Block returns the block, where the first byte is the length
+func (data *SMBusData) Block() []byte {
+	length := int(data[0])
+	if length > I2C_SMBUS_BLOCK_MAX {
+		length = I2C_SMBUS_BLOCK_MAX
+	}
+	return data[1 : length+1]
+}
+
+// SetBlock sets the block and the length
+func (data *SMBusData) SetBlock(value []byte) {
+	data[0] = uint8(copy(data[1:I2C_SMBUS_BLOCK_MAX+1], value))
+}
@@ -0,0 +1,362 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package i2c
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// I2C is the configuration for the i2c-dev driver. Words are read and
+// written using the SMBus convention, where the low byte is
+// transferred first
+type I2C struct {
+	Bus     uint   // Bus number
+	DevPath string // Path to device (default: /dev)
+	Device  Device // Device, or nil to open /dev/i2c-N
+}
+
+type i2c struct {
+	log   gopi.Logger
+	bus   uint
+	dev   Device
+	funcs Func
+	slave uint8
+
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	I2C_SLAVE_NONE = 0xFF
+	I2C_SLAVE_MAX  = 0x7F
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the I2C driver
+func (config I2C) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.i2c.Open{ bus=%v }", config.Bus)
+
+	this := new(i2c)
+	this.log = log
+	this.bus = config.Bus
+	this.slave = I2C_SLAVE_NONE
+
+	// Open the device
+	if config.Device != nil {
+		this.dev = config.Device
+	} else if dev, err := OpenDevice(config.DevPath, config.Bus); err != nil {
+		return nil, err
+	} else {
+		this.dev = dev
+	}
+
+	// Get the adapter functionality
+	if funcs, err := this.dev.Funcs(); err != nil {
+		this.dev.Close()
+		return nil, err
+	} else {
+		this.funcs = funcs
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the I2C driver
+func (this *i2c) Close() error {
+	this.log.Debug("sys.i2c.Close{ bus=%v }", this.bus)
+
+	this.Lock()
+	defer this.Unlock()
+
+	err := this.dev.Close()
+	this.dev = nil
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - SLAVE
+
+// SetSlave sets the current slave address
+func (this *i2c) SetSlave(slave uint8) error {
+	this.log.Debug2("sys.i2c.SetSlave{ slave=0x%02X }", slave)
+
+	this.Lock()
+	defer this.Unlock()
+
+	return this.setSlave(slave)
+}
+
+// GetSlave returns the current slave address, or 0xFF if not set
+func (this *i2c) GetSlave() uint8 {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.slave
+}
+
+// DetectSlave returns true if a slave responds at an address. The
+// probe follows i2cdetect: a read for EEPROM and write-protect ranges
+// and a quick write elsewhere, falling back to a read if quick
+// commands are not supported by the adapter
+func (this *i2c) DetectSlave(slave uint8) (bool, error) {
+	this.log.Debug2("sys.i2c.DetectSlave{ slave=0x%02X }", slave)
+
+	this.Lock()
+	defer this.Unlock()
+
+	// Restore the slave address on return
+	current := this.slave
+	defer func() {
+		if current == I2C_SLAVE_NONE {
+			this.slave = I2C_SLAVE_NONE
+		} else if current != this.slave {
+			this.setSlave(current)
+		}
+	}()
+	if err := this.setSlave(slave); err != nil {
+		return false, err
+	}
+
+	var err error
+	if (slave >= 0x30 && slave <= 0x37) || (slave >= 0x50 && slave <= 0x5F) || this.funcs&I2C_FUNC_SMBUS_QUICK == 0 {
+		var data SMBusData
+		err = this.dev.SMBus(I2C_SMBUS_READ, 0, I2C_SMBUS_BYTE, &data)
+	} else {
+		err = this.dev.SMBus(I2C_SMBUS_WRITE, 0, I2C_SMBUS_QUICK, nil)
+	}
+	switch err {
+	case nil:
+		return true, nil
+	case syscall.ENXIO, syscall.EREMOTEIO, syscall.EIO:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - READ
+
+// ReadUint8 reads a byte from a register
+func (this *i2c) ReadUint8(reg uint8) (uint8, error) {
+	this.log.Debug2("sys.i2c.ReadUint8{ reg=0x%02X }", reg)
+
+	this.Lock()
+	defer this.Unlock()
+
+	return this.readByte(reg)
+}
+
+// ReadInt8 reads a signed byte from a register
+func (this *i2c) ReadInt8(reg uint8) (int8, error) {
+	value, err := this.ReadUint8(reg)
+	return int8(value), err
+}
+
+// ReadUint16 reads a word from a register. If word transactions are
+// not supported, two bytes are read from consecutive registers
+func (this *i2c) ReadUint16(reg uint8) (uint16, error) {
+	this.log.Debug2("sys.i2c.ReadUint16{ reg=0x%02X }", reg)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.check(); err != nil {
+		return 0, err
+	} else if this.funcs&I2C_FUNC_SMBUS_READ_WORD_DATA != 0 {
+		var data SMBusData
+		if err := this.dev.SMBus(I2C_SMBUS_READ, reg, I2C_SMBUS_WORD_DATA, &data); err != nil {
+			return 0, err
+		} else {
+			return data.Word(), nil
+		}
+	} else if lsb, err := this.readByte(reg); err != nil {
+		return 0, err
+	} else if msb, err := this.readByte(reg + 1); err != nil {
+		return 0, err
+	} else {
+		return uint16(msb)<<8 | uint16(lsb), nil
+	}
+}
+
+// ReadInt16 reads a signed word from a register
+func (this *i2c) ReadInt16(reg uint8) (int16, error) {
+	value, err := this.ReadUint16(reg)
+	return int16(value), err
+}
+
+// ReadBlock reads up to 32 bytes starting at a register. If block
+// transactions are not supported, bytes are read from consecutive
+// registers
+func (this *i2c) ReadBlock(reg, length uint8) ([]byte, error) {
+	this.log.Debug2("sys.i2c.ReadBlock{ reg=0x%02X length=%v }", reg, length)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.check(); err != nil {
+		return nil, err
+	} else if length == 0 || length > I2C_SMBUS_BLOCK_MAX {
+		return nil, gopi.ErrBadParameter
+	} else if this.funcs&I2C_FUNC_SMBUS_READ_I2C_BLOCK != 0 {
+		var data SMBusData
+		data[0] = length
+		if err := this.dev.SMBus(I2C_SMBUS_READ, reg, I2C_SMBUS_I2C_BLOCK_DATA, &data); err != nil {
+			return nil, err
+		} else {
+			block := make([]byte, len(data.Block()))
+			copy(block, data.Block())
+			return block, nil
+		}
+	}
+	block := make([]byte, length)
+	for i := range block {
+		if value, err := this.readByte(reg + uint8(i)); err != nil {
+			return nil, err
+		} else {
+			block[i] = value
+		}
+	}
+	return block, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - WRITE
+
+// WriteUint8 writes a byte to a register
+func (this *i2c) WriteUint8(reg, value uint8) error {
+	this.log.Debug2("sys.i2c.WriteUint8{ reg=0x%02X value=0x%02X }", reg, value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	return this.writeByte(reg, value)
+}
+
+// WriteInt8 writes a signed byte to a register
+func (this *i2c) WriteInt8(reg uint8, value int8) error {
+	return this.WriteUint8(reg, uint8(value))
+}
+
+// WriteUint16 writes a word to a register. If word transactions are
+// not supported, two bytes are written to consecutive registers
+func (this *i2c) WriteUint16(reg uint8, value uint16) error {
+	this.log.Debug2("sys.i2c.WriteUint16{ reg=0x%02X value=0x%04X }", reg, value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.check(); err != nil {
+		return err
+	} else if this.funcs&I2C_FUNC_SMBUS_WRITE_WORD_DATA != 0 {
+		var data SMBusData
+		data.SetWord(value)
+		return this.dev.SMBus(I2C_SMBUS_WRITE, reg, I2C_SMBUS_WORD_DATA, &data)
+	} else if err := this.writeByte(reg, uint8(value)); err != nil {
+		return err
+	} else {
+		return this.writeByte(reg+1, uint8(value>>8))
+	}
+}
+
+// WriteInt16 writes a signed word to a register
+func (this *i2c) WriteInt16(reg uint8, value int16) error {
+	return this.WriteUint16(reg, uint16(value))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *i2c) String() string {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.slave == I2C_SLAVE_NONE {
+		return fmt.Sprintf("<sys.i2c>{ bus=%v funcs=0x%08X slave=<nil> }", this.bus, uint32(this.funcs))
+	} else {
+		return fmt.Sprintf("<sys.i2c>{ bus=%v funcs=0x%08X slave=0x%02X }", this.bus, uint32(this.funcs), this.slave)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *i2c) check() error {
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if this.slave == I2C_SLAVE_NONE {
+		return gopi.ErrOutOfOrder
+	} else {
+		return nil
+	}
+}
+
+func (this *i2c) setSlave(slave uint8) error {
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if slave > I2C_SLAVE_MAX {
+		return gopi.ErrBadParameter
+	} else if err := this.dev.SetSlave(slave); err != nil {
+		return err
+	} else {
+		this.slave = slave
+		return nil
+	}
+}
+
+// readByte reads a byte from a register, or writes the register
+// and then reads a byte if byte data transactions are not supported
+func (this *i2c) readByte(reg uint8) (uint8, error) {
+	var data SMBusData
+	if err := this.check(); err != nil {
+		return 0, err
+	} else if this.funcs&I2C_FUNC_SMBUS_READ_BYTE_DATA != 0 {
+		if err := this.dev.SMBus(I2C_SMBUS_READ, reg, I2C_SMBUS_BYTE_DATA, &data); err != nil {
+			return 0, err
+		}
+	} else if this.funcs&(I2C_FUNC_SMBUS_WRITE_BYTE|I2C_FUNC_SMBUS_READ_BYTE) == (I2C_FUNC_SMBUS_WRITE_BYTE | I2C_FUNC_SMBUS_READ_BYTE) {
+		if err := this.dev.SMBus(I2C_SMBUS_WRITE, reg, I2C_SMBUS_BYTE, nil); err != nil {
+			return 0, err
+		} else if err := this.dev.SMBus(I2C_SMBUS_READ, 0, I2C_SMBUS_BYTE, &data); err != nil {
+			return 0, err
+		}
+	} else {
+		return 0, gopi.ErrNotImplemented
+	}
+	return data.Byte(), nil
+}
+
+func (this *i2c) writeByte(reg, value uint8) error {
+	if err := this.check(); err != nil {
+		return err
+	} else if this.funcs&I2C_FUNC_SMBUS_WRITE_BYTE_DATA == 0 {
+		return gopi.ErrNotImplemented
+	} else {
+		var data SMBusData
+		data.SetByte(value)
+		return this.dev.SMBus(I2C_SMBUS_WRITE, reg, I2C_SMBUS_BYTE_DATA, &data)
+	}
+}
@@ -0,0 +1,230 @@
+//go:build linux
+// +build linux
+
+package i2c_test
+
+import (
+	"syscall"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/i2c"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE DEVICE
+
+const (
+	FUNCS_ALL  = i2c.I2C_FUNC_SMBUS_QUICK | i2c.I2C_FUNC_SMBUS_READ_BYTE | i2c.I2C_FUNC_SMBUS_WRITE_BYTE | i2c.I2C_FUNC_SMBUS_BYTE_DATA | i2c.I2C_FUNC_SMBUS_WORD_DATA | i2c.I2C_FUNC_SMBUS_READ_I2C_BLOCK
+	FUNCS_BYTE = i2c.I2C_FUNC_SMBUS_READ_BYTE | i2c.I2C_FUNC_SMBUS_WRITE_BYTE | i2c.I2C_FUNC_SMBUS_WRITE_BYTE_DATA
+)
+
+// device simulates slaves with 256 byte registers, transferring words
+// low byte first as on the wire
+type device struct {
+	funcs  i2c.Func
+	slave  uint8
+	ptr    uint8
+	slaves map[uint8]*[256]byte
+	sizes  []uint32
+}
+
+func newDevice(funcs i2c.Func, slaves ...uint8) *device {
+	this := &device{funcs: funcs, slaves: make(map[uint8]*[256]byte)}
+	for _, slave := range slaves {
+		this.slaves[slave] = new([256]byte)
+	}
+	return this
+}
+
+func (this *device) Close() error {
+	return nil
+}
+
+func (this *device) SetSlave(slave uint8) error {
+	this.slave = slave
+	return nil
+}
+
+func (this *device) Funcs() (i2c.Func, error) {
+	return this.funcs, nil
+}
+
+func (this *device) SMBus(rw uint8, command uint8, size uint32, data *i2c.SMBusData) error {
+	mem, exists := this.slaves[this.slave]
+	if exists == false {
+		return syscall.ENXIO
+	}
+	this.sizes = append(this.sizes, size)
+	switch {
+	case size == i2c.I2C_SMBUS_QUICK:
+		return nil
+	case size == i2c.I2C_SMBUS_BYTE && rw == i2c.I2C_SMBUS_WRITE:
+		this.ptr = command
+	case size == i2c.I2C_SMBUS_BYTE:
+		data.SetByte(mem[this.ptr])
+		this.ptr++
+	case size == i2c.I2C_SMBUS_BYTE_DATA && rw == i2c.I2C_SMBUS_WRITE:
+		mem[command] = data.Byte()
+	case size == i2c.I2C_SMBUS_BYTE_DATA:
+		data.SetByte(mem[command])
+	case size == i2c.I2C_SMBUS_WORD_DATA && rw == i2c.I2C_SMBUS_WRITE:
+		mem[command] = uint8(data.Word())
+		mem[command+1] = uint8(data.Word() >> 8)
+	case size == i2c.I2C_SMBUS_WORD_DATA:
+		data.SetWord(uint16(mem[command]) | uint16(mem[command+1])<<8)
+	case size == i2c.I2C_SMBUS_I2C_BLOCK_DATA && rw == i2c.I2C_SMBUS_READ:
+		data.SetBlock(mem[command : int(command)+int(data[0])])
+	default:
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestI2C_000(t *testing.T) {
+	driver := openI2C(t, newDevice(FUNCS_ALL, 0x40))
+	defer driver.Close()
+
+	if slave := driver.GetSlave(); slave != i2c.I2C_SLAVE_NONE {
+		t.Error("Unexpected slave", slave)
+	}
+	if _, err := driver.ReadUint8(0); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+	if err := driver.SetSlave(0x80); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := driver.SetSlave(0x40); err != nil {
+		t.Error(err)
+	} else if slave := driver.GetSlave(); slave != 0x40 {
+		t.Error("Unexpected slave", slave)
+	}
+}
+
+func TestI2C_001(t *testing.T) {
+	dev := newDevice(FUNCS_ALL, 0x40, 0x50)
+	driver := openI2C(t, dev)
+	defer driver.Close()
+
+	// Detect slaves, restoring the current slave afterwards
+	driver.SetSlave(0x40)
+	for addr := uint8(0x03); addr <= 0x77; addr++ {
+		if found, err := driver.DetectSlave(addr); err != nil {
+			t.Error(err)
+		} else if expected := addr == 0x40 || addr == 0x50; found != expected {
+			t.Errorf("DetectSlave(0x%02X): expected %v", addr, expected)
+		}
+	}
+	if slave := driver.GetSlave(); slave != 0x40 || dev.slave != 0x40 {
+		t.Error("Slave not restored, got", slave)
+	}
+}
+
+func TestI2C_002(t *testing.T) {
+	dev := newDevice(FUNCS_ALL, 0x40)
+	driver := openI2C(t, dev)
+	defer driver.Close()
+
+	// Words are transferred low byte first
+	driver.SetSlave(0x40)
+	dev.slaves[0x40][0x10] = 0x34
+	dev.slaves[0x40][0x11] = 0x12
+	if value, err := driver.ReadUint16(0x10); err != nil {
+		t.Error(err)
+	} else if value != 0x1234 {
+		t.Errorf("Expected 0x1234, got 0x%04X", value)
+	}
+	if err := driver.WriteInt16(0x20, -2); err != nil {
+		t.Error(err)
+	} else if lsb, msb := dev.slaves[0x40][0x20], dev.slaves[0x40][0x21]; lsb != 0xFE || msb != 0xFF {
+		t.Errorf("Unexpected bytes 0x%02X 0x%02X", lsb, msb)
+	} else if value, err := driver.ReadInt16(0x20); err != nil {
+		t.Error(err)
+	} else if value != -2 {
+		t.Error("Expected -2, got", value)
+	}
+	if err := driver.WriteInt8(0x30, -1); err != nil {
+		t.Error(err)
+	} else if value, err := driver.ReadUint8(0x30); err != nil {
+		t.Error(err)
+	} else if value != 0xFF {
+		t.Error("Expected 0xFF, got", value)
+	}
+}
+
+func TestI2C_003(t *testing.T) {
+	dev := newDevice(FUNCS_ALL, 0x40)
+	driver := openI2C(t, dev)
+	defer driver.Close()
+
+	driver.SetSlave(0x40)
+	copy(dev.slaves[0x40][0x80:], []byte{1, 2, 3, 4, 5})
+	if block, err := driver.ReadBlock(0x80, 5); err != nil {
+		t.Error(err)
+	} else if string(block) != string([]byte{1, 2, 3, 4, 5}) {
+		t.Error("Unexpected block", block)
+	}
+	if _, err := driver.ReadBlock(0x80, 33); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestI2C_004(t *testing.T) {
+	// Adapter without word, block or byte data reads uses byte fallbacks
+	dev := newDevice(FUNCS_BYTE, 0x40)
+	driver := openI2C(t, dev)
+	defer driver.Close()
+
+	driver.SetSlave(0x40)
+	copy(dev.slaves[0x40][0x10:], []byte{0x34, 0x12, 0x56})
+	if value, err := driver.ReadUint16(0x10); err != nil {
+		t.Error(err)
+	} else if value != 0x1234 {
+		t.Errorf("Expected 0x1234, got 0x%04X", value)
+	}
+	if block, err := driver.ReadBlock(0x10, 3); err != nil {
+		t.Error(err)
+	} else if string(block) != string([]byte{0x34, 0x12, 0x56}) {
+		t.Error("Unexpected block", block)
+	}
+	if err := driver.WriteUint16(0x20, 0xABCD); err != nil {
+		t.Error(err)
+	} else if lsb, msb := dev.slaves[0x40][0x20], dev.slaves[0x40][0x21]; lsb != 0xCD || msb != 0xAB {
+		t.Errorf("Unexpected bytes 0x%02X 0x%02X", lsb, msb)
+	}
+	for _, size := range dev.sizes {
+		if size == i2c.I2C_SMBUS_WORD_DATA || size == i2c.I2C_SMBUS_I2C_BLOCK_DATA {
+			t.Error("Unexpected transaction size", size)
+		}
+	}
+
+	// Detection falls back to read byte without quick command
+	if found, err := driver.DetectSlave(0x40); err != nil {
+		t.Error(err)
+	} else if found == false {
+		t.Error("Expected slave at 0x40")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openI2C(t *testing.T, dev i2c.Device) gopi.I2C {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(i2c.I2C{Device: dev}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.I2C)
+	}
+}
@@ -0,0 +1,630 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package input
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Device is the configuration for an evdev input device. When the
+// reader is not an evdev device, the name, type and bus should be set
+type Device struct {
+	Path      string               // Path to the device, such as /dev/input/event0
+	Reader    io.Reader            // Event stream, or nil to open the path
+	Name      string               // Name, or empty to read from the device
+	Type      gopi.InputDeviceType // Type, or INPUT_TYPE_NONE to read from the device
+	Bus       gopi.InputDeviceBus  // Bus, or INPUT_BUS_NONE to read from the device
+	Exclusive bool                 // Grab the device so events are not delivered elsewhere
+}
+
+type device struct {
+	log        gopi.Logger
+	path       string
+	name       string
+	devicetype gopi.InputDeviceType
+	bus        gopi.InputDeviceBus
+	r          io.Reader
+	source     gopi.InputDevice
+	done       chan struct{}
+
+	// Decoding state
+	position gopi.Point
+	relative gopi.Point
+	keystate gopi.KeyState
+	scancode uint32
+	rel, abs bool
+	dropped  bool
+	slot     uint
+	slots    []slot
+	noslot   bool
+
+	sync.Mutex
+	event.Publisher
+}
+
+type slot struct {
+	position gopi.Point
+	state    gopi.InputEventType
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	INPUT_NAME_SIZE = 256
+	INPUT_MAX_SLOTS = 16
+	INPUT_IOC_TYPE  = 'E'
+	INPUT_KEY_MAX   = 0x2FF
+	INPUT_BTN_JOY   = 0x120
+	INPUT_BTN_PAD   = 0x130
+	INPUT_BTN_TOUCH = 0x14A
+	INPUT_KEY_A     = 30
+	INPUT_KEY_Z     = 44
+	INPUT_KEY_SPACE = 57
+	INPUT_LOCK_MASK = gopi.KEYSTATE_CAPSLOCK | gopi.KEYSTATE_NUMLOCK | gopi.KEYSTATE_SCROLLLOCK
+)
+
+var (
+	EVIOCGID   = ioctlIOC(_IOC_READ, INPUT_IOC_TYPE, 0x02, 8)
+	EVIOCGNAME = ioctlIOC(_IOC_READ, INPUT_IOC_TYPE, 0x06, INPUT_NAME_SIZE)
+	EVIOCGLED  = ioctlIOC(_IOC_READ, INPUT_IOC_TYPE, 0x19, (LED_MAX+1)/8)
+	EVIOCGRAB  = ioctlIOC(_IOC_WRITE, INPUT_IOC_TYPE, 0x90, 4)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open an input device
+func (config Device) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.input.Device.Open{ path=%v exclusive=%v }", strconv.Quote(config.Path), config.Exclusive)
+
+	this := new(device)
+	this.log = log
+	this.path = config.Path
+	this.done = make(chan struct{})
+	this.slots = make([]slot, 1, INPUT_MAX_SLOTS)
+	this.source = this
+
+	// Open the device
+	if config.Reader != nil {
+		this.r = config.Reader
+	} else if config.Path == "" {
+		return nil, gopi.ErrBadParameter
+	} else if fh, err := openDevice(config.Path); err != nil {
+		return nil, err
+	} else {
+		this.r = fh
+	}
+
+	// Read the name, type, bus and LEDs from evdev devices
+	if conn, ok := this.r.(syscall.Conn); ok {
+		if err := this.probe(conn, config.Exclusive); err != nil {
+			this.close()
+			return nil, err
+		}
+	}
+	if config.Name != "" {
+		this.name = config.Name
+	}
+	if config.Type != gopi.INPUT_TYPE_NONE {
+		this.devicetype = config.Type
+	}
+	if config.Bus != gopi.INPUT_BUS_NONE {
+		this.bus = config.Bus
+	}
+
+	// Read events in the background until the device is closed
+	go this.readTask(this.r)
+
+	// Success
+	return this, nil
+}
+
+// Close the input device
+func (this *device) Close() error {
+	this.log.Debug("sys.input.Device.Close{ name=%v }", strconv.Quote(this.name))
+
+	this.Lock()
+	if this.r == nil {
+		this.Unlock()
+		return nil
+	}
+	err := this.close()
+	this.Unlock()
+
+	// Unsubscribe before waiting, which ends emitting to subscribers
+	// which are not receiving, then wait for the read loop to end
+	this.Publisher.Close()
+	<-this.done
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - DEVICE
+
+func (this *device) Name() string {
+	return this.name
+}
+
+func (this *device) Type() gopi.InputDeviceType {
+	return this.devicetype
+}
+
+func (this *device) Bus() gopi.InputDeviceBus {
+	return this.bus
+}
+
+// Path returns the device path, or empty if the device was opened
+// from a reader
+func (this *device) Path() string {
+	return this.path
+}
+
+func (this *device) Position() gopi.Point {
+	this.Lock()
+	defer this.Unlock()
+	return this.position
+}
+
+func (this *device) SetPosition(position gopi.Point) {
+	this.Lock()
+	defer this.Unlock()
+	this.position = position
+}
+
+func (this *device) KeyState() gopi.KeyState {
+	this.Lock()
+	defer this.Unlock()
+	return this.keystate
+}
+
+// SetKeyState sets caps lock, num lock and scroll lock on or off,
+// and sets the LEDs on devices which are writable. Other key states
+// are not modifiable
+func (this *device) SetKeyState(flags gopi.KeyState, state bool) error {
+	this.log.Debug2("sys.input.Device.SetKeyState{ flags=%v state=%v }", flags, state)
+
+	if flags == gopi.KEYSTATE_NONE || flags&^INPUT_LOCK_MASK != 0 {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.r == nil {
+		return gopi.ErrOutOfOrder
+	} else if state {
+		this.keystate |= flags
+	} else {
+		this.keystate &^= flags
+	}
+	return this.setLEDs(this.keystate)
+}
+
+// Matches returns true if the device matches a name or path, one of
+// the device types and the bus. An empty name, INPUT_TYPE_NONE and
+// INPUT_BUS_NONE match any device
+func (this *device) Matches(name string, flags gopi.InputDeviceType, bus gopi.InputDeviceBus) bool {
+	if name != "" && name != this.name && name != this.path && name != filepath.Base(this.path) {
+		return false
+	} else if flags != gopi.INPUT_TYPE_NONE && flags&this.devicetype == 0 {
+		return false
+	} else if bus != gopi.INPUT_BUS_NONE && bus != gopi.INPUT_BUS_ANY && bus != this.bus {
+		return false
+	} else {
+		return true
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *device) String() string {
+	return fmt.Sprintf("<sys.input.device>{ name=%v type=%v bus=%v path=%v keystate=%v }", strconv.Quote(this.name), this.devicetype, this.bus, strconv.Quote(this.path), this.keystate)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// readTask reads events until the reader ends or the device is closed
+func (this *device) readTask(r io.Reader) {
+	defer close(this.done)
+	for {
+		raw, err := ReadEvent(r)
+		if err != nil {
+			return
+		}
+		this.Lock()
+		events, leds := this.decode(raw)
+		if leds {
+			if err := this.setLEDs(this.keystate); err != nil {
+				this.log.Debug2("sys.input.Device: SetLEDs: %v", err)
+			}
+		}
+		this.Unlock()
+		for _, evt := range events {
+			this.Emit(evt)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// decode updates the device state from a raw event, and returns the
+// events to emit and whether the lock LEDs have changed
+func (this *device) decode(raw RawEvent) ([]gopi.Event, bool) {
+	ts := raw.Timestamp()
+
+	// After events are dropped, ignore events until the next report
+	if raw.Type == EV_SYN && raw.Code == SYN_DROPPED {
+		this.dropped = true
+		return nil, false
+	} else if this.dropped {
+		if raw.Type == EV_SYN && raw.Code == SYN_REPORT {
+			this.dropped = false
+			this.reset()
+		}
+		return nil, false
+	}
+
+	switch raw.Type {
+	case EV_SYN:
+		if raw.Code == SYN_REPORT {
+			return this.report(ts), false
+		}
+	case EV_MSC:
+		if raw.Code == MSC_SCAN {
+			this.scancode = uint32(raw.Value)
+		}
+	case EV_KEY:
+		return this.key(ts, gopi.KeyCode(raw.Code), raw.Value)
+	case EV_LED:
+		if flag := ledKeyState(raw.Code); flag != gopi.KEYSTATE_NONE {
+			if raw.Value != 0 {
+				this.keystate |= flag
+			} else {
+				this.keystate &^= flag
+			}
+		}
+	case EV_REL:
+		switch raw.Code {
+		case REL_X:
+			this.relative.X += float32(raw.Value)
+			this.rel = true
+		case REL_Y:
+			this.relative.Y += float32(raw.Value)
+			this.rel = true
+		}
+	case EV_ABS:
+		this.absolute(raw.Code, raw.Value)
+	}
+	return nil, false
+}
+
+// key returns a key event, and updates modifier and lock key states
+func (this *device) key(ts time.Duration, keycode gopi.KeyCode, value int32) ([]gopi.Event, bool) {
+	var eventtype gopi.InputEventType
+	switch value {
+	case KEY_RELEASE:
+		eventtype = gopi.INPUT_EVENT_KEYRELEASE
+	case KEY_PRESS:
+		eventtype = gopi.INPUT_EVENT_KEYPRESS
+	case KEY_REPEAT:
+		eventtype = gopi.INPUT_EVENT_KEYREPEAT
+	default:
+		return nil, false
+	}
+
+	leds := false
+	if flag := modifierKeyState(keycode); flag != gopi.KEYSTATE_NONE {
+		if eventtype == gopi.INPUT_EVENT_KEYPRESS {
+			this.keystate |= flag
+		} else if eventtype == gopi.INPUT_EVENT_KEYRELEASE {
+			this.keystate &^= flag
+		}
+	} else if flag := lockKeyState(keycode); flag != gopi.KEYSTATE_NONE && eventtype == gopi.INPUT_EVENT_KEYPRESS {
+		this.keystate ^= flag
+		leds = true
+	}
+
+	evt := &evt{
+		source:     this.source,
+		timestamp:  ts,
+		devicetype: this.devicetype,
+		eventtype:  eventtype,
+		keycode:    keycode,
+		keystate:   this.keystate,
+		scancode:   this.scancode,
+		position:   this.position,
+	}
+	this.scancode = 0
+	return []gopi.Event{evt}, leds
+}
+
+// absolute updates the absolute position or multi-touch slots
+func (this *device) absolute(code uint16, value int32) {
+	switch code {
+	case ABS_X:
+		this.position.X = float32(value)
+		this.abs = true
+	case ABS_Y:
+		this.position.Y = float32(value)
+		this.abs = true
+	case ABS_MT_SLOT:
+		// Drop multi-touch events until a valid slot is selected
+		if value >= 0 && value < INPUT_MAX_SLOTS {
+			this.slot = uint(value)
+			this.noslot = false
+			for uint(len(this.slots)) <= this.slot {
+				this.slots = append(this.slots, slot{})
+			}
+		} else {
+			this.noslot = true
+		}
+	case ABS_MT_TRACKING_ID:
+		if this.noslot {
+			return
+		} else if value < 0 {
+			this.slots[this.slot].state = gopi.INPUT_EVENT_TOUCHRELEASE
+		} else {
+			this.slots[this.slot].state = gopi.INPUT_EVENT_TOUCHPRESS
+		}
+	case ABS_MT_POSITION_X, ABS_MT_POSITION_Y:
+		if this.noslot {
+			return
+		} else if code == ABS_MT_POSITION_X {
+			this.slots[this.slot].position.X = float32(value)
+		} else {
+			this.slots[this.slot].position.Y = float32(value)
+		}
+		if this.slots[this.slot].state == gopi.INPUT_EVENT_NONE {
+			this.slots[this.slot].state = gopi.INPUT_EVENT_TOUCHPOSITION
+		}
+	}
+}
+
+// report returns position and touch events at the end of a frame
+func (this *device) report(ts time.Duration) []gopi.Event {
+	events := make([]gopi.Event, 0, 2)
+	if this.rel {
+		this.position.X += this.relative.X
+		this.position.Y += this.relative.Y
+		events = append(events, &evt{
+			source:     this.source,
+			timestamp:  ts,
+			devicetype: this.devicetype,
+			eventtype:  gopi.INPUT_EVENT_RELPOSITION,
+			keystate:   this.keystate,
+			position:   this.position,
+			relative:   this.relative,
+		})
+	}
+	if this.abs {
+		events = append(events, &evt{
+			source:     this.source,
+			timestamp:  ts,
+			devicetype: this.devicetype,
+			eventtype:  gopi.INPUT_EVENT_ABSPOSITION,
+			keystate:   this.keystate,
+			position:   this.position,
+		})
+	}
+	for i, slot := range this.slots {
+		if slot.state != gopi.INPUT_EVENT_NONE {
+			events = append(events, &evt{
+				source:     this.source,
+				timestamp:  ts,
+				devicetype: this.devicetype,
+				eventtype:  slot.state,
+				keystate:   this.keystate,
+				position:   slot.position,
+				slot:       uint(i),
+			})
+		}
+	}
+	this.reset()
+	return events
+}
+
+// reset clears the state for the current frame
+func (this *device) reset() {
+	this.relative = gopi.Point{}
+	this.rel, this.abs = false, false
+	this.scancode = 0
+	for i := range this.slots {
+		this.slots[i].state = gopi.INPUT_EVENT_NONE
+	}
+}
+
+// close closes the reader if it can be closed
+func (this *device) close() error {
+	r := this.r
+	this.r = nil
+	if closer, ok := r.(io.Closer); ok {
+		return closer.Close()
+	} else {
+		return nil
+	}
+}
+
+// setLEDs writes the lock key states to the device if it is writable
+func (this *device) setLEDs(state gopi.KeyState) error {
+	w, ok := this.r.(io.Writer)
+	if ok == false {
+		return nil
+	}
+	for _, code := range []uint16{LED_NUML, LED_CAPSL, LED_SCROLLL} {
+		value := int32(0)
+		if state&ledKeyState(code) != 0 {
+			value = 1
+		}
+		if err := WriteEvent(w, RawEvent{Type: EV_LED, Code: code, Value: value}); err != nil {
+			return err
+		}
+	}
+	return WriteEvent(w, RawEvent{Type: EV_SYN, Code: SYN_REPORT})
+}
+
+// probe reads the name, type, bus and LEDs from an evdev device,
+// and grabs the device if exclusive. Files which are not evdev
+// devices are ignored
+func (this *device) probe(conn syscall.Conn, exclusive bool) error {
+	name := make([]byte, INPUT_NAME_SIZE)
+	if err := ioctl(conn, EVIOCGNAME, name); err == syscall.ENOTTY || err == syscall.EINVAL {
+		return nil
+	} else if err != nil {
+		return err
+	} else {
+		this.name = cstring(name)
+	}
+
+	id := make([]byte, 8)
+	if err := ioctl(conn, EVIOCGID, id); err != nil {
+		return err
+	} else {
+		this.bus = gopi.InputDeviceBus(nativeUint16(id[0:2]))
+	}
+
+	// Determine the type from the capabilities
+	keys, rel, abs := make([]byte, (INPUT_KEY_MAX+8)/8), make([]byte, 2), make([]byte, (ABS_MAX+8)/8)
+	if err := ioctl(conn, ioctlEVIOCGBIT(EV_KEY, len(keys)), keys); err != nil {
+		return err
+	} else if err := ioctl(conn, ioctlEVIOCGBIT(EV_REL, len(rel)), rel); err != nil {
+		return err
+	} else if err := ioctl(conn, ioctlEVIOCGBIT(EV_ABS, len(abs)), abs); err != nil {
+		return err
+	} else {
+		this.devicetype = deviceType(keys, rel, abs)
+	}
+
+	// Read the LED states
+	leds := make([]byte, (LED_MAX+1)/8)
+	if err := ioctl(conn, EVIOCGLED, leds); err != nil {
+		return err
+	}
+	for _, code := range []uint16{LED_NUML, LED_CAPSL, LED_SCROLLL} {
+		if bit(leds, int(code)) {
+			this.keystate |= ledKeyState(code)
+		}
+	}
+
+	// Grab the device
+	if exclusive {
+		if err := ioctlValue(conn, EVIOCGRAB, 1); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// openDevice opens a device non-blocking so that closing the file
+// ends the read loop, and read-only if it is not writable
+func openDevice(path string) (*os.File, error) {
+	flags := syscall.O_NONBLOCK | syscall.O_CLOEXEC
+	if fh, err := os.OpenFile(path, os.O_RDWR|flags, 0); os.IsPermission(err) {
+		return os.OpenFile(path, os.O_RDONLY|flags, 0)
+	} else {
+		return fh, err
+	}
+}
+
+// deviceType returns the device type from the key, relative and
+// absolute capability bits
+func deviceType(keys, rel, abs []byte) gopi.InputDeviceType {
+	switch {
+	case bit(abs, ABS_MT_POSITION_X) || bit(keys, INPUT_BTN_TOUCH):
+		return gopi.INPUT_TYPE_TOUCHSCREEN
+	case bit(rel, REL_X) && bit(rel, REL_Y):
+		return gopi.INPUT_TYPE_MOUSE
+	case bit(abs, ABS_X) && (bit(keys, INPUT_BTN_JOY) || bit(keys, INPUT_BTN_PAD)):
+		return gopi.INPUT_TYPE_JOYSTICK
+	case bit(keys, INPUT_KEY_A) && bit(keys, INPUT_KEY_Z) && bit(keys, INPUT_KEY_SPACE):
+		return gopi.INPUT_TYPE_KEYBOARD
+	}
+	for _, b := range keys {
+		if b != 0 {
+			return gopi.INPUT_TYPE_REMOTE
+		}
+	}
+	return gopi.INPUT_TYPE_NONE
+}
+
+// modifierKeyState returns the key state for a modifier key
+func modifierKeyState(keycode gopi.KeyCode) gopi.KeyState {
+	switch keycode {
+	case gopi.KEYCODE_LEFTSHIFT:
+		return gopi.KEYSTATE_LEFTSHIFT
+	case gopi.KEYCODE_RIGHTSHIFT:
+		return gopi.KEYSTATE_RIGHTSHIFT
+	case gopi.KEYCODE_LEFTALT:
+		return gopi.KEYSTATE_LEFTALT
+	case gopi.KEYCODE_RIGHTALT:
+		return gopi.KEYSTATE_RIGHTALT
+	case gopi.KEYCODE_LEFTMETA:
+		return gopi.KEYSTATE_LEFTMETA
+	case gopi.KEYCODE_RIGHTMETA:
+		return gopi.KEYSTATE_RIGHTMETA
+	case gopi.KEYCODE_LEFTCTRL:
+		return gopi.KEYSTATE_LEFTCTRL
+	case gopi.KEYCODE_RIGHTCTRL:
+		return gopi.KEYSTATE_RIGHTCTRL
+	default:
+		return gopi.KEYSTATE_NONE
+	}
+}
+
+// lockKeyState returns the key state for a lock key
+func lockKeyState(keycode gopi.KeyCode) gopi.KeyState {
+	switch keycode {
+	case gopi.KEYCODE_CAPSLOCK:
+		return gopi.KEYSTATE_CAPSLOCK
+	case gopi.KEYCODE_NUMLOCK:
+		return gopi.KEYSTATE_NUMLOCK
+	case gopi.KEYCODE_SCROLLLOCK:
+		return gopi.KEYSTATE_SCROLLLOCK
+	default:
+		return gopi.KEYSTATE_NONE
+	}
+}
+
+// ledKeyState returns the key state for an LED
+func ledKeyState(code uint16) gopi.KeyState {
+	switch code {
+	case LED_CAPSL:
+		return gopi.KEYSTATE_CAPSLOCK
+	case LED_NUML:
+		return gopi.KEYSTATE_NUMLOCK
+	case LED_SCROLLL:
+		return gopi.KEYSTATE_SCROLLLOCK
+	default:
+		return gopi.KEYSTATE_NONE
+	}
+}
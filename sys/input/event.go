@@ -0,0 +1,95 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package input
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type evt struct {
+	source     gopi.Driver
+	timestamp  time.Duration
+	devicetype gopi.InputDeviceType
+	eventtype  gopi.InputEventType
+	keycode    gopi.KeyCode
+	keystate   gopi.KeyState
+	scancode   uint32
+	position   gopi.Point
+	relative   gopi.Point
+	slot       uint
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENT INTERFACE
+
+func (this *evt) Name() string {
+	return "InputEvent"
+}
+
+func (this *evt) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *evt) Timestamp() time.Duration {
+	return this.timestamp
+}
+
+func (this *evt) DeviceType() gopi.InputDeviceType {
+	return this.devicetype
+}
+
+func (this *evt) EventType() gopi.InputEventType {
+	return this.eventtype
+}
+
+func (this *evt) KeyCode() gopi.KeyCode {
+	return this.keycode
+}
+
+func (this *evt) KeyState() gopi.KeyState {
+	return this.keystate
+}
+
+func (this *evt) ScanCode() uint32 {
+	return this.scancode
+}
+
+func (this *evt) Position() gopi.Point {
+	return this.position
+}
+
+func (this *evt) Relative() gopi.Point {
+	return this.relative
+}
+
+func (this *evt) Slot() uint {
+	return this.slot
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *evt) String() string {
+	switch this.eventtype {
+	case gopi.INPUT_EVENT_KEYPRESS, gopi.INPUT_EVENT_KEYRELEASE, gopi.INPUT_EVENT_KEYREPEAT:
+		return fmt.Sprintf("<sys.input.event>{ type=%v device=%v keycode=%v keystate=%v scancode=0x%X ts=%v }", this.eventtype, this.devicetype, this.keycode, this.keystate, this.scancode, this.timestamp)
+	case gopi.INPUT_EVENT_TOUCHPRESS, gopi.INPUT_EVENT_TOUCHRELEASE, gopi.INPUT_EVENT_TOUCHPOSITION:
+		return fmt.Sprintf("<sys.input.event>{ type=%v device=%v slot=%v position=%v ts=%v }", this.eventtype, this.devicetype, this.slot, this.position, this.timestamp)
+	default:
+		return fmt.Sprintf("<sys.input.event>{ type=%v device=%v position=%v relative=%v ts=%v }", this.eventtype, this.devicetype, this.position, this.relative, this.timestamp)
+	}
+}
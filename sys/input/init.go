@@ -0,0 +1,37 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package input
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register input manager
+	gopi.RegisterModule(gopi.Module{
+		Name: "sys/input",
+		Type: gopi.MODULE_TYPE_INPUT,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagBool("input.exclusive", false, "Grab input devices")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			exclusive, _ := app.AppFlags.GetBool("input.exclusive")
+			return gopi.Open(Input{
+				Exclusive: exclusive,
+			}, app.Logger)
+		},
+	})
+}
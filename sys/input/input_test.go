@@ -0,0 +1,504 @@
+//go:build linux
+// +build linux
+
+package input_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/input"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE DEVICE
+
+// stream reads events from a pipe, and records events written
+type stream struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+
+	sync.Mutex
+	sent bytes.Buffer
+}
+
+func newStream() *stream {
+	r, w := io.Pipe()
+	return &stream{r: r, w: w}
+}
+
+func (this *stream) Read(data []byte) (int, error) {
+	return this.r.Read(data)
+}
+
+func (this *stream) Write(data []byte) (int, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.sent.Write(data)
+}
+
+func (this *stream) Close() error {
+	return this.r.Close()
+}
+
+// send writes raw events, in a frame which ends with SYN_REPORT
+func (this *stream) send(t *testing.T, events ...input.RawEvent) {
+	t.Helper()
+	events = append(events, input.RawEvent{Type: input.EV_SYN, Code: input.SYN_REPORT})
+	go func() {
+		for _, evt := range events {
+			if err := input.WriteEvent(this.w, evt); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// leds returns the LED events written
+func (this *stream) leds(t *testing.T) map[uint16]int32 {
+	t.Helper()
+	this.Lock()
+	defer this.Unlock()
+	leds := make(map[uint16]int32)
+	for this.sent.Len() > 0 {
+		if evt, err := input.ReadEvent(&this.sent); err != nil {
+			t.Fatal(err)
+		} else if evt.Type == input.EV_LED {
+			leds[evt.Code] = evt.Value
+		}
+	}
+	return leds
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestInput_000(t *testing.T) {
+	// Raw events round trip
+	buf := new(bytes.Buffer)
+	evt := input.NewRawEvent(1500*time.Millisecond, input.EV_KEY, uint16(gopi.KEYCODE_A), 1)
+	if err := input.WriteEvent(buf, evt); err != nil {
+		t.Fatal(err)
+	} else if buf.Len() != input.RAW_EVENT_SIZE {
+		t.Error("Unexpected size", buf.Len())
+	} else if evt_, err := input.ReadEvent(buf); err != nil {
+		t.Fatal(err)
+	} else if evt_ != evt {
+		t.Error("Expected", evt, "got", evt_)
+	} else if evt_.Timestamp() != 1500*time.Millisecond {
+		t.Error("Unexpected timestamp", evt_.Timestamp())
+	} else if _, err := input.ReadEvent(buf); err != io.EOF {
+		t.Error("Expected EOF, got", err)
+	}
+}
+
+func TestInput_001(t *testing.T) {
+	dev := newStream()
+	device := openDevice(t, dev, gopi.INPUT_TYPE_KEYBOARD)
+	defer device.Close()
+	events := device.Subscribe()
+
+	// Scancode and modifiers
+	dev.send(t,
+		key(gopi.KEYCODE_LEFTSHIFT, 1),
+		input.RawEvent{Type: input.EV_MSC, Code: input.MSC_SCAN, Value: 0x70004},
+		key(gopi.KEYCODE_A, 1),
+	)
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_KEYPRESS || evt.KeyCode() != gopi.KEYCODE_LEFTSHIFT {
+		t.Error("Unexpected event", evt)
+	} else if evt := next(t, events); evt.KeyCode() != gopi.KEYCODE_A || evt.ScanCode() != 0x70004 {
+		t.Error("Unexpected event", evt)
+	} else if evt.KeyState() != gopi.KEYSTATE_LEFTSHIFT || evt.DeviceType() != gopi.INPUT_TYPE_KEYBOARD {
+		t.Error("Unexpected event", evt)
+	}
+	dev.send(t, key(gopi.KEYCODE_A, 2), key(gopi.KEYCODE_LEFTSHIFT, 0))
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_KEYREPEAT || evt.ScanCode() != 0 {
+		t.Error("Unexpected event", evt)
+	} else if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_KEYRELEASE || evt.KeyState() != gopi.KEYSTATE_NONE {
+		t.Error("Unexpected event", evt)
+	}
+
+	// Caps lock toggles on press and sets the LED
+	dev.send(t, key(gopi.KEYCODE_CAPSLOCK, 1), key(gopi.KEYCODE_CAPSLOCK, 0))
+	next(t, events)
+	if evt := next(t, events); evt.KeyState() != gopi.KEYSTATE_CAPSLOCK {
+		t.Error("Unexpected event", evt)
+	} else if leds := dev.leds(t); leds[input.LED_CAPSL] != 1 || leds[input.LED_NUML] != 0 {
+		t.Error("Unexpected LEDs", leds)
+	}
+
+	// Set key state
+	if err := device.SetKeyState(gopi.KEYSTATE_CAPSLOCK|gopi.KEYSTATE_NUMLOCK, false); err != nil {
+		t.Error(err)
+	} else if device.KeyState() != gopi.KEYSTATE_NONE {
+		t.Error("Unexpected key state", device.KeyState())
+	} else if leds := dev.leds(t); leds[input.LED_CAPSL] != 0 {
+		t.Error("Unexpected LEDs", leds)
+	} else if err := device.SetKeyState(gopi.KEYSTATE_LEFTSHIFT, true); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// LED events from the device set the key state
+	dev.send(t, input.RawEvent{Type: input.EV_LED, Code: input.LED_NUML, Value: 1}, key(gopi.KEYCODE_B, 1))
+	if evt := next(t, events); evt.KeyState() != gopi.KEYSTATE_NUMLOCK {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+func TestInput_002(t *testing.T) {
+	dev := newStream()
+	device := openDevice(t, dev, gopi.INPUT_TYPE_MOUSE)
+	defer device.Close()
+	events := device.Subscribe()
+
+	// Relative events are summed in a frame
+	device.SetPosition(gopi.Point{X: 100, Y: 100})
+	dev.send(t,
+		input.RawEvent{Type: input.EV_REL, Code: input.REL_X, Value: 5},
+		input.RawEvent{Type: input.EV_REL, Code: input.REL_Y, Value: -10},
+		input.RawEvent{Type: input.EV_REL, Code: input.REL_X, Value: 5},
+	)
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_RELPOSITION {
+		t.Error("Unexpected event", evt)
+	} else if evt.Relative() != (gopi.Point{X: 10, Y: -10}) || evt.Position() != (gopi.Point{X: 110, Y: 90}) {
+		t.Error("Unexpected event", evt)
+	} else if device.Position() != (gopi.Point{X: 110, Y: 90}) {
+		t.Error("Unexpected position", device.Position())
+	}
+
+	// Events are dropped until the next report
+	dev.send(t,
+		input.RawEvent{Type: input.EV_REL, Code: input.REL_X, Value: 5},
+		input.RawEvent{Type: input.EV_SYN, Code: input.SYN_DROPPED},
+		input.RawEvent{Type: input.EV_REL, Code: input.REL_X, Value: 5},
+	)
+	dev.send(t, input.RawEvent{Type: input.EV_ABS, Code: input.ABS_X, Value: 20})
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_ABSPOSITION || evt.Position() != (gopi.Point{X: 20, Y: 90}) {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+func TestInput_003(t *testing.T) {
+	dev := newStream()
+	device := openDevice(t, dev, gopi.INPUT_TYPE_TOUCHSCREEN)
+	defer device.Close()
+	events := device.Subscribe()
+
+	// Two touches
+	dev.send(t,
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_SLOT, Value: 0},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_TRACKING_ID, Value: 10},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_X, Value: 1},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_Y, Value: 2},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_SLOT, Value: 1},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_TRACKING_ID, Value: 11},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_X, Value: 3},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_Y, Value: 4},
+	)
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_TOUCHPRESS || evt.Slot() != 0 || evt.Position() != (gopi.Point{X: 1, Y: 2}) {
+		t.Error("Unexpected event", evt)
+	} else if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_TOUCHPRESS || evt.Slot() != 1 || evt.Position() != (gopi.Point{X: 3, Y: 4}) {
+		t.Error("Unexpected event", evt)
+	}
+
+	// Move the second touch, and release the first
+	dev.send(t,
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_X, Value: 5},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_SLOT, Value: 0},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_TRACKING_ID, Value: -1},
+	)
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_TOUCHRELEASE || evt.Slot() != 0 {
+		t.Error("Unexpected event", evt)
+	} else if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_TOUCHPOSITION || evt.Slot() != 1 || evt.Position() != (gopi.Point{X: 5, Y: 4}) {
+		t.Error("Unexpected event", evt)
+	}
+
+	// Events for a slot out of range are dropped until a valid slot
+	// is selected
+	dev.send(t,
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_SLOT, Value: input.INPUT_MAX_SLOTS},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_TRACKING_ID, Value: 12},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_X, Value: 6},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_SLOT, Value: 1},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_POSITION_Y, Value: 7},
+	)
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_TOUCHPOSITION || evt.Slot() != 1 || evt.Position() != (gopi.Point{X: 5, Y: 7}) {
+		t.Error("Unexpected event", evt)
+	}
+	dev.send(t,
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_SLOT, Value: -1},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_MT_TRACKING_ID, Value: -1},
+		input.RawEvent{Type: input.EV_ABS, Code: input.ABS_X, Value: 8},
+	)
+	if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_ABSPOSITION {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+func TestInput_004(t *testing.T) {
+	dev := newStream()
+	device := openDevice(t, dev, gopi.INPUT_TYPE_REMOTE)
+	defer device.Close()
+
+	if device.Matches("", gopi.INPUT_TYPE_ANY, gopi.INPUT_BUS_ANY) == false {
+		t.Error("Expected match")
+	} else if device.Matches("test", gopi.INPUT_TYPE_KEYBOARD|gopi.INPUT_TYPE_REMOTE, gopi.INPUT_BUS_NONE) == false {
+		t.Error("Expected match")
+	} else if device.Matches("test", gopi.INPUT_TYPE_KEYBOARD, gopi.INPUT_BUS_NONE) {
+		t.Error("Unexpected match")
+	} else if device.Matches("other", gopi.INPUT_TYPE_ANY, gopi.INPUT_BUS_NONE) {
+		t.Error("Unexpected match")
+	} else if device.Matches("", gopi.INPUT_TYPE_ANY, gopi.INPUT_BUS_USB) == false {
+		t.Error("Expected match")
+	} else if device.Matches("", gopi.INPUT_TYPE_ANY, gopi.INPUT_BUS_BLUETOOTH) {
+		t.Error("Unexpected match")
+	}
+}
+
+func TestInput_005(t *testing.T) {
+	// Files in the device path which are not evdev devices are
+	// matched by path
+	tmp, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	for _, name := range []string{"event0", "event1", "mouse0"} {
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := openManager(t, tmp)
+	defer manager.Close()
+
+	if devices, err := manager.OpenDevicesByName("event1", gopi.INPUT_TYPE_NONE, gopi.INPUT_BUS_ANY); err != nil {
+		t.Error(err)
+	} else if len(devices) != 1 || devices[0].Name() != "" {
+		t.Error("Unexpected devices", devices)
+	} else if devices, err := manager.OpenDevicesByName("", gopi.INPUT_TYPE_NONE, gopi.INPUT_BUS_ANY); err != nil {
+		t.Error(err)
+	} else if len(devices) != 1 {
+		t.Error("Unexpected devices", devices)
+	} else if len(manager.GetOpenDevices()) != 2 {
+		t.Error("Unexpected open devices", manager.GetOpenDevices())
+	} else if err := manager.CloseDevice(devices[0]); err != nil {
+		t.Error(err)
+	} else if err := manager.CloseDevice(devices[0]); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	} else if len(manager.GetOpenDevices()) != 1 {
+		t.Error("Unexpected open devices", manager.GetOpenDevices())
+	}
+}
+
+func TestInput_006(t *testing.T) {
+	// Events from added devices are emitted by the manager
+	manager := openManager(t, "")
+	defer manager.Close()
+	dev := newStream()
+	device := openDevice(t, dev, gopi.INPUT_TYPE_KEYBOARD)
+	events := manager.Subscribe()
+
+	if err := manager.AddDevice(device); err != nil {
+		t.Fatal(err)
+	} else if err := manager.AddDevice(device); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	dev.send(t, key(gopi.KEYCODE_ENTER, 1))
+	if evt := next(t, events); evt.KeyCode() != gopi.KEYCODE_ENTER || evt.Source() != device {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.Logger)
+	}
+}
+
+func openDevice(t *testing.T, r io.Reader, devicetype gopi.InputDeviceType) gopi.InputDevice {
+	t.Helper()
+	if driver, err := gopi.Open(input.Device{Reader: r, Name: "test", Type: devicetype, Bus: gopi.INPUT_BUS_USB}, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.InputDevice)
+	}
+}
+
+func openManager(t *testing.T, path string) gopi.InputManager {
+	t.Helper()
+	if driver, err := gopi.Open(input.Input{DevPath: path}, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.InputManager)
+	}
+}
+
+func key(keycode gopi.KeyCode, value int32) input.RawEvent {
+	return input.RawEvent{Type: input.EV_KEY, Code: uint16(keycode), Value: value}
+}
+
+func next(t *testing.T, events <-chan gopi.Event) gopi.InputEvent {
+	t.Helper()
+	select {
+	case evt := <-events:
+		return evt.(gopi.InputEvent)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+		return nil
+	}
+}
+
+func TestInput_007(t *testing.T) {
+	// Virtual devices emit in-process through the manager when
+	// uinput is unavailable
+	tmp, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	manager := openManager(t, tmp)
+	defer manager.Close()
+	events := manager.Subscribe()
+
+	device := openVirtual(t, input.Virtual{
+		Name:    "virtual",
+		Type:    gopi.INPUT_TYPE_KEYBOARD | gopi.INPUT_TYPE_MOUSE,
+		Manager: manager,
+		DevPath: filepath.Join(tmp, "uinput"),
+	})
+	if device.UInput() {
+		t.Error("Expected in-process device")
+	} else if device.Bus() != gopi.INPUT_BUS_VIRTUAL || device.Matches("virtual", gopi.INPUT_TYPE_MOUSE, gopi.INPUT_BUS_VIRTUAL) == false {
+		t.Error("Unexpected device", device)
+	} else if devices := manager.GetOpenDevices(); len(devices) != 1 || devices[0] != device {
+		t.Error("Unexpected open devices", devices)
+	}
+
+	if err := device.Send(input.Event{Type: gopi.INPUT_EVENT_KEYPRESS, KeyCode: gopi.KEYCODE_LEFTCTRL}); err != nil {
+		t.Error(err)
+	} else if evt := next(t, events); evt.KeyCode() != gopi.KEYCODE_LEFTCTRL || evt.Source() != device {
+		t.Error("Unexpected event", evt)
+	}
+	if err := device.Send(input.Event{Type: gopi.INPUT_EVENT_KEYPRESS, KeyCode: gopi.KEYCODE_C, ScanCode: 0x2E}); err != nil {
+		t.Error(err)
+	} else if evt := next(t, events); evt.KeyCode() != gopi.KEYCODE_C || evt.ScanCode() != 0x2E || evt.KeyState() != gopi.KEYSTATE_LEFTCTRL {
+		t.Error("Unexpected event", evt)
+	}
+	if err := device.Send(input.Event{Type: gopi.INPUT_EVENT_RELPOSITION, Relative: gopi.Point{X: 3, Y: 4}}); err != nil {
+		t.Error(err)
+	} else if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_RELPOSITION || evt.Position() != (gopi.Point{X: 3, Y: 4}) {
+		t.Error("Unexpected event", evt)
+	}
+	if err := device.Send(input.Event{Type: gopi.INPUT_EVENT_TOUCHPRESS, Slot: 2, Position: gopi.Point{X: 5, Y: 6}}); err != nil {
+		t.Error(err)
+	} else if evt := next(t, events); evt.EventType() != gopi.INPUT_EVENT_TOUCHPRESS || evt.Slot() != 2 || evt.Position() != (gopi.Point{X: 5, Y: 6}) {
+		t.Error("Unexpected event", evt)
+	}
+
+	// Invalid events
+	for _, evt := range []input.Event{
+		{Type: gopi.INPUT_EVENT_NONE},
+		{Type: gopi.INPUT_EVENT_KEYPRESS},
+		{Type: gopi.INPUT_EVENT_TOUCHRELEASE, Slot: 100},
+	} {
+		if err := device.Send(evt); err != gopi.ErrBadParameter {
+			t.Error(evt, "Expected ErrBadParameter, got", err)
+		}
+	}
+
+	// Closing the device removes it from the manager
+	if err := device.Close(); err != nil {
+		t.Error(err)
+	} else if devices := manager.GetOpenDevices(); len(devices) != 0 {
+		t.Error("Unexpected open devices", devices)
+	} else if err := device.Send(input.Event{Type: gopi.INPUT_EVENT_KEYPRESS, KeyCode: gopi.KEYCODE_C}); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+}
+
+func TestInput_008(t *testing.T) {
+	dev := newStream()
+	device := openDevice(t, dev, gopi.INPUT_TYPE_KEYBOARD)
+
+	// A subscriber which doesn't receive doesn't block Close
+	device.Subscribe()
+	dev.send(t, key(gopi.KEYCODE_A, input.KEY_PRESS))
+	dev.send(t, key(gopi.KEYCODE_A, input.KEY_RELEASE))
+	time.Sleep(100 * time.Millisecond)
+	expectClose(t, device)
+}
+
+func TestInput_009(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	device := openVirtual(t, input.Virtual{
+		Name:    "virtual",
+		Type:    gopi.INPUT_TYPE_KEYBOARD,
+		DevPath: filepath.Join(tmp, "uinput"),
+	})
+
+	// A subscriber which doesn't receive blocks Send, but doesn't
+	// block Close
+	device.Subscribe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			device.Send(input.Event{Type: gopi.INPUT_EVENT_KEYPRESS, KeyCode: gopi.KEYCODE_A})
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	expectClose(t, device)
+	if err := device.Send(input.Event{Type: gopi.INPUT_EVENT_KEYPRESS, KeyCode: gopi.KEYCODE_A}); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+}
+
+// expectClose closes a driver, and fails if Close doesn't return
+func expectClose(t *testing.T, driver gopi.Driver) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		driver.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for Close")
+	}
+}
+
+func openVirtual(t *testing.T, config input.Virtual) input.VirtualDevice {
+	t.Helper()
+	if driver, err := gopi.Open(config, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(input.VirtualDevice)
+	}
+}
@@ -0,0 +1,233 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package input
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Input is the configuration for the input manager, which opens
+// evdev devices and emits events from all open devices
+type Input struct {
+	DevPath   string // Path to devices (default: /dev/input)
+	Exclusive bool   // Grab devices so events are not delivered elsewhere
+}
+
+type manager struct {
+	log       gopi.Logger
+	devpath   string
+	exclusive bool
+	devices   []*managed
+
+	sync.Mutex
+	event.Publisher
+}
+
+// managed is a device and the channel on which its events are
+// received
+type managed struct {
+	device gopi.InputDevice
+	events <-chan gopi.Event
+	done   chan struct{}
+}
+
+// pather is implemented by devices which have a path
+type pather interface {
+	Path() string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	INPUT_DEV_PATH = "/dev/input"
+	INPUT_DEV_GLOB = "event*"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the input manager
+func (config Input) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.input.Open{ devpath=%v exclusive=%v }", strconv.Quote(config.DevPath), config.Exclusive)
+
+	this := new(manager)
+	this.log = log
+	this.devpath = config.DevPath
+	if this.devpath == "" {
+		this.devpath = INPUT_DEV_PATH
+	}
+	this.exclusive = config.Exclusive
+	this.devices = make([]*managed, 0)
+
+	// Success
+	return this, nil
+}
+
+// Close the input manager and all open devices
+func (this *manager) Close() error {
+	this.log.Debug("sys.input.Close{ devpath=%v }", strconv.Quote(this.devpath))
+
+	var result error
+	for _, device := range this.GetOpenDevices() {
+		if err := this.CloseDevice(device); err != nil && result == nil {
+			result = err
+		}
+	}
+	this.Publisher.Close()
+
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - INPUT MANAGER
+
+// OpenDevicesByName opens devices in the device path which match a
+// name, device types and bus, and returns the newly opened devices.
+// Devices which cannot be opened are skipped
+func (this *manager) OpenDevicesByName(name string, flags gopi.InputDeviceType, bus gopi.InputDeviceBus) ([]gopi.InputDevice, error) {
+	this.log.Debug2("sys.input.OpenDevicesByName{ name=%v flags=%v bus=%v }", strconv.Quote(name), flags, bus)
+
+	paths, err := filepath.Glob(filepath.Join(this.devpath, INPUT_DEV_GLOB))
+	if err != nil {
+		return nil, err
+	}
+
+	opened := make([]gopi.InputDevice, 0, len(paths))
+	for _, path := range paths {
+		if this.isOpen(path) {
+			continue
+		}
+		driver, err := gopi.Open(Device{Path: path, Exclusive: this.exclusive}, this.log)
+		if err != nil {
+			this.log.Debug("sys.input.OpenDevicesByName: %v: %v", path, err)
+			continue
+		}
+		device := driver.(gopi.InputDevice)
+		if device.Matches(name, flags, bus) == false {
+			device.Close()
+		} else if err := this.AddDevice(device); err != nil {
+			device.Close()
+			return nil, err
+		} else {
+			opened = append(opened, device)
+		}
+	}
+
+	// Success
+	return opened, nil
+}
+
+// CloseDevice closes a device and stops emitting its events
+func (this *manager) CloseDevice(device gopi.InputDevice) error {
+	this.log.Debug2("sys.input.CloseDevice{ device=%v }", device)
+
+	this.Lock()
+	var m *managed
+	for i, device_ := range this.devices {
+		if device_.device == device {
+			m = device_
+			this.devices = append(this.devices[:i], this.devices[i+1:]...)
+			break
+		}
+	}
+	this.Unlock()
+
+	if m == nil {
+		return gopi.ErrNotFound
+	}
+
+	// Closing the device closes the channel, which ends forwarding
+	err := m.device.Close()
+	m.device.Unsubscribe(m.events)
+	<-m.done
+	return err
+}
+
+// GetOpenDevices returns the open devices
+func (this *manager) GetOpenDevices() []gopi.InputDevice {
+	this.Lock()
+	defer this.Unlock()
+	devices := make([]gopi.InputDevice, len(this.devices))
+	for i, device := range this.devices {
+		devices[i] = device.device
+	}
+	return devices
+}
+
+// AddDevice adds a device, so that its events are emitted by the
+// manager and it is closed when the manager is closed
+func (this *manager) AddDevice(device gopi.InputDevice) error {
+	this.log.Debug2("sys.input.AddDevice{ device=%v }", device)
+
+	if device == nil {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	for _, device_ := range this.devices {
+		if device_.device == device {
+			return gopi.ErrBadParameter
+		}
+	}
+	m := &managed{device, device.Subscribe(), make(chan struct{})}
+	this.devices = append(this.devices, m)
+	go this.forwardTask(m)
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *manager) String() string {
+	return fmt.Sprintf("<sys.input>{ devpath=%v exclusive=%v devices=%v }", strconv.Quote(this.devpath), this.exclusive, this.GetOpenDevices())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// forwardTask emits events from a device until its channel is closed
+func (this *manager) forwardTask(m *managed) {
+	defer close(m.done)
+	for evt := range m.events {
+		this.Emit(evt)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// isOpen returns true if a device with the path is open
+func (this *manager) isOpen(path string) bool {
+	this.Lock()
+	defer this.Unlock()
+	for _, device := range this.devices {
+		if device_, ok := device.device.(pather); ok && device_.Path() == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package input
+
+import (
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RawEvent is a struct input_event as read from and written to
+// /dev/input/eventN, in host byte order and layout
+type RawEvent struct {
+	Time  syscall.Timeval
+	Type  EventType
+	Code  uint16
+	Value int32
+}
+
+// EventType is the evdev event type
+type EventType uint16
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	EV_SYN EventType = 0x00
+	EV_KEY EventType = 0x01
+	EV_REL EventType = 0x02
+	EV_ABS EventType = 0x03
+	EV_MSC EventType = 0x04
+	EV_LED EventType = 0x11
+	EV_REP EventType = 0x14
+	EV_MAX EventType = 0x1F
+)
+
+// EV_SYN codes
+const (
+	SYN_REPORT  = 0x00
+	SYN_DROPPED = 0x03
+)
+
+// EV_KEY values
+const (
+	KEY_RELEASE = 0
+	KEY_PRESS   = 1
+	KEY_REPEAT  = 2
+)
+
+// EV_REL codes
+const (
+	REL_X = 0x00
+	REL_Y = 0x01
+)
+
+// EV_ABS codes
+const (
+	ABS_X              = 0x00
+	ABS_Y              = 0x01
+	ABS_MT_SLOT        = 0x2F
+	ABS_MT_POSITION_X  = 0x35
+	ABS_MT_POSITION_Y  = 0x36
+	ABS_MT_TRACKING_ID = 0x39
+	ABS_MAX            = 0x3F
+)
+
+// EV_MSC codes
+const (
+	MSC_SCAN = 0x04
+)
+
+// EV_LED codes
+const (
+	LED_NUML    = 0x00
+	LED_CAPSL   = 0x01
+	LED_SCROLLL = 0x02
+	LED_MAX     = 0x0F
+)
+
+const (
+	// RAW_EVENT_SIZE is the size of a struct input_event
+	RAW_EVENT_SIZE = int(unsafe.Sizeof(RawEvent{}))
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE
+
+// ReadEvent reads a single event
+func ReadEvent(r io.Reader) (RawEvent, error) {
+	var evt RawEvent
+	buf := (*[RAW_EVENT_SIZE]byte)(unsafe.Pointer(&evt))[:]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return RawEvent{}, err
+	} else {
+		return evt, nil
+	}
+}
+
+// WriteEvent writes a single event
+func WriteEvent(w io.Writer, evt RawEvent) error {
+	buf := (*[RAW_EVENT_SIZE]byte)(unsafe.Pointer(&evt))[:]
+	if n, err := w.Write(buf); err != nil {
+		return err
+	} else if n != len(buf) {
+		return io.ErrShortWrite
+	} else {
+		return nil
+	}
+}
+
+// NewRawEvent returns an event with a timestamp
+func NewRawEvent(ts time.Duration, t EventType, code uint16, value int32) RawEvent {
+	return RawEvent{syscall.NsecToTimeval(int64(ts)), t, code, value}
+}
+
+// Timestamp returns the event time as a duration since the epoch
+func (this RawEvent) Timestamp() time.Duration {
+	return time.Duration(this.Time.Nano())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t EventType) String() string {
+	switch t {
+	case EV_SYN:
+		return "EV_SYN"
+	case EV_KEY:
+		return "EV_KEY"
+	case EV_REL:
+		return "EV_REL"
+	case EV_ABS:
+		return "EV_ABS"
+	case EV_MSC:
+		return "EV_MSC"
+	case EV_LED:
+		return "EV_LED"
+	case EV_REP:
+		return "EV_REP"
+	default:
+		return fmt.Sprintf("EV_0x%02X", uint16(t))
+	}
+}
+
+func (this RawEvent) String() string {
+	return fmt.Sprintf("<sys.input.raw>{ type=%v code=0x%04X value=%v ts=%v }", this.Type, this.Code, this.Value, this.Timestamp())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IOCTL
+
+const (
+	_IOC_NONE  = 0
+	_IOC_WRITE = 1
+	_IOC_READ  = 2
+)
+
+func ioctlIOC(dir, t, nr uintptr, size int) uintptr {
+	return (dir << 30) | (uintptr(size) << 16) | (t << 8) | nr
+}
+
+func ioctlEVIOCGBIT(t EventType, size int) uintptr {
+	return ioctlIOC(_IOC_READ, INPUT_IOC_TYPE, 0x20+uintptr(t), size)
+}
+
+// ioctl calls an ioctl which reads into or writes from a buffer
+func ioctl(conn syscall.Conn, cmd uintptr, buf []byte) error {
+	var errno syscall.Errno
+	if raw, err := conn.SyscallConn(); err != nil {
+		return err
+	} else if err := raw.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, uintptr(unsafe.Pointer(&buf[0])))
+	}); err != nil {
+		return err
+	} else if errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+// ioctlValue calls an ioctl which takes a value
+func ioctlValue(conn syscall.Conn, cmd uintptr, value uintptr) error {
+	var errno syscall.Errno
+	if raw, err := conn.SyscallConn(); err != nil {
+		return err
+	} else if err := raw.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, value)
+	}); err != nil {
+		return err
+	} else if errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+// bit returns true if a bit is set in a capability bitmask
+func bit(bits []byte, n int) bool {
+	return n/8 < len(bits) && bits[n/8]&(1<<uint(n%8)) != 0
+}
+
+// cstring returns a string from a nul-terminated buffer
+func cstring(buf []byte) string {
+	for i, b := range buf {
+		if b == 0 {
+			return string(buf[:i])
+		}
+	}
+	return string(buf)
+}
+
+// nativeUint16 returns a uint16 in host byte order
+func nativeUint16(buf []byte) uint16 {
+	return *(*uint16)(unsafe.Pointer(&buf[0]))
+}
@@ -0,0 +1,369 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package input
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Virtual is the configuration for a virtual input device of bus
+// INPUT_BUS_VIRTUAL. The device is created through /dev/uinput so
+// that events are delivered by the kernel to all readers. When uinput
+// is unavailable, events are emitted by the device in-process and
+// published through the input manager
+type Virtual struct {
+	Name    string               // Name of the device
+	Type    gopi.InputDeviceType // Keyboard, mouse, touchscreen or remote, or a combination
+	Size    gopi.Size            // Range of absolute positions (default: VIRTUAL_ABS_MAX)
+	Manager gopi.InputManager    // Manager to publish through when uinput is unavailable
+	DevPath string               // Path to uinput (default: /dev/uinput)
+}
+
+// VirtualDevice is an input device which events can be sent to
+type VirtualDevice interface {
+	gopi.InputDevice
+
+	// Send an event to the device
+	Send(evt Event) error
+
+	// UInput returns true if the device was created through
+	// uinput, or false if events are emitted in-process
+	UInput() bool
+}
+
+// Event is an event sent to a virtual device. Key events use the key
+// code and optional scancode, position events use the absolute or
+// relative position, and touch events use the slot and position
+type Event struct {
+	Type     gopi.InputEventType
+	KeyCode  gopi.KeyCode
+	ScanCode uint32
+	Position gopi.Point
+	Relative gopi.Point
+	Slot     uint
+}
+
+type virtual struct {
+	*device
+	w          io.WriteCloser
+	uinput     bool
+	manager    gopi.InputManager
+	trackingid int32
+	sent       sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	VIRTUAL_DEV_PATH  = "/dev/uinput"
+	VIRTUAL_NAME_SIZE = 80
+	VIRTUAL_ABS_MAX   = 0xFFFF
+	VIRTUAL_IOC_TYPE  = 'U'
+	VIRTUAL_KEY_MAX   = 0xFF
+	VIRTUAL_BTN_LEFT  = 0x110
+	VIRTUAL_BTN_RIGHT = 0x111
+	VIRTUAL_BTN_MID   = 0x112
+)
+
+var (
+	UI_DEV_CREATE  = ioctlIOC(_IOC_NONE, VIRTUAL_IOC_TYPE, 1, 0)
+	UI_DEV_DESTROY = ioctlIOC(_IOC_NONE, VIRTUAL_IOC_TYPE, 2, 0)
+	UI_SET_EVBIT   = ioctlIOC(_IOC_WRITE, VIRTUAL_IOC_TYPE, 100, 4)
+	UI_SET_KEYBIT  = ioctlIOC(_IOC_WRITE, VIRTUAL_IOC_TYPE, 101, 4)
+	UI_SET_RELBIT  = ioctlIOC(_IOC_WRITE, VIRTUAL_IOC_TYPE, 102, 4)
+	UI_SET_ABSBIT  = ioctlIOC(_IOC_WRITE, VIRTUAL_IOC_TYPE, 103, 4)
+	UI_SET_MSCBIT  = ioctlIOC(_IOC_WRITE, VIRTUAL_IOC_TYPE, 104, 4)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open a virtual input device
+func (config Virtual) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.input.Virtual.Open{ name=%v type=%v }", strconv.Quote(config.Name), config.Type)
+
+	if config.Name == "" || config.Type == gopi.INPUT_TYPE_NONE {
+		return nil, gopi.ErrBadParameter
+	}
+	devpath := config.DevPath
+	if devpath == "" {
+		devpath = VIRTUAL_DEV_PATH
+	}
+
+	this := new(virtual)
+
+	// Create the device through uinput, or else emit in-process
+	var r io.Reader
+	if fh, err := os.OpenFile(devpath, os.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0); err == nil {
+		if err := createUInput(fh, config); err != nil {
+			fh.Close()
+			return nil, err
+		}
+		r, this.w, this.uinput = fh, fh, true
+	} else {
+		log.Debug("sys.input.Virtual.Open: %v: Emitting events in-process", err)
+		r, this.w = io.Pipe()
+	}
+
+	// Events read from uinput update the LED states, and events
+	// read from the pipe are emitted
+	if driver, err := gopi.Open(Device{Reader: r, Name: config.Name, Type: config.Type, Bus: gopi.INPUT_BUS_VIRTUAL}, log); err != nil {
+		this.w.Close()
+		return nil, err
+	} else {
+		this.device = driver.(*device)
+		this.device.Lock()
+		this.device.source = this
+		this.device.Unlock()
+	}
+
+	// Publish through the manager when in-process
+	if this.uinput == false && config.Manager != nil {
+		if err := config.Manager.AddDevice(this); err != nil {
+			this.Close()
+			return nil, err
+		}
+		this.manager = config.Manager
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the virtual device. Devices added to the manager are
+// removed from it
+func (this *virtual) Close() error {
+	this.device.Lock()
+	manager := this.manager
+	this.manager = nil
+	this.device.Unlock()
+
+	// CloseDevice calls Close again, with the manager cleared
+	if manager != nil {
+		return manager.CloseDevice(this)
+	}
+
+	this.log.Debug("sys.input.Virtual.Close{ name=%v }", strconv.Quote(this.name))
+
+	// The in-process device is closed first, which ends a Send
+	// blocked writing to the pipe. The uinput device is destroyed
+	// before it is closed
+	var result error
+	if this.uinput == false {
+		result = this.device.Close()
+	}
+
+	this.sent.Lock()
+	if this.w == nil {
+		this.sent.Unlock()
+		return result
+	} else if this.uinput {
+		if err := ioctlValue(this.w.(*os.File), UI_DEV_DESTROY, 0); err != nil {
+			result = err
+		}
+	} else if err := this.w.Close(); err != nil && result == nil {
+		result = err
+	}
+	this.w = nil
+	this.sent.Unlock()
+
+	// Close the uinput device without holding the send mutex
+	if this.uinput {
+		if err := this.device.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - VIRTUAL
+
+func (this *virtual) UInput() bool {
+	return this.uinput
+}
+
+// Send an event to the device, which is delivered after a SYN_REPORT
+func (this *virtual) Send(evt Event) error {
+	this.log.Debug2("sys.input.Virtual.Send{ evt=%v }", evt)
+
+	this.sent.Lock()
+	defer this.sent.Unlock()
+
+	if this.w == nil {
+		return gopi.ErrOutOfOrder
+	}
+	raw, err := this.encode(evt)
+	if err != nil {
+		return err
+	}
+	ts := time.Duration(time.Now().UnixNano())
+	for _, raw := range append(raw, RawEvent{Type: EV_SYN, Code: SYN_REPORT}) {
+		raw.Time = syscall.NsecToTimeval(int64(ts))
+		if err := WriteEvent(this.w, raw); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *virtual) String() string {
+	return fmt.Sprintf("<sys.input.virtual>{ name=%v type=%v uinput=%v }", strconv.Quote(this.name), this.devicetype, this.uinput)
+}
+
+func (evt Event) String() string {
+	return fmt.Sprintf("<sys.input.Event>{ type=%v keycode=%v scancode=0x%X position=%v relative=%v slot=%v }", evt.Type, evt.KeyCode, evt.ScanCode, evt.Position, evt.Relative, evt.Slot)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// encode returns the raw events for an event
+func (this *virtual) encode(evt Event) ([]RawEvent, error) {
+	switch evt.Type {
+	case gopi.INPUT_EVENT_KEYPRESS, gopi.INPUT_EVENT_KEYRELEASE, gopi.INPUT_EVENT_KEYREPEAT:
+		if evt.KeyCode == gopi.KEYCODE_NONE || evt.KeyCode > INPUT_KEY_MAX {
+			return nil, gopi.ErrBadParameter
+		}
+		raw := make([]RawEvent, 0, 2)
+		if evt.ScanCode != 0 {
+			raw = append(raw, RawEvent{Type: EV_MSC, Code: MSC_SCAN, Value: int32(evt.ScanCode)})
+		}
+		value := int32(KEY_PRESS)
+		if evt.Type == gopi.INPUT_EVENT_KEYRELEASE {
+			value = KEY_RELEASE
+		} else if evt.Type == gopi.INPUT_EVENT_KEYREPEAT {
+			value = KEY_REPEAT
+		}
+		return append(raw, RawEvent{Type: EV_KEY, Code: uint16(evt.KeyCode), Value: value}), nil
+	case gopi.INPUT_EVENT_RELPOSITION:
+		return []RawEvent{
+			{Type: EV_REL, Code: REL_X, Value: int32(evt.Relative.X)},
+			{Type: EV_REL, Code: REL_Y, Value: int32(evt.Relative.Y)},
+		}, nil
+	case gopi.INPUT_EVENT_ABSPOSITION:
+		return []RawEvent{
+			{Type: EV_ABS, Code: ABS_X, Value: int32(evt.Position.X)},
+			{Type: EV_ABS, Code: ABS_Y, Value: int32(evt.Position.Y)},
+		}, nil
+	case gopi.INPUT_EVENT_TOUCHPRESS, gopi.INPUT_EVENT_TOUCHPOSITION, gopi.INPUT_EVENT_TOUCHRELEASE:
+		if evt.Slot >= INPUT_MAX_SLOTS {
+			return nil, gopi.ErrBadParameter
+		}
+		raw := []RawEvent{{Type: EV_ABS, Code: ABS_MT_SLOT, Value: int32(evt.Slot)}}
+		switch evt.Type {
+		case gopi.INPUT_EVENT_TOUCHPRESS:
+			this.trackingid = (this.trackingid + 1) & VIRTUAL_ABS_MAX
+			raw = append(raw, RawEvent{Type: EV_ABS, Code: ABS_MT_TRACKING_ID, Value: this.trackingid})
+		case gopi.INPUT_EVENT_TOUCHRELEASE:
+			return append(raw, RawEvent{Type: EV_ABS, Code: ABS_MT_TRACKING_ID, Value: -1}), nil
+		}
+		return append(raw,
+			RawEvent{Type: EV_ABS, Code: ABS_MT_POSITION_X, Value: int32(evt.Position.X)},
+			RawEvent{Type: EV_ABS, Code: ABS_MT_POSITION_Y, Value: int32(evt.Position.Y)},
+		), nil
+	default:
+		return nil, gopi.ErrBadParameter
+	}
+}
+
+// createUInput sets the capabilities for the device type, and
+// creates the device
+func createUInput(fh *os.File, config Virtual) error {
+	bits := make(map[uintptr][]int)
+	absmax := make(map[int]int32)
+	xmax, ymax := int32(config.Size.W), int32(config.Size.H)
+	if xmax <= 0 || ymax <= 0 {
+		xmax, ymax = VIRTUAL_ABS_MAX, VIRTUAL_ABS_MAX
+	}
+	if config.Type&(gopi.INPUT_TYPE_KEYBOARD|gopi.INPUT_TYPE_REMOTE) != 0 {
+		bits[UI_SET_EVBIT] = append(bits[UI_SET_EVBIT], int(EV_KEY), int(EV_MSC))
+		bits[UI_SET_MSCBIT] = append(bits[UI_SET_MSCBIT], MSC_SCAN)
+		for key := 1; key <= VIRTUAL_KEY_MAX; key++ {
+			bits[UI_SET_KEYBIT] = append(bits[UI_SET_KEYBIT], key)
+		}
+	}
+	if config.Type&gopi.INPUT_TYPE_MOUSE != 0 {
+		bits[UI_SET_EVBIT] = append(bits[UI_SET_EVBIT], int(EV_KEY), int(EV_REL))
+		bits[UI_SET_KEYBIT] = append(bits[UI_SET_KEYBIT], VIRTUAL_BTN_LEFT, VIRTUAL_BTN_RIGHT, VIRTUAL_BTN_MID)
+		bits[UI_SET_RELBIT] = append(bits[UI_SET_RELBIT], REL_X, REL_Y)
+	}
+	if config.Type&(gopi.INPUT_TYPE_TOUCHSCREEN|gopi.INPUT_TYPE_JOYSTICK) != 0 {
+		bits[UI_SET_EVBIT] = append(bits[UI_SET_EVBIT], int(EV_KEY), int(EV_ABS))
+		bits[UI_SET_ABSBIT] = append(bits[UI_SET_ABSBIT], ABS_X, ABS_Y)
+		absmax[ABS_X], absmax[ABS_Y] = xmax, ymax
+	}
+	if config.Type&gopi.INPUT_TYPE_TOUCHSCREEN != 0 {
+		bits[UI_SET_KEYBIT] = append(bits[UI_SET_KEYBIT], INPUT_BTN_TOUCH)
+		bits[UI_SET_ABSBIT] = append(bits[UI_SET_ABSBIT], ABS_MT_SLOT, ABS_MT_TRACKING_ID, ABS_MT_POSITION_X, ABS_MT_POSITION_Y)
+		absmax[ABS_MT_SLOT] = INPUT_MAX_SLOTS - 1
+		absmax[ABS_MT_TRACKING_ID] = VIRTUAL_ABS_MAX
+		absmax[ABS_MT_POSITION_X], absmax[ABS_MT_POSITION_Y] = xmax, ymax
+	}
+	if config.Type&gopi.INPUT_TYPE_JOYSTICK != 0 {
+		bits[UI_SET_KEYBIT] = append(bits[UI_SET_KEYBIT], INPUT_BTN_JOY)
+	}
+
+	// Set capabilities, EV_ bits first
+	for _, cmd := range []uintptr{UI_SET_EVBIT, UI_SET_KEYBIT, UI_SET_RELBIT, UI_SET_ABSBIT, UI_SET_MSCBIT} {
+		for _, value := range bits[cmd] {
+			if err := ioctlValue(fh, cmd, uintptr(value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Write the legacy struct uinput_user_dev and create the device
+	dev := uinputUserDev{bustype: uint16(gopi.INPUT_BUS_VIRTUAL), version: 1}
+	copy(dev.name[:VIRTUAL_NAME_SIZE-1], config.Name)
+	for code, max := range absmax {
+		dev.absmax[code] = max
+	}
+	buf := (*[unsafe.Sizeof(uinputUserDev{})]byte)(unsafe.Pointer(&dev))[:]
+	if _, err := fh.Write(buf); err != nil {
+		return err
+	} else if err := ioctlValue(fh, UI_DEV_CREATE, 0); err != nil {
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+// uinputUserDev is the struct uinput_user_dev
+type uinputUserDev struct {
+	name                             [VIRTUAL_NAME_SIZE]byte
+	bustype, vendor, product         uint16
+	version                          uint16
+	ffEffectsMax                     uint32
+	absmax, absmin, absfuzz, absflat [ABS_MAX + 1]int32
+}
@@ -0,0 +1,214 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package keymap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Keymaps maps device names to scancodes and key codes. In JSON the
+// scancodes are hexadecimal strings and the key codes are names:
+//
+//	{ "Apple Remote": { "0x0B": "KEYCODE_UP", "0x0D": "KEYCODE_DOWN" } }
+type Keymaps map[string]map[ScanCode]KeyCode
+
+// ScanCode is a scancode which is marshalled as hexadecimal
+type ScanCode uint32
+
+// KeyCode is a key code which is marshalled as a name
+type KeyCode gopi.KeyCode
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	KEYCODE_PREFIX = "KEYCODE_"
+	KEYCODE_MAX    = 0x02FF
+)
+
+var (
+	keycodes map[string]gopi.KeyCode
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// READ
+
+// ReadFile reads keymaps from a JSON file, or YAML if the file
+// extension is .yaml or .yml
+func ReadFile(path string) (Keymaps, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ReadYAML(fh)
+	default:
+		return ReadJSON(fh)
+	}
+}
+
+// ReadJSON reads keymaps as JSON
+func ReadJSON(r io.Reader) (Keymaps, error) {
+	keymaps := make(Keymaps)
+	if err := json.NewDecoder(r).Decode(&keymaps); err != nil {
+		return nil, err
+	} else {
+		return keymaps, nil
+	}
+}
+
+// ReadYAML reads keymaps from the subset of YAML which has device
+// names at the top level and indented scancode and key code pairs:
+//
+//	# Remote control
+//	Apple Remote:
+//	  0x0B: KEYCODE_UP
+//	  0x0D: KEYCODE_DOWN
+func ReadYAML(r io.Reader) (Keymaps, error) {
+	keymaps := make(Keymaps)
+	scanner := bufio.NewScanner(r)
+	device := ""
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		if strings.TrimSpace(text) == "" || strings.TrimSpace(text) == "---" {
+			continue
+		}
+		indented := text[0] == ' ' || text[0] == '\t'
+		key, value, ok := yamlPair(text)
+		if ok == false {
+			return nil, fmt.Errorf("Line %v: Syntax error", line)
+		}
+		if indented == false {
+			if value != "" {
+				return nil, fmt.Errorf("Line %v: Expected device name", line)
+			}
+			device = key
+			if _, exists := keymaps[device]; exists == false {
+				keymaps[device] = make(map[ScanCode]KeyCode)
+			}
+			continue
+		} else if device == "" {
+			return nil, fmt.Errorf("Line %v: Missing device name", line)
+		}
+		var scancode ScanCode
+		var keycode KeyCode
+		if err := scancode.UnmarshalText([]byte(key)); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else if err := keycode.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else {
+			keymaps[device][scancode] = keycode
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keymaps, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MARSHAL
+
+func (s ScanCode) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("0x%02X", uint32(s))), nil
+}
+
+func (s *ScanCode) UnmarshalText(text []byte) error {
+	if value, err := strconv.ParseUint(strings.TrimSpace(string(text)), 0, 32); err != nil {
+		return fmt.Errorf("Invalid scancode: %v", strconv.Quote(string(text)))
+	} else {
+		*s = ScanCode(value)
+		return nil
+	}
+}
+
+func (k KeyCode) MarshalText() ([]byte, error) {
+	return []byte(gopi.KeyCode(k).String()), nil
+}
+
+// UnmarshalText accepts key code names with or without the KEYCODE_
+// prefix in any case, or numbers
+func (k *KeyCode) UnmarshalText(text []byte) error {
+	name := strings.ToUpper(strings.TrimSpace(string(text)))
+	if strings.HasPrefix(name, KEYCODE_PREFIX) == false {
+		name = KEYCODE_PREFIX + name
+	}
+	if keycode, exists := keycodes[name]; exists {
+		*k = KeyCode(keycode)
+	} else if value, err := strconv.ParseUint(strings.TrimPrefix(name, KEYCODE_PREFIX), 0, 16); err == nil {
+		*k = KeyCode(value)
+	} else {
+		return fmt.Errorf("Invalid key code: %v", strconv.Quote(string(text)))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func init() {
+	// Map key code names to key codes
+	keycodes = make(map[string]gopi.KeyCode)
+	for k := gopi.KeyCode(0); k <= KEYCODE_MAX; k++ {
+		if name := k.String(); strings.HasPrefix(name, KEYCODE_PREFIX+"0x") == false {
+			keycodes[name] = k
+		}
+	}
+}
+
+// yamlPair returns the key and value for a "key: value" or "key:"
+// line, removing any quotes
+func yamlPair(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	key, value := "", ""
+	if strings.HasSuffix(text, ":") {
+		key = text[:len(text)-1]
+	} else if i := strings.Index(text, ": "); i >= 0 {
+		key, value = text[:i], text[i+2:]
+	} else {
+		return "", "", false
+	}
+	if key, value = unquote(key), unquote(value); key == "" {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			if value_, err := strconv.Unquote(value); err == nil {
+				return value_
+			}
+		}
+		return value[1 : len(value)-1]
+	}
+	return value
+}
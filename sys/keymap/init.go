@@ -0,0 +1,51 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package keymap
+
+import (
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register key mapper
+	gopi.RegisterModule(gopi.Module{
+		Name: "sys/keymap",
+		Type: gopi.MODULE_TYPE_KEYMAP,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagString("keymap.path", "", "Path to persist learnt key mappings")
+			config.AppFlags.FlagString("keymap.files", "", "Comma-separated JSON or YAML keymap files")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			path, _ := app.AppFlags.GetString("keymap.path")
+			files, _ := app.AppFlags.GetString("keymap.files")
+			return gopi.Open(KeyMap{
+				Path:  path,
+				Files: splitFiles(files),
+				Clock: app.Clock,
+			}, app.Logger)
+		},
+	})
+}
+
+func splitFiles(value string) []string {
+	files := make([]string, 0)
+	for _, file := range strings.Split(value, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
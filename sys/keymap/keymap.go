@@ -0,0 +1,232 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package keymap
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/persistence"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// KeyMap is the configuration for the key mapper. Keymaps are read
+// from files, and learnt mappings are persisted to a separate file
+// which takes precedence
+type KeyMap struct {
+	Files []string      // JSON or YAML keymap files
+	Path  string        // Path to persist learnt mappings, or empty
+	Delta time.Duration // Delay before writing learnt mappings (default: KEYMAP_WRITE_DELTA)
+	Clock gopi.Clock    // Clock for writing learnt mappings (default: gopi.SystemClock)
+}
+
+type keymap struct {
+	log    gopi.Logger
+	path   string
+	delta  time.Duration
+	clock  gopi.Clock
+	files  Keymaps
+	learnt Keymaps
+
+	persistence.File
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	KEYMAP_FILENAME    = "keymap.json"
+	KEYMAP_WRITE_DELTA = 5 * time.Second
+
+	// KEYMAP_DEVICE_ANY is the device name for mappings which
+	// apply to all devices
+	KEYMAP_DEVICE_ANY = "*"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the key mapper
+func (config KeyMap) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.keymap.Open{ files=%v path=%v }", config.Files, strconv.Quote(config.Path))
+
+	this := new(keymap)
+	this.log = log
+	this.path = config.Path
+	this.delta = config.Delta
+	this.clock = config.Clock
+	if this.delta == 0 {
+		this.delta = KEYMAP_WRITE_DELTA
+	}
+
+	// Read keymap files, where later files override earlier ones
+	this.files = make(Keymaps)
+	for _, path := range config.Files {
+		if keymaps, err := ReadFile(path); err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		} else {
+			this.files.merge(keymaps)
+		}
+	}
+
+	// Read and persist learnt mappings
+	this.learnt = make(Keymaps)
+	if err := this.File.Init(this, &this.learnt, log); err != nil {
+		return nil, err
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the key mapper, writing any learnt mappings
+func (this *keymap) Close() error {
+	this.log.Debug("sys.keymap.Close{ path=%v }", strconv.Quote(this.path))
+	return this.File.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PERSISTENCE CONFIG
+
+func (this *keymap) DefaultFilename() string {
+	return KEYMAP_FILENAME
+}
+
+func (this *keymap) WriteDelta() time.Duration {
+	return this.delta
+}
+
+func (this *keymap) Path() string {
+	return this.path
+}
+
+func (this *keymap) Indent() bool {
+	return true
+}
+
+func (this *keymap) Clock() gopi.Clock {
+	return this.clock
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - KEYMAPPER
+
+// Lookup returns the key code for a device and scancode. Mappings for
+// the device take precedence over mappings for any device
+func (this *keymap) Lookup(device string, scancode uint32) gopi.KeyCode {
+	this.Lock()
+	defer this.Unlock()
+
+	for _, device := range []string{device, KEYMAP_DEVICE_ANY} {
+		if keycode, exists := this.learnt.lookup(device, scancode); exists {
+			return keycode
+		} else if keycode, exists := this.files.lookup(device, scancode); exists {
+			return keycode
+		}
+	}
+	return gopi.KEYCODE_NONE
+}
+
+// Learn maps a device and scancode to a key code, and persists the
+// mapping
+func (this *keymap) Learn(device string, scancode uint32, keycode gopi.KeyCode) error {
+	this.log.Debug2("sys.keymap.Learn{ device=%v scancode=0x%02X keycode=%v }", strconv.Quote(device), scancode, keycode)
+
+	if device == "" || keycode == gopi.KEYCODE_NONE {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	if this.learnt[device] == nil {
+		this.learnt[device] = make(map[ScanCode]KeyCode)
+	}
+	this.learnt[device][ScanCode(scancode)] = KeyCode(keycode)
+	this.Unlock()
+
+	this.SetModified()
+	return nil
+}
+
+// Forget removes a learnt mapping for a device and scancode, so that
+// any mapping from a keymap file applies again. If there is no learnt
+// mapping, a mapping from a keymap file is masked
+func (this *keymap) Forget(device string, scancode uint32) error {
+	this.log.Debug2("sys.keymap.Forget{ device=%v scancode=0x%02X }", strconv.Quote(device), scancode)
+
+	if device == "" {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	keycode, learnt := this.learnt.lookup(device, scancode)
+	_, file := this.files.lookup(device, scancode)
+	if learnt && (keycode != gopi.KEYCODE_NONE || file == false) {
+		delete(this.learnt[device], ScanCode(scancode))
+		if len(this.learnt[device]) == 0 {
+			delete(this.learnt, device)
+		}
+	} else if file && learnt == false {
+		if this.learnt[device] == nil {
+			this.learnt[device] = make(map[ScanCode]KeyCode)
+		}
+		this.learnt[device][ScanCode(scancode)] = KeyCode(gopi.KEYCODE_NONE)
+	} else {
+		this.Unlock()
+		return gopi.ErrNotFound
+	}
+	this.Unlock()
+
+	this.SetModified()
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *keymap) String() string {
+	return fmt.Sprintf("<sys.keymap>{ files=%v learnt=%v %v }", this.files.count(), this.learnt.count(), this.File.String())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this Keymaps) lookup(device string, scancode uint32) (gopi.KeyCode, bool) {
+	if keys, exists := this[device]; exists == false {
+		return gopi.KEYCODE_NONE, false
+	} else if keycode, exists := keys[ScanCode(scancode)]; exists == false {
+		return gopi.KEYCODE_NONE, false
+	} else {
+		return gopi.KeyCode(keycode), true
+	}
+}
+
+func (this Keymaps) merge(other Keymaps) {
+	for device, keys := range other {
+		if this[device] == nil {
+			this[device] = make(map[ScanCode]KeyCode, len(keys))
+		}
+		for scancode, keycode := range keys {
+			this[device][scancode] = keycode
+		}
+	}
+}
+
+func (this Keymaps) count() int {
+	count := 0
+	for _, keys := range this {
+		count += len(keys)
+	}
+	return count
+}
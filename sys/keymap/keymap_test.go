@@ -0,0 +1,193 @@
+package keymap_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/keymap"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+const (
+	KEYMAP_JSON = `{
+		"Apple Remote": { "0x0B": "KEYCODE_UP", "0x0D": "down", "14": "0x1C" },
+		"*": { "0x01": "KEYCODE_ESC" }
+	}`
+	KEYMAP_YAML = `---
+# Remote control
+Apple Remote:
+  0x0B: KEYCODE_UP
+  "0x0D": 'down'   # Quoted
+
+"*":
+  0x01: KEYCODE_ESC
+`
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestKeymap_000(t *testing.T) {
+	for _, keymaps := range []keymap.Keymaps{
+		readJSON(t, KEYMAP_JSON),
+		readYAML(t, KEYMAP_YAML),
+	} {
+		if keycode := keymaps["Apple Remote"][0x0B]; gopi.KeyCode(keycode) != gopi.KEYCODE_UP {
+			t.Error("Unexpected key code", keycode)
+		} else if keycode := keymaps["Apple Remote"][0x0D]; gopi.KeyCode(keycode) != gopi.KEYCODE_DOWN {
+			t.Error("Unexpected key code", keycode)
+		} else if keycode := keymaps["*"][0x01]; gopi.KeyCode(keycode) != gopi.KEYCODE_ESC {
+			t.Error("Unexpected key code", keycode)
+		}
+	}
+	if keycode := readJSON(t, KEYMAP_JSON)["Apple Remote"][14]; gopi.KeyCode(keycode) != gopi.KEYCODE_ENTER {
+		t.Error("Unexpected key code", keycode)
+	}
+}
+
+func TestKeymap_001(t *testing.T) {
+	for _, yaml := range []string{
+		"  0x0B: KEYCODE_UP\n",
+		"Remote: value\n",
+		"Remote:\n  0x0B KEYCODE_UP\n",
+		"Remote:\n  scancode: KEYCODE_UP\n",
+		"Remote:\n  0x0B: KEYCODE_INVALID\n",
+	} {
+		if _, err := keymap.ReadYAML(strings.NewReader(yaml)); err == nil {
+			t.Errorf("Expected error for %q", yaml)
+		}
+	}
+}
+
+func TestKeymap_002(t *testing.T) {
+	// Device mappings take precedence over wildcard mappings
+	path := writeFile(t, "keymap.json", `{ "Remote": { "0x01": "KEYCODE_A" }, "*": { "0x01": "KEYCODE_B", "0x02": "KEYCODE_C" } }`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	driver := openKeymap(t, keymap.KeyMap{Files: []string{path}})
+	defer driver.Close()
+
+	if keycode := driver.Lookup("Remote", 0x01); keycode != gopi.KEYCODE_A {
+		t.Error("Unexpected key code", keycode)
+	} else if keycode := driver.Lookup("Other", 0x01); keycode != gopi.KEYCODE_B {
+		t.Error("Unexpected key code", keycode)
+	} else if keycode := driver.Lookup("Remote", 0x02); keycode != gopi.KEYCODE_C {
+		t.Error("Unexpected key code", keycode)
+	} else if keycode := driver.Lookup("Remote", 0x03); keycode != gopi.KEYCODE_NONE {
+		t.Error("Unexpected key code", keycode)
+	}
+
+	// Learnt mappings take precedence over files, forgetting a learnt
+	// mapping reverts to the file, and forgetting a file mapping masks it
+	if err := driver.Learn("Remote", 0x01, gopi.KEYCODE_X); err != nil {
+		t.Error(err)
+	} else if keycode := driver.Lookup("Remote", 0x01); keycode != gopi.KEYCODE_X {
+		t.Error("Unexpected key code", keycode)
+	} else if err := driver.Forget("Remote", 0x01); err != nil {
+		t.Error(err)
+	} else if keycode := driver.Lookup("Remote", 0x01); keycode != gopi.KEYCODE_A {
+		t.Error("Unexpected key code", keycode)
+	} else if err := driver.Forget("Remote", 0x01); err != nil {
+		t.Error(err)
+	} else if keycode := driver.Lookup("Remote", 0x01); keycode != gopi.KEYCODE_NONE {
+		t.Error("Unexpected key code", keycode)
+	} else if err := driver.Forget("Remote", 0x01); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	} else if err := driver.Forget("Remote", 0x03); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	} else if err := driver.Learn("Remote", 0x03, gopi.KEYCODE_NONE); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestKeymap_003(t *testing.T) {
+	// Learnt mappings are persisted
+	tmp, err := ioutil.TempDir("", "keymap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "learnt.json")
+
+	driver := openKeymap(t, keymap.KeyMap{Path: path, Delta: time.Second})
+	if err := driver.Learn("Remote", 0x1234, gopi.KEYCODE_VOLUMEUP); err != nil {
+		t.Error(err)
+	} else if err := driver.Learn("Remote", 0x1235, gopi.KEYCODE_VOLUMEDOWN); err != nil {
+		t.Error(err)
+	} else if err := driver.Forget("Remote", 0x1235); err != nil {
+		t.Error(err)
+	} else if err := driver.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if data, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(data), "KEYCODE_VOLUMEUP") == false {
+		t.Error("Unexpected contents", string(data))
+	}
+
+	driver = openKeymap(t, keymap.KeyMap{Path: path, Delta: time.Second})
+	defer driver.Close()
+	if keycode := driver.Lookup("Remote", 0x1234); keycode != gopi.KEYCODE_VOLUMEUP {
+		t.Error("Unexpected key code", keycode)
+	} else if keycode := driver.Lookup("Remote", 0x1235); keycode != gopi.KEYCODE_NONE {
+		t.Error("Unexpected key code", keycode)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openKeymap(t *testing.T, config keymap.KeyMap) gopi.KeyMapper {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(config, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.KeyMapper)
+	}
+}
+
+func readJSON(t *testing.T, data string) keymap.Keymaps {
+	t.Helper()
+	if keymaps, err := keymap.ReadJSON(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return keymaps
+	}
+}
+
+func readYAML(t *testing.T, data string) keymap.Keymaps {
+	t.Helper()
+	if keymaps, err := keymap.ReadYAML(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return keymaps
+	}
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	tmp, err := ioutil.TempDir("", "keymap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tmp, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,277 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package codec
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Codec is the configuration for the infrared codec, which decodes
+// pulses and spaces from a LIRC driver into CodecEvent events
+type Codec struct {
+	LIRC   gopi.LIRC     // LIRC driver
+	Gap    uint32        // Space which ends a frame in microseconds (default: CODEC_GAP)
+	Repeat time.Duration // Time between frames which are repeats (default: CODEC_REPEAT)
+}
+
+// Driver decodes and sends infrared codes
+type Driver interface {
+	gopi.Driver
+	gopi.Publisher
+
+	// Send a code, followed by a number of repeats
+	Send(protocol Protocol, device, scancode uint32, repeats uint) error
+}
+
+type codec struct {
+	log    gopi.Logger
+	lirc   gopi.LIRC
+	gap    uint32
+	repeat time.Duration
+	events <-chan gopi.Event
+	frame  []uint32
+	last   Code
+	ts     time.Time
+	toggle bool
+
+	sync.Mutex
+	event.Publisher
+	event.Tasks
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CODEC_GAP    = 5000
+	CODEC_REPEAT = 250 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the codec
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.lirc.codec.Open{ lirc=%v }", config.LIRC)
+
+	if config.LIRC == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.gap = config.Gap
+	if this.gap == 0 {
+		this.gap = CODEC_GAP
+	}
+	this.repeat = config.Repeat
+	if this.repeat == 0 {
+		this.repeat = CODEC_REPEAT
+	}
+
+	// Decode events in the background
+	this.events = this.lirc.Subscribe()
+	this.Tasks.Start(this.receiveTask)
+
+	// Success
+	return this, nil
+}
+
+// Close the codec
+func (this *codec) Close() error {
+	this.log.Debug("sys.lirc.codec.Close{ }")
+
+	// Unsubscribe before stopping, which ends emitting to subscribers
+	// which are not receiving, then stop receiving
+	this.Publisher.Close()
+	err := this.Tasks.Close()
+	this.lirc.Unsubscribe(this.events)
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - SEND
+
+// Send a code and repeats as a single pulse train, with spaces
+// between frames to keep the protocol period. RC5 and RC6 toggle on
+// each call, and NEC repeats are sent as repeat frames
+func (this *codec) Send(protocol Protocol, device, scancode uint32, repeats uint) error {
+	this.log.Debug2("sys.lirc.codec.Send{ protocol=%v device=0x%X scancode=0x%X repeats=%v }", protocol, device, scancode, repeats)
+
+	this.Lock()
+	code := Code{Protocol: protocol, Device: device, ScanCode: scancode, Toggle: this.toggle}
+	this.Unlock()
+
+	frame, err := Encode(code)
+	if err != nil {
+		return err
+	}
+	values := frame
+	for i := uint(0); i < repeats; i++ {
+		if protocol == PROTOCOL_NEC {
+			code.Repeat = true
+		}
+		if frame_, err := Encode(code); err != nil {
+			return err
+		} else {
+			values = append(values, space(protocol.Period(), frame, this.gap))
+			values = append(values, frame_...)
+			frame = frame_
+		}
+	}
+
+	// Set the carrier if the device supports it
+	if err := this.lirc.SetSendCarrierHz(protocol.CarrierHz()); err != nil && err != gopi.ErrNotImplemented {
+		return err
+	} else if err := this.lirc.PulseSend(values); err != nil {
+		return err
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	if protocol == PROTOCOL_RC5 || protocol == PROTOCOL_RC6 {
+		this.toggle = !this.toggle
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<sys.lirc.codec>{ lirc=%v gap=%vus repeat=%v }", this.lirc, this.gap, this.repeat)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+func (this *codec) receiveTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+
+	events := this.events
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt == nil {
+				// LIRC driver has closed
+				events = nil
+			} else if evt_, ok := evt.(gopi.LIRCEvent); ok {
+				if code, ts, ok := this.receive(evt_); ok {
+					this.Emit(NewEvent(this, code, ts))
+				}
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// receive adds an event to the current frame, and decodes the frame
+// when it ends with a long space or timeout
+func (this *codec) receive(evt gopi.LIRCEvent) (Code, time.Time, bool) {
+	this.Lock()
+	defer this.Unlock()
+
+	switch evt.Type() {
+	case gopi.LIRC_TYPE_PULSE:
+		if len(this.frame)%2 == 1 {
+			this.frame[len(this.frame)-1] += evt.Value()
+		} else {
+			this.frame = append(this.frame, evt.Value())
+		}
+		return Code{}, time.Time{}, false
+	case gopi.LIRC_TYPE_SPACE:
+		if len(this.frame) == 0 {
+			return Code{}, time.Time{}, false
+		} else if evt.Value() < this.gap {
+			if len(this.frame)%2 == 0 {
+				this.frame[len(this.frame)-1] += evt.Value()
+			} else {
+				this.frame = append(this.frame, evt.Value())
+			}
+			return Code{}, time.Time{}, false
+		}
+	case gopi.LIRC_TYPE_TIMEOUT:
+		if len(this.frame) == 0 {
+			return Code{}, time.Time{}, false
+		}
+	default:
+		return Code{}, time.Time{}, false
+	}
+
+	// End of frame
+	frame := this.frame
+	if len(frame)%2 == 0 {
+		frame = frame[:len(frame)-1]
+	}
+	this.frame = nil
+	ts := timestamp(evt)
+	code, ok := Decode(frame)
+	if ok == false {
+		this.log.Debug2("sys.lirc.codec: Unable to decode %v", frame)
+		return Code{}, time.Time{}, false
+	}
+
+	// Determine repeats, or set the code for NEC repeat frames
+	last, since := this.last, ts.Sub(this.ts)
+	this.ts = ts
+	if code.Protocol == PROTOCOL_NEC && code.Repeat {
+		if last.Protocol != PROTOCOL_NEC || since > this.repeat {
+			this.last = Code{}
+			return Code{}, time.Time{}, false
+		}
+		code.Device, code.ScanCode = last.Device, last.ScanCode
+	} else {
+		code.Repeat = since <= this.repeat && code.Protocol == last.Protocol && code.Device == last.Device && code.ScanCode == last.ScanCode && code.Toggle == last.Toggle
+	}
+	this.last = code
+
+	return code, ts, true
+}
+
+// space returns the space between repeated frames
+func space(period uint32, frame []uint32, gap uint32) uint32 {
+	var total uint32
+	for _, value := range frame {
+		total += value
+	}
+	if total+gap*2 > period {
+		return gap * 2
+	} else {
+		return period - total
+	}
+}
+
+// timestamp returns the event timestamp if it has one
+func timestamp(evt gopi.Event) time.Time {
+	if evt_, ok := evt.(interface{ Timestamp() time.Time }); ok {
+		return evt_.Timestamp()
+	} else {
+		return time.Now()
+	}
+}
@@ -0,0 +1,293 @@
+package codec_test
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/lirc/codec"
+	"github.com/djthorpe/gopi/util/event"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+var (
+	update = flag.Bool("update", false, "Update golden files")
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE LIRC
+
+type lirc struct {
+	sent    []uint32
+	carrier uint32
+	event.Publisher
+}
+
+type lircevent struct {
+	source gopi.Driver
+	t      gopi.LIRCType
+	value  uint32
+	ts     time.Time
+}
+
+func (this *lirc) Close() error                              { return nil }
+func (this *lirc) RcvMode() gopi.LIRCMode                    { return gopi.LIRC_MODE_MODE2 }
+func (this *lirc) SendMode() gopi.LIRCMode                   { return gopi.LIRC_MODE_PULSE }
+func (this *lirc) SetRcvMode(gopi.LIRCMode) error            { return gopi.ErrNotImplemented }
+func (this *lirc) SetSendMode(gopi.LIRCMode) error           { return gopi.ErrNotImplemented }
+func (this *lirc) GetRcvResolution() (uint32, error)         { return 0, gopi.ErrNotImplemented }
+func (this *lirc) SetRcvTimeout(uint32) error                { return gopi.ErrNotImplemented }
+func (this *lirc) SetRcvTimeoutReports(bool) error           { return gopi.ErrNotImplemented }
+func (this *lirc) SetRcvCarrierHz(uint32) error              { return gopi.ErrNotImplemented }
+func (this *lirc) SetRcvCarrierRangeHz(uint32, uint32) error { return gopi.ErrNotImplemented }
+func (this *lirc) SetSendDutyCycle(uint32) error             { return gopi.ErrNotImplemented }
+
+func (this *lirc) SetSendCarrierHz(value uint32) error {
+	this.carrier = value
+	return nil
+}
+
+func (this *lirc) PulseSend(values []uint32) error {
+	this.sent = append(this.sent, values...)
+	return nil
+}
+
+func (this *lircevent) Name() string         { return "LIRCEvent" }
+func (this *lircevent) Source() gopi.Driver  { return this.source }
+func (this *lircevent) Type() gopi.LIRCType  { return this.t }
+func (this *lircevent) Value() uint32        { return this.value }
+func (this *lircevent) Timestamp() time.Time { return this.ts }
+
+// replay emits pulses and spaces, with timestamps which advance
+// by each duration
+func (this *lirc) replay(values []uint32, types []gopi.LIRCType) {
+	ts := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, value := range values {
+		ts = ts.Add(time.Duration(value) * time.Microsecond)
+		this.Emit(&lircevent{this, types[i], value, ts})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestCodec_000(t *testing.T) {
+	// Golden files contain the decoded codes for recorded sequences
+	files, err := filepath.Glob("testdata/*.mode2")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(files) == 0 {
+		t.Fatal("No golden files")
+	}
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			values, types := readMode2(t, path)
+			output := decode(t, values, types)
+			golden := strings.TrimSuffix(path, ".mode2") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(output), 0644); err != nil {
+					t.Fatal(err)
+				}
+			} else if expected, err := ioutil.ReadFile(golden); err != nil {
+				t.Fatal(err)
+			} else if output != string(expected) {
+				t.Errorf("Expected:\n%vGot:\n%v", string(expected), output)
+			}
+		})
+	}
+}
+
+func TestCodec_001(t *testing.T) {
+	// Encoded codes decode to the same code
+	codes := []codec.Code{
+		{Protocol: codec.PROTOCOL_NEC, Device: 0x00, ScanCode: 0xFF},
+		{Protocol: codec.PROTOCOL_NEC, Device: 0x1234, ScanCode: 0x01},
+		{Protocol: codec.PROTOCOL_NEC, Repeat: true},
+		{Protocol: codec.PROTOCOL_RC5, Device: 0x1F, ScanCode: 0x7F, Toggle: true},
+		{Protocol: codec.PROTOCOL_RC5, Device: 0x00, ScanCode: 0x00},
+		{Protocol: codec.PROTOCOL_RC6, Device: 0xFF, ScanCode: 0x00, Toggle: true},
+		{Protocol: codec.PROTOCOL_RC6, Device: 0x00, ScanCode: 0xFF},
+		{Protocol: codec.PROTOCOL_SONY12, Device: 0x1F, ScanCode: 0x7F},
+		{Protocol: codec.PROTOCOL_SONY15, Device: 0xA5, ScanCode: 0x00},
+		{Protocol: codec.PROTOCOL_SONY20, Device: 0x1FFF, ScanCode: 0x55},
+	}
+	for _, code := range codes {
+		if frame, err := codec.Encode(code); err != nil {
+			t.Error(code, err)
+		} else if len(frame)%2 == 0 {
+			t.Error(code, "Frame has even length", len(frame))
+		} else if decoded, ok := codec.Decode(frame); ok == false {
+			t.Error(code, "Unable to decode", frame)
+		} else if decoded != code {
+			t.Errorf("Expected %v, got %v", code, decoded)
+		}
+	}
+}
+
+func TestCodec_002(t *testing.T) {
+	codes := []codec.Code{
+		{Protocol: codec.PROTOCOL_NONE},
+		{Protocol: codec.PROTOCOL_NEC, ScanCode: 0x100},
+		{Protocol: codec.PROTOCOL_NEC, Device: 0xFB04},
+		{Protocol: codec.PROTOCOL_RC5, Device: 0x20},
+		{Protocol: codec.PROTOCOL_RC6, ScanCode: 0x100},
+		{Protocol: codec.PROTOCOL_SONY12, Device: 0x20},
+		{Protocol: codec.PROTOCOL_SONY15, ScanCode: 0x80},
+	}
+	for _, code := range codes {
+		if _, err := codec.Encode(code); err != gopi.ErrBadParameter {
+			t.Error(code, "Expected ErrBadParameter, got", err)
+		}
+	}
+}
+
+func TestCodec_003(t *testing.T) {
+	dev := new(lirc)
+	driver := openCodec(t, dev)
+	defer driver.Close()
+
+	// NEC repeats are sent as repeat frames in one pulse train
+	if err := driver.Send(codec.PROTOCOL_NEC, 0x04, 0x08, 1); err != nil {
+		t.Fatal(err)
+	} else if dev.carrier != codec.NEC_CARRIER_HZ {
+		t.Error("Unexpected carrier", dev.carrier)
+	} else if len(dev.sent) != 67+1+3 {
+		t.Error("Unexpected length", len(dev.sent))
+	} else if total := sum(dev.sent[:68]); total != codec.NEC_PERIOD {
+		t.Error("Unexpected period", total)
+	}
+
+	// RC5 toggles between sends
+	dev.sent = nil
+	driver.Send(codec.PROTOCOL_RC5, 0x00, 0x0C, 0)
+	first, _ := codec.Decode(dev.sent)
+	dev.sent = nil
+	driver.Send(codec.PROTOCOL_RC5, 0x00, 0x0C, 0)
+	second, _ := codec.Decode(dev.sent)
+	if first.Toggle == second.Toggle {
+		t.Error("Expected toggle to change")
+	}
+}
+
+func TestCodec_004(t *testing.T) {
+	dev := new(lirc)
+	driver := openCodec(t, dev)
+
+	// A subscriber which doesn't receive doesn't block Close
+	driver.Subscribe()
+	values, types := readMode2(t, "testdata/nec.mode2")
+	go dev.replay(values, types)
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		driver.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for Close")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openCodec(t *testing.T, dev gopi.LIRC) codec.Driver {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(codec.Codec{LIRC: dev}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(codec.Driver)
+	}
+}
+
+// decode replays values through a codec and returns the decoded
+// events, one per line
+func decode(t *testing.T, values []uint32, types []gopi.LIRCType) string {
+	t.Helper()
+	dev := new(lirc)
+	driver := openCodec(t, dev)
+	defer driver.Close()
+
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+	done := make(chan struct{})
+	go func() {
+		dev.replay(values, types)
+		close(done)
+	}()
+
+	output := ""
+	for {
+		select {
+		case evt := <-events:
+			evt_ := evt.(codec.CodecEvent)
+			output += fmt.Sprintf("%v device=0x%X scancode=0x%X repeat=%v\n", evt_.Protocol(), evt_.Device(), evt_.ScanCode(), evt_.Repeat())
+		case <-done:
+			done = nil
+		case <-time.After(100 * time.Millisecond):
+			if done == nil {
+				return output
+			}
+		}
+	}
+}
+
+// readMode2 reads the output of the mode2 utility
+func readMode2(t *testing.T, path string) ([]uint32, []gopi.LIRCType) {
+	t.Helper()
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	values, types := []uint32{}, []gopi.LIRCType{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch fields[0] {
+		case "pulse":
+			types = append(types, gopi.LIRC_TYPE_PULSE)
+		case "space":
+			types = append(types, gopi.LIRC_TYPE_SPACE)
+		case "timeout":
+			types = append(types, gopi.LIRC_TYPE_TIMEOUT)
+		default:
+			t.Fatal("Unexpected line", scanner.Text())
+		}
+		values = append(values, uint32(value))
+	}
+	return values, types
+}
+
+func sum(values []uint32) uint32 {
+	var total uint32
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
@@ -0,0 +1,244 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package codec
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Code is a decoded frame. NEC repeat frames have the repeat flag set
+// and no device or scancode
+type Code struct {
+	Protocol Protocol
+	Device   uint32
+	ScanCode uint32
+	Toggle   bool
+	Repeat   bool
+}
+
+type decodeFunc func(frame []uint32) (Code, bool)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode returns the code for a frame of alternating pulse and space
+// durations in microseconds, starting and ending with a pulse
+func Decode(frame []uint32) (Code, bool) {
+	for _, fn := range []decodeFunc{decodeNEC, decodeSony, decodeRC5, decodeRC6} {
+		if code, ok := fn(frame); ok {
+			return code, true
+		}
+	}
+	return Code{}, false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func decodeNEC(frame []uint32) (Code, bool) {
+	// Repeat frame
+	if len(frame) == 3 {
+		if within(frame[0], NEC_HEADER_PULSE) && within(frame[1], NEC_REPEAT_SPACE) && within(frame[2], NEC_BIT_PULSE) {
+			return Code{Protocol: PROTOCOL_NEC, Repeat: true}, true
+		}
+		return Code{}, false
+	}
+
+	// Header, 32 bits and trailing pulse
+	if len(frame) != 2+32*2+1 {
+		return Code{}, false
+	} else if within(frame[0], NEC_HEADER_PULSE) == false || within(frame[1], NEC_HEADER_SPACE) == false {
+		return Code{}, false
+	}
+	var value uint32
+	for i := 0; i < 32; i++ {
+		pulse, space := frame[2+i*2], frame[3+i*2]
+		if within(pulse, NEC_BIT_PULSE) == false {
+			return Code{}, false
+		} else if within(space, NEC_BIT_1_SPACE) {
+			value |= 1 << uint(i)
+		} else if within(space, NEC_BIT_0_SPACE) == false {
+			return Code{}, false
+		}
+	}
+	if within(frame[len(frame)-1], NEC_BIT_PULSE) == false {
+		return Code{}, false
+	}
+
+	// Bytes are address, inverted address (or high address byte for
+	// extended NEC), command and inverted command
+	addr, naddr := value&0xFF, (value>>8)&0xFF
+	cmd, ncmd := (value>>16)&0xFF, (value>>24)&0xFF
+	if cmd != ^ncmd&0xFF {
+		return Code{}, false
+	} else if addr == ^naddr&0xFF {
+		return Code{Protocol: PROTOCOL_NEC, Device: addr, ScanCode: cmd}, true
+	} else {
+		return Code{Protocol: PROTOCOL_NEC, Device: naddr<<8 | addr, ScanCode: cmd}, true
+	}
+}
+
+func decodeSony(frame []uint32) (Code, bool) {
+	// Header and bits, where each bit is a pulse and all but the last
+	// are followed by a space
+	if len(frame) < 3 || len(frame)%2 == 0 {
+		return Code{}, false
+	} else if within(frame[0], SONY_HEADER_PULSE) == false || within(frame[1], SONY_HEADER_SPACE) == false {
+		return Code{}, false
+	}
+	bits := (len(frame) - 1) / 2
+	var value uint32
+	for i := 0; i < bits; i++ {
+		pulse := frame[2+i*2]
+		if within(pulse, SONY_BIT_1_PULSE) {
+			value |= 1 << uint(i)
+		} else if within(pulse, SONY_BIT_0_PULSE) == false {
+			return Code{}, false
+		}
+		if i < bits-1 && within(frame[3+i*2], SONY_BIT_SPACE) == false {
+			return Code{}, false
+		}
+	}
+
+	// Seven command bits are followed by the device
+	switch bits {
+	case 12:
+		return Code{Protocol: PROTOCOL_SONY12, Device: value >> 7, ScanCode: value & 0x7F}, true
+	case 15:
+		return Code{Protocol: PROTOCOL_SONY15, Device: value >> 7, ScanCode: value & 0x7F}, true
+	case 20:
+		return Code{Protocol: PROTOCOL_SONY20, Device: value >> 7, ScanCode: value & 0x7F}, true
+	default:
+		return Code{}, false
+	}
+}
+
+func decodeRC5(frame []uint32) (Code, bool) {
+	// The first start bit begins with a space, which is not received
+	levels, ok := toLevels(frame, RC5_UNIT)
+	if ok == false {
+		return Code{}, false
+	}
+	levels = append([]bool{false}, levels...)
+	if len(levels) == RC5_BITS*2-1 {
+		levels = append(levels, false)
+	}
+	if len(levels) != RC5_BITS*2 {
+		return Code{}, false
+	}
+
+	// A one is a space followed by a pulse
+	var value uint32
+	for i := 0; i < RC5_BITS; i++ {
+		switch {
+		case levels[i*2] == false && levels[i*2+1]:
+			value = value<<1 | 1
+		case levels[i*2] && levels[i*2+1] == false:
+			value = value << 1
+		default:
+			return Code{}, false
+		}
+	}
+
+	// Start bit, field bit (inverted command bit 6), toggle, five address
+	// bits and six command bits
+	if value&0x2000 == 0 {
+		return Code{}, false
+	}
+	code := Code{
+		Protocol: PROTOCOL_RC5,
+		Toggle:   value&0x0800 != 0,
+		Device:   (value >> 6) & 0x1F,
+		ScanCode: value & 0x3F,
+	}
+	if value&0x1000 == 0 {
+		code.ScanCode |= 0x40
+	}
+	return code, true
+}
+
+func decodeRC6(frame []uint32) (Code, bool) {
+	if len(frame) < 3 {
+		return Code{}, false
+	} else if within(frame[0], RC6_HEADER_PULSE) == false || within(frame[1], RC6_HEADER_SPACE) == false {
+		return Code{}, false
+	}
+	levels, ok := toLevels(frame[2:], RC6_UNIT)
+	if ok == false {
+		return Code{}, false
+	}
+
+	// Start bit, three mode bits, double length trailer bit and
+	// sixteen bits of address and command
+	const size = 2 + 3*2 + 4 + 16*2
+	for len(levels) < size {
+		levels = append(levels, false)
+	}
+	if len(levels) != size {
+		return Code{}, false
+	}
+
+	// A one is a pulse followed by a space
+	bit := func(levels []bool) (uint32, bool) {
+		switch {
+		case levels[0] && levels[1] == false:
+			return 1, true
+		case levels[0] == false && levels[1]:
+			return 0, true
+		default:
+			return 0, false
+		}
+	}
+	var value uint32
+	for i := 0; i < 4; i++ {
+		if b, ok := bit(levels[i*2:]); ok == false {
+			return Code{}, false
+		} else {
+			value = value<<1 | b
+		}
+	}
+	if value != 0x8 {
+		// Start bit is one, and only mode 0 is supported
+		return Code{}, false
+	}
+	var toggle bool
+	switch {
+	case levels[8] && levels[9] && levels[10] == false && levels[11] == false:
+		toggle = true
+	case levels[8] == false && levels[9] == false && levels[10] && levels[11]:
+		toggle = false
+	default:
+		return Code{}, false
+	}
+	value = 0
+	for i := 0; i < 16; i++ {
+		if b, ok := bit(levels[12+i*2:]); ok == false {
+			return Code{}, false
+		} else {
+			value = value<<1 | b
+		}
+	}
+	return Code{Protocol: PROTOCOL_RC6, Toggle: toggle, Device: value >> 8, ScanCode: value & 0xFF}, true
+}
+
+// toLevels converts durations into levels of one unit, where true
+// is a pulse. Returns false if a duration is not a multiple of the unit
+func toLevels(frame []uint32, unit uint32) ([]bool, bool) {
+	levels := make([]bool, 0, len(frame)*2)
+	for i, duration := range frame {
+		n := (duration + unit/2) / unit
+		if n == 0 || n > 3 || within(duration, n*unit) == false {
+			return nil, false
+		}
+		for j := uint32(0); j < n; j++ {
+			levels = append(levels, i%2 == 0)
+		}
+	}
+	return levels, true
+}
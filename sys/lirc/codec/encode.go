@@ -0,0 +1,170 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package codec
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Encode returns a frame of alternating pulse and space durations in
+// microseconds for a code, which starts and ends with a pulse as
+// required by PulseSend
+func Encode(code Code) ([]uint32, error) {
+	switch code.Protocol {
+	case PROTOCOL_NEC:
+		return encodeNEC(code)
+	case PROTOCOL_SONY12:
+		return encodeSony(code, 12, 0x1F)
+	case PROTOCOL_SONY15:
+		return encodeSony(code, 15, 0xFF)
+	case PROTOCOL_SONY20:
+		return encodeSony(code, 20, 0x1FFF)
+	case PROTOCOL_RC5:
+		return encodeRC5(code)
+	case PROTOCOL_RC6:
+		return encodeRC6(code)
+	default:
+		return nil, gopi.ErrBadParameter
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func encodeNEC(code Code) ([]uint32, error) {
+	if code.Repeat {
+		return []uint32{NEC_HEADER_PULSE, NEC_REPEAT_SPACE, NEC_BIT_PULSE}, nil
+	} else if code.ScanCode > 0xFF || code.Device > 0xFFFF {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// An extended device which looks like an address and its inverse
+	// would be decoded as an eight-bit device
+	value := code.Device
+	if value <= 0xFF {
+		value |= (^value & 0xFF) << 8
+	} else if value>>8 == ^value&0xFF {
+		return nil, gopi.ErrBadParameter
+	}
+	value |= code.ScanCode<<16 | (^code.ScanCode&0xFF)<<24
+
+	frame := make([]uint32, 0, 2+32*2+1)
+	frame = append(frame, NEC_HEADER_PULSE, NEC_HEADER_SPACE)
+	for i := uint(0); i < 32; i++ {
+		if value&(1<<i) != 0 {
+			frame = append(frame, NEC_BIT_PULSE, NEC_BIT_1_SPACE)
+		} else {
+			frame = append(frame, NEC_BIT_PULSE, NEC_BIT_0_SPACE)
+		}
+	}
+	return append(frame, NEC_BIT_PULSE), nil
+}
+
+func encodeSony(code Code, bits uint, max uint32) ([]uint32, error) {
+	if code.ScanCode > 0x7F || code.Device > max {
+		return nil, gopi.ErrBadParameter
+	}
+	value := code.Device<<7 | code.ScanCode
+	frame := make([]uint32, 0, 2+bits*2)
+	frame = append(frame, SONY_HEADER_PULSE, SONY_HEADER_SPACE)
+	for i := uint(0); i < bits; i++ {
+		if i > 0 {
+			frame = append(frame, SONY_BIT_SPACE)
+		}
+		if value&(1<<i) != 0 {
+			frame = append(frame, SONY_BIT_1_PULSE)
+		} else {
+			frame = append(frame, SONY_BIT_0_PULSE)
+		}
+	}
+	return frame, nil
+}
+
+func encodeRC5(code Code) ([]uint32, error) {
+	if code.ScanCode > 0x7F || code.Device > 0x1F {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Start bit, field bit (inverted command bit 6), toggle, five address
+	// bits and six command bits
+	value := uint32(0x2000) | code.Device<<6 | code.ScanCode&0x3F
+	if code.ScanCode&0x40 == 0 {
+		value |= 0x1000
+	}
+	if code.Toggle {
+		value |= 0x0800
+	}
+
+	// A one is a space followed by a pulse
+	levels := make([]bool, 0, RC5_BITS*2)
+	for i := RC5_BITS - 1; i >= 0; i-- {
+		if value&(1<<uint(i)) != 0 {
+			levels = append(levels, false, true)
+		} else {
+			levels = append(levels, true, false)
+		}
+	}
+	return fromLevels(levels, RC5_UNIT), nil
+}
+
+func encodeRC6(code Code) ([]uint32, error) {
+	if code.ScanCode > 0xFF || code.Device > 0xFF {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// A one is a pulse followed by a space. The start bit is one, mode
+	// is zero and the trailer bit is double length
+	bit := func(levels []bool, one bool) []bool {
+		if one {
+			return append(levels, true, false)
+		} else {
+			return append(levels, false, true)
+		}
+	}
+	levels := make([]bool, 0, 2+3*2+4+16*2)
+	levels = bit(levels, true)
+	for i := 0; i < 3; i++ {
+		levels = bit(levels, false)
+	}
+	if code.Toggle {
+		levels = append(levels, true, true, false, false)
+	} else {
+		levels = append(levels, false, false, true, true)
+	}
+	value := code.Device<<8 | code.ScanCode
+	for i := 15; i >= 0; i-- {
+		levels = bit(levels, value&(1<<uint(i)) != 0)
+	}
+	return append([]uint32{RC6_HEADER_PULSE, RC6_HEADER_SPACE}, fromLevels(levels, RC6_UNIT)...), nil
+}
+
+// fromLevels converts levels of one unit into durations, removing
+// any leading and trailing space
+func fromLevels(levels []bool, unit uint32) []uint32 {
+	for len(levels) > 0 && levels[0] == false {
+		levels = levels[1:]
+	}
+	for len(levels) > 0 && levels[len(levels)-1] == false {
+		levels = levels[:len(levels)-1]
+	}
+	frame := make([]uint32, 0, len(levels))
+	for i, level := range levels {
+		if i > 0 && level == levels[i-1] {
+			frame[len(frame)-1] += unit
+		} else {
+			frame = append(frame, unit)
+		}
+	}
+	return frame
+}
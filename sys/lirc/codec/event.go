@@ -0,0 +1,86 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package codec
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// CodecEvent is emitted when a frame is decoded
+type CodecEvent interface {
+	gopi.Event
+
+	// Protocol for the frame
+	Protocol() Protocol
+
+	// Device address
+	Device() uint32
+
+	// Scancode for the button
+	ScanCode() uint32
+
+	// Repeat is true when the button is held down
+	Repeat() bool
+
+	// Timestamp of the end of the frame
+	Timestamp() time.Time
+}
+
+type evt struct {
+	source    gopi.Driver
+	code      Code
+	timestamp time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENT INTERFACE
+
+func NewEvent(source gopi.Driver, code Code, ts time.Time) CodecEvent {
+	return &evt{source, code, ts}
+}
+
+func (this *evt) Name() string {
+	return "CodecEvent"
+}
+
+func (this *evt) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *evt) Protocol() Protocol {
+	return this.code.Protocol
+}
+
+func (this *evt) Device() uint32 {
+	return this.code.Device
+}
+
+func (this *evt) ScanCode() uint32 {
+	return this.code.ScanCode
+}
+
+func (this *evt) Repeat() bool {
+	return this.code.Repeat
+}
+
+func (this *evt) Timestamp() time.Time {
+	return this.timestamp
+}
+
+func (this *evt) String() string {
+	return fmt.Sprintf("<sys.lirc.codec.event>{ protocol=%v device=0x%X scancode=0x%X repeat=%v ts=%v }", this.code.Protocol, this.code.Device, this.code.ScanCode, this.code.Repeat, this.timestamp.Format(time.StampMicro))
+}
@@ -0,0 +1,36 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package codec
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register codec, which is returned by app.ModuleInstance("lirc/codec")
+	gopi.RegisterModule(gopi.Module{
+		Name:     "lirc/codec",
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Requires: []string{"lirc"},
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagDuration("lirc.repeat", CODEC_REPEAT, "Time between repeated infrared codes")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			repeat, _ := app.AppFlags.GetDuration("lirc.repeat")
+			return gopi.Open(Codec{
+				LIRC:   app.LIRC,
+				Repeat: repeat,
+			}, app.Logger)
+		},
+	})
+}
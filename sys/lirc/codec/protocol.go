@@ -0,0 +1,134 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package codec
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Protocol is an infrared protocol
+type Protocol uint8
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PROTOCOL_NONE   Protocol = iota
+	PROTOCOL_NEC             // NEC with 8-bit or 16-bit (extended) device
+	PROTOCOL_RC5             // Philips RC5 with 7-bit (RC5X) scancode
+	PROTOCOL_RC6             // Philips RC6 mode 0
+	PROTOCOL_SONY12          // Sony SIRC with 5-bit device
+	PROTOCOL_SONY15          // Sony SIRC with 8-bit device
+	PROTOCOL_SONY20          // Sony SIRC with 5-bit device and 8-bit extended device
+	PROTOCOL_MAX    = PROTOCOL_SONY20
+)
+
+// Timings in microseconds
+const (
+	NEC_HEADER_PULSE = 9000
+	NEC_HEADER_SPACE = 4500
+	NEC_REPEAT_SPACE = 2250
+	NEC_BIT_PULSE    = 560
+	NEC_BIT_0_SPACE  = 560
+	NEC_BIT_1_SPACE  = 1690
+	NEC_PERIOD       = 108000
+	NEC_CARRIER_HZ   = 38000
+
+	SONY_HEADER_PULSE = 2400
+	SONY_HEADER_SPACE = 600
+	SONY_BIT_0_PULSE  = 600
+	SONY_BIT_1_PULSE  = 1200
+	SONY_BIT_SPACE    = 600
+	SONY_PERIOD       = 45000
+	SONY_CARRIER_HZ   = 40000
+
+	RC5_UNIT       = 889
+	RC5_BITS       = 14
+	RC5_PERIOD     = 113778
+	RC5_CARRIER_HZ = 36000
+
+	RC6_UNIT         = 444
+	RC6_HEADER_PULSE = 6 * RC6_UNIT
+	RC6_HEADER_SPACE = 2 * RC6_UNIT
+	RC6_PERIOD       = 106667
+	RC6_CARRIER_HZ   = 36000
+)
+
+const (
+	// TOLERANCE is the percentage by which a timing may differ
+	TOLERANCE = 35
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// CarrierHz returns the carrier frequency for the protocol
+func (p Protocol) CarrierHz() uint32 {
+	switch p {
+	case PROTOCOL_NEC:
+		return NEC_CARRIER_HZ
+	case PROTOCOL_RC5:
+		return RC5_CARRIER_HZ
+	case PROTOCOL_RC6:
+		return RC6_CARRIER_HZ
+	case PROTOCOL_SONY12, PROTOCOL_SONY15, PROTOCOL_SONY20:
+		return SONY_CARRIER_HZ
+	default:
+		return 0
+	}
+}
+
+// Period returns the time between the start of repeated frames
+func (p Protocol) Period() uint32 {
+	switch p {
+	case PROTOCOL_NEC:
+		return NEC_PERIOD
+	case PROTOCOL_RC5:
+		return RC5_PERIOD
+	case PROTOCOL_RC6:
+		return RC6_PERIOD
+	case PROTOCOL_SONY12, PROTOCOL_SONY15, PROTOCOL_SONY20:
+		return SONY_PERIOD
+	default:
+		return 0
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p Protocol) String() string {
+	switch p {
+	case PROTOCOL_NONE:
+		return "PROTOCOL_NONE"
+	case PROTOCOL_NEC:
+		return "PROTOCOL_NEC"
+	case PROTOCOL_RC5:
+		return "PROTOCOL_RC5"
+	case PROTOCOL_RC6:
+		return "PROTOCOL_RC6"
+	case PROTOCOL_SONY12:
+		return "PROTOCOL_SONY12"
+	case PROTOCOL_SONY15:
+		return "PROTOCOL_SONY15"
+	case PROTOCOL_SONY20:
+		return "PROTOCOL_SONY20"
+	default:
+		return "[?? Invalid Protocol value]"
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// within returns true if a value is within tolerance of an expected value
+func within(value, expected uint32) bool {
+	delta := expected * TOLERANCE / 100
+	return value+delta >= expected && value <= expected+delta
+}
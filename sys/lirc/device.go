@@ -0,0 +1,194 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lirc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Device is the /dev/lircN file. Values are read and written as
+// 32-bit words in host byte order. Any file can be used, in which
+// case the ioctls return ENOTTY and the device is assumed to receive
+// mode2 values and send pulses
+type Device interface {
+	io.ReadWriteCloser
+
+	// SyscallConn returns the connection for ioctls, which unlike
+	// Fd() doesn't put the file into blocking mode
+	SyscallConn() (syscall.RawConn, error)
+}
+
+// Features is the device features bitmask
+type Features uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	LIRC_DEV_PATH = "/dev"
+	LIRC_DEV_NAME = "lirc%v"
+	LIRC_IOC_TYPE = 0x69
+)
+
+const (
+	LIRC_CAN_SEND_PULSE            Features = Features(gopi.LIRC_MODE_PULSE)
+	LIRC_CAN_SEND_MASK             Features = 0x0000003F
+	LIRC_CAN_SET_SEND_CARRIER      Features = 0x00000100
+	LIRC_CAN_SET_SEND_DUTY_CYCLE   Features = 0x00000200
+	LIRC_CAN_SET_TRANSMITTER_MASK  Features = 0x00000400
+	LIRC_CAN_REC_MODE2             Features = Features(gopi.LIRC_MODE_MODE2) << 16
+	LIRC_CAN_REC_LIRCCODE          Features = Features(gopi.LIRC_MODE_LIRCCODE) << 16
+	LIRC_CAN_REC_MASK              Features = LIRC_CAN_SEND_MASK << 16
+	LIRC_CAN_SET_REC_CARRIER       Features = LIRC_CAN_SET_SEND_CARRIER << 16
+	LIRC_CAN_MEASURE_CARRIER       Features = 0x02000000
+	LIRC_CAN_USE_WIDEBAND_RECEIVER Features = 0x04000000
+	LIRC_CAN_SET_REC_TIMEOUT       Features = 0x10000000
+	LIRC_CAN_GET_REC_RESOLUTION    Features = 0x20000000
+	LIRC_CAN_SET_REC_CARRIER_RANGE Features = 0x80000000
+
+	// LIRC_FEATURES_FILE are the features assumed when the
+	// device is not a LIRC device
+	LIRC_FEATURES_FILE = LIRC_CAN_REC_MODE2 | LIRC_CAN_SEND_PULSE
+)
+
+const (
+	LIRC_VALUE_MASK = 0x00FFFFFF
+	LIRC_MODE2_MASK = 0xFF000000
+	LIRC_WORD_SIZE  = 4
+)
+
+var (
+	LIRC_GET_FEATURES            = ioctlIOR(0x00)
+	LIRC_GET_SEND_MODE           = ioctlIOR(0x01)
+	LIRC_GET_REC_MODE            = ioctlIOR(0x02)
+	LIRC_GET_REC_RESOLUTION      = ioctlIOR(0x07)
+	LIRC_SET_SEND_MODE           = ioctlIOW(0x11)
+	LIRC_SET_REC_MODE            = ioctlIOW(0x12)
+	LIRC_SET_SEND_CARRIER        = ioctlIOW(0x13)
+	LIRC_SET_REC_CARRIER         = ioctlIOW(0x14)
+	LIRC_SET_SEND_DUTY_CYCLE     = ioctlIOW(0x15)
+	LIRC_SET_REC_TIMEOUT         = ioctlIOW(0x18)
+	LIRC_SET_REC_TIMEOUT_REPORTS = ioctlIOW(0x19)
+	LIRC_SET_REC_CARRIER_RANGE   = ioctlIOW(0x1F)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN
+
+// OpenDevice opens /dev/lircN for reading and writing
+func OpenDevice(path string, device uint) (Device, error) {
+	if path == "" {
+		path = LIRC_DEV_PATH
+	}
+	// Non-blocking so that closing the file ends the read loop
+	return os.OpenFile(filepath.Join(path, fmt.Sprintf(LIRC_DEV_NAME, device)), os.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f Features) String() string {
+	str := ""
+	for v := Features(1); v != 0; v <<= 1 {
+		if f&v == 0 {
+			continue
+		}
+		switch v {
+		case LIRC_CAN_SEND_PULSE:
+			str += "LIRC_CAN_SEND_PULSE|"
+		case LIRC_CAN_SET_SEND_CARRIER:
+			str += "LIRC_CAN_SET_SEND_CARRIER|"
+		case LIRC_CAN_SET_SEND_DUTY_CYCLE:
+			str += "LIRC_CAN_SET_SEND_DUTY_CYCLE|"
+		case LIRC_CAN_SET_TRANSMITTER_MASK:
+			str += "LIRC_CAN_SET_TRANSMITTER_MASK|"
+		case LIRC_CAN_REC_MODE2:
+			str += "LIRC_CAN_REC_MODE2|"
+		case LIRC_CAN_REC_LIRCCODE:
+			str += "LIRC_CAN_REC_LIRCCODE|"
+		case LIRC_CAN_SET_REC_CARRIER:
+			str += "LIRC_CAN_SET_REC_CARRIER|"
+		case LIRC_CAN_MEASURE_CARRIER:
+			str += "LIRC_CAN_MEASURE_CARRIER|"
+		case LIRC_CAN_USE_WIDEBAND_RECEIVER:
+			str += "LIRC_CAN_USE_WIDEBAND_RECEIVER|"
+		case LIRC_CAN_SET_REC_TIMEOUT:
+			str += "LIRC_CAN_SET_REC_TIMEOUT|"
+		case LIRC_CAN_GET_REC_RESOLUTION:
+			str += "LIRC_CAN_GET_REC_RESOLUTION|"
+		case LIRC_CAN_SET_REC_CARRIER_RANGE:
+			str += "LIRC_CAN_SET_REC_CARRIER_RANGE|"
+		default:
+			str += fmt.Sprintf("0x%08X|", uint32(v))
+		}
+	}
+	if str == "" {
+		return "LIRC_CAN_NONE"
+	}
+	return str[:len(str)-1]
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IOCTL
+
+const (
+	_IOC_WRITE = 1
+	_IOC_READ  = 2
+)
+
+func ioctlIOR(nr uintptr) uintptr {
+	return (_IOC_READ << 30) | (LIRC_WORD_SIZE << 16) | (LIRC_IOC_TYPE << 8) | nr
+}
+
+func ioctlIOW(nr uintptr) uintptr {
+	return (_IOC_WRITE << 30) | (LIRC_WORD_SIZE << 16) | (LIRC_IOC_TYPE << 8) | nr
+}
+
+func getUint32(dev Device, cmd uintptr) (uint32, error) {
+	var value uint32
+	if err := ioctl(dev, cmd, &value); err != nil {
+		return 0, err
+	} else {
+		return value, nil
+	}
+}
+
+func setUint32(dev Device, cmd uintptr, value uint32) error {
+	return ioctl(dev, cmd, &value)
+}
+
+func ioctl(dev Device, cmd uintptr, value *uint32) error {
+	var errno syscall.Errno
+	if conn, err := dev.SyscallConn(); err != nil {
+		return err
+	} else if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, uintptr(unsafe.Pointer(value)))
+	}); err != nil {
+		return err
+	} else if errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lirc
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type evt struct {
+	source    gopi.Driver
+	t         gopi.LIRCType
+	value     uint32
+	timestamp time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENT INTERFACE
+
+func NewEvent(source gopi.LIRC, t gopi.LIRCType, value uint32, ts time.Time) gopi.LIRCEvent {
+	return &evt{source, t, value, ts}
+}
+
+func (this *evt) Name() string {
+	return "LIRCEvent"
+}
+
+func (this *evt) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *evt) Type() gopi.LIRCType {
+	return this.t
+}
+
+func (this *evt) Value() uint32 {
+	return this.value
+}
+
+func (this *evt) Timestamp() time.Time {
+	return this.timestamp
+}
+
+func (this *evt) String() string {
+	return fmt.Sprintf("<sys.lirc.event>{ type=%v value=%v ts=%v }", this.t, this.value, this.timestamp.Format(time.StampMicro))
+}
@@ -0,0 +1,37 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lirc
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register LIRC
+	gopi.RegisterModule(gopi.Module{
+		Name: "sys/lirc",
+		Type: gopi.MODULE_TYPE_LIRC,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("lirc.device", 0, "LIRC device number")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			device, _ := app.AppFlags.GetUint("lirc.device")
+			return gopi.Open(LIRC{
+				Device: device,
+			}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,415 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lirc
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// LIRC is the configuration for the /dev/lircN driver
+type LIRC struct {
+	Device  uint   // Device number
+	DevPath string // Path to device (default: /dev)
+	File    Device // Device file, or nil to open /dev/lircN
+}
+
+type lirc struct {
+	log      gopi.Logger
+	device   uint
+	dev      Device
+	features Features
+	rcvmode  gopi.LIRCMode
+	sendmode gopi.LIRCMode
+	done     chan struct{}
+
+	sync.Mutex
+	event.Publisher
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	LIRC_DUTY_CYCLE_MAX = 100
+	LIRC_READ_WORDS     = 64
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the LIRC driver
+func (config LIRC) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.lirc.Open{ device=%v }", config.Device)
+
+	this := new(lirc)
+	this.log = log
+	this.device = config.Device
+	this.done = make(chan struct{})
+
+	// Open the device
+	if config.File != nil {
+		this.dev = config.File
+	} else if dev, err := OpenDevice(config.DevPath, config.Device); err != nil {
+		return nil, err
+	} else {
+		this.dev = dev
+	}
+
+	// Get the features and modes, or assume mode2 and pulse for
+	// files which are not LIRC devices
+	if features, err := getUint32(this.dev, LIRC_GET_FEATURES); err == syscall.ENOTTY {
+		this.features = LIRC_FEATURES_FILE
+		this.rcvmode = gopi.LIRC_MODE_MODE2
+		this.sendmode = gopi.LIRC_MODE_PULSE
+	} else if err != nil {
+		this.dev.Close()
+		return nil, err
+	} else {
+		this.features = Features(features)
+		if this.features&LIRC_CAN_REC_MASK != 0 {
+			if mode, err := getUint32(this.dev, LIRC_GET_REC_MODE); err != nil {
+				this.dev.Close()
+				return nil, err
+			} else {
+				this.rcvmode = gopi.LIRCMode(mode)
+			}
+		}
+		if this.features&LIRC_CAN_SEND_MASK != 0 {
+			if mode, err := getUint32(this.dev, LIRC_GET_SEND_MODE); err != nil {
+				this.dev.Close()
+				return nil, err
+			} else {
+				this.sendmode = gopi.LIRCMode(mode)
+			}
+		}
+	}
+
+	// Read values in the background until the device is closed
+	if this.features&LIRC_CAN_REC_MASK != 0 {
+		go this.readTask(this.dev)
+	} else {
+		close(this.done)
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the LIRC driver
+func (this *lirc) Close() error {
+	this.log.Debug("sys.lirc.Close{ device=%v }", this.device)
+
+	this.Lock()
+	if this.dev == nil {
+		this.Unlock()
+		return nil
+	}
+	err := this.dev.Close()
+	this.dev = nil
+	this.Unlock()
+
+	// Unsubscribe before waiting, which ends emitting to subscribers
+	// which are not receiving, then wait for the read loop to end
+	this.Publisher.Close()
+	<-this.done
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - MODES
+
+// RcvMode returns the receive mode
+func (this *lirc) RcvMode() gopi.LIRCMode {
+	this.Lock()
+	defer this.Unlock()
+	return this.rcvmode
+}
+
+// SendMode returns the send mode
+func (this *lirc) SendMode() gopi.LIRCMode {
+	this.Lock()
+	defer this.Unlock()
+	return this.sendmode
+}
+
+// SetRcvMode sets the receive mode, which only decodes events in
+// LIRC_MODE_MODE2
+func (this *lirc) SetRcvMode(mode gopi.LIRCMode) error {
+	this.log.Debug2("sys.lirc.SetRcvMode{ mode=%v }", mode)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if mode == gopi.LIRC_MODE_NONE || mode > gopi.LIRC_MODE_MAX {
+		return gopi.ErrBadParameter
+	} else if err := this.check(Features(mode) << 16); err != nil {
+		return err
+	} else if mode == this.rcvmode {
+		return nil
+	} else if err := setUint32(this.dev, LIRC_SET_REC_MODE, uint32(mode)); err != nil {
+		return err
+	} else {
+		this.rcvmode = mode
+		return nil
+	}
+}
+
+// SetSendMode sets the send mode
+func (this *lirc) SetSendMode(mode gopi.LIRCMode) error {
+	this.log.Debug2("sys.lirc.SetSendMode{ mode=%v }", mode)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if mode == gopi.LIRC_MODE_NONE || mode > gopi.LIRC_MODE_MAX {
+		return gopi.ErrBadParameter
+	} else if err := this.check(Features(mode)); err != nil {
+		return err
+	} else if mode == this.sendmode {
+		return nil
+	} else if err := setUint32(this.dev, LIRC_SET_SEND_MODE, uint32(mode)); err != nil {
+		return err
+	} else {
+		this.sendmode = mode
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - RECEIVE PARAMETERS
+
+// GetRcvResolution returns the receive resolution in microseconds
+func (this *lirc) GetRcvResolution() (uint32, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.check(LIRC_CAN_GET_REC_RESOLUTION); err != nil {
+		return 0, err
+	} else {
+		return getUint32(this.dev, LIRC_GET_REC_RESOLUTION)
+	}
+}
+
+// SetRcvTimeout sets the receive timeout in microseconds, where zero
+// disables the timeout
+func (this *lirc) SetRcvTimeout(micros uint32) error {
+	this.log.Debug2("sys.lirc.SetRcvTimeout{ micros=%v }", micros)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.check(LIRC_CAN_SET_REC_TIMEOUT); err != nil {
+		return err
+	} else {
+		return setUint32(this.dev, LIRC_SET_REC_TIMEOUT, micros)
+	}
+}
+
+// SetRcvTimeoutReports enables LIRC_TYPE_TIMEOUT events
+func (this *lirc) SetRcvTimeoutReports(enable bool) error {
+	this.log.Debug2("sys.lirc.SetRcvTimeoutReports{ enable=%v }", enable)
+
+	this.Lock()
+	defer this.Unlock()
+
+	value := uint32(0)
+	if enable {
+		value = 1
+	}
+	if err := this.check(LIRC_CAN_SET_REC_TIMEOUT); err != nil {
+		return err
+	} else {
+		return setUint32(this.dev, LIRC_SET_REC_TIMEOUT_REPORTS, value)
+	}
+}
+
+// SetRcvCarrierHz sets the receive carrier frequency
+func (this *lirc) SetRcvCarrierHz(value uint32) error {
+	this.log.Debug2("sys.lirc.SetRcvCarrierHz{ value=%v }", value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value == 0 {
+		return gopi.ErrBadParameter
+	} else if err := this.check(LIRC_CAN_SET_REC_CARRIER); err != nil {
+		return err
+	} else {
+		return setUint32(this.dev, LIRC_SET_REC_CARRIER, value)
+	}
+}
+
+// SetRcvCarrierRangeHz sets the range of receive carrier frequencies.
+// The lower bound is set first, and takes effect when the upper bound
+// is set
+func (this *lirc) SetRcvCarrierRangeHz(min uint32, max uint32) error {
+	this.log.Debug2("sys.lirc.SetRcvCarrierRangeHz{ min=%v max=%v }", min, max)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if min == 0 || min > max {
+		return gopi.ErrBadParameter
+	} else if err := this.check(LIRC_CAN_SET_REC_CARRIER_RANGE | LIRC_CAN_SET_REC_CARRIER); err != nil {
+		return err
+	} else if err := setUint32(this.dev, LIRC_SET_REC_CARRIER_RANGE, min); err != nil {
+		return err
+	} else {
+		return setUint32(this.dev, LIRC_SET_REC_CARRIER, max)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - SEND PARAMETERS
+
+// SetSendCarrierHz sets the send carrier frequency
+func (this *lirc) SetSendCarrierHz(value uint32) error {
+	this.log.Debug2("sys.lirc.SetSendCarrierHz{ value=%v }", value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value == 0 {
+		return gopi.ErrBadParameter
+	} else if err := this.check(LIRC_CAN_SET_SEND_CARRIER); err != nil {
+		return err
+	} else {
+		return setUint32(this.dev, LIRC_SET_SEND_CARRIER, value)
+	}
+}
+
+// SetSendDutyCycle sets the send duty cycle as a percentage
+func (this *lirc) SetSendDutyCycle(value uint32) error {
+	this.log.Debug2("sys.lirc.SetSendDutyCycle{ value=%v }", value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value == 0 || value >= LIRC_DUTY_CYCLE_MAX {
+		return gopi.ErrBadParameter
+	} else if err := this.check(LIRC_CAN_SET_SEND_DUTY_CYCLE); err != nil {
+		return err
+	} else {
+		return setUint32(this.dev, LIRC_SET_SEND_DUTY_CYCLE, value)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - SEND
+
+// PulseSend transmits alternating pulse and space durations in
+// microseconds, starting and ending with a pulse
+func (this *lirc) PulseSend(values []uint32) error {
+	this.log.Debug2("sys.lirc.PulseSend{ values=%v }", values)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if len(values) == 0 || len(values)%2 == 0 {
+		return gopi.ErrBadParameter
+	} else if err := this.check(LIRC_CAN_SEND_PULSE); err != nil {
+		return err
+	} else if this.sendmode != gopi.LIRC_MODE_PULSE {
+		return gopi.ErrOutOfOrder
+	}
+
+	buf := make([]byte, len(values)*LIRC_WORD_SIZE)
+	for i, value := range values {
+		if value == 0 || value > LIRC_VALUE_MASK {
+			return gopi.ErrBadParameter
+		}
+		*(*uint32)(unsafe.Pointer(&buf[i*LIRC_WORD_SIZE])) = value
+	}
+	if n, err := this.dev.Write(buf); err != nil {
+		return err
+	} else if n != len(buf) {
+		return gopi.ErrUnexpectedResponse
+	} else {
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *lirc) String() string {
+	return fmt.Sprintf("<sys.lirc>{ device=%v features=%v rcvmode=%v sendmode=%v }", this.device, this.features, this.rcvmode, this.sendmode)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// check returns ErrOutOfOrder if the driver is closed, or
+// ErrNotImplemented if the device doesn't have all of the features
+func (this *lirc) check(features Features) error {
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if this.features&features != features {
+		return gopi.ErrNotImplemented
+	} else {
+		return nil
+	}
+}
+
+// decode returns the type and value of a mode2 word
+func decode(word uint32) (gopi.LIRCType, uint32) {
+	return gopi.LIRCType(word & LIRC_MODE2_MASK), word & LIRC_VALUE_MASK
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// readTask reads words until the device is closed, emitting events
+// for mode2 values. Partial words are kept for the next read
+func (this *lirc) readTask(dev Device) {
+	defer close(this.done)
+
+	buf := make([]byte, LIRC_READ_WORDS*LIRC_WORD_SIZE)
+	offset := 0
+	for {
+		n, err := dev.Read(buf[offset:])
+		if err != nil {
+			return
+		}
+		n += offset
+		ts := time.Now()
+		this.Lock()
+		mode := this.rcvmode
+		this.Unlock()
+		words := n / LIRC_WORD_SIZE
+		for i := 0; i < words; i++ {
+			if mode != gopi.LIRC_MODE_MODE2 {
+				continue
+			}
+			word := *(*uint32)(unsafe.Pointer(&buf[i*LIRC_WORD_SIZE]))
+			if t, value := decode(word); t > gopi.LIRC_TYPE_MAX {
+				this.log.Warn("sys.lirc: Ignoring value 0x%08X", word)
+			} else {
+				this.Emit(NewEvent(this, t, value, ts))
+			}
+		}
+		offset = copy(buf, buf[words*LIRC_WORD_SIZE:n])
+	}
+}
@@ -0,0 +1,221 @@
+//go:build linux
+// +build linux
+
+package lirc_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/lirc"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE DEVICE
+
+// pipe reads mode2 values from a pipe, and records values sent
+type pipe struct {
+	*os.File
+	w    *os.File
+	sent bytes.Buffer
+}
+
+func newPipe(t *testing.T) *pipe {
+	t.Helper()
+	if r, w, err := os.Pipe(); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return &pipe{File: r, w: w}
+	}
+}
+
+func (this *pipe) Write(data []byte) (int, error) {
+	return this.sent.Write(data)
+}
+
+func (this *pipe) Close() error {
+	this.w.Close()
+	return this.File.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestLIRC_000(t *testing.T) {
+	dev := newPipe(t)
+	driver := openLIRC(t, dev)
+	defer driver.Close()
+
+	// Files which are not LIRC devices receive mode2 and send pulses
+	if mode := driver.RcvMode(); mode != gopi.LIRC_MODE_MODE2 {
+		t.Error("Unexpected receive mode", mode)
+	}
+	if mode := driver.SendMode(); mode != gopi.LIRC_MODE_PULSE {
+		t.Error("Unexpected send mode", mode)
+	}
+	if err := driver.SetRcvMode(gopi.LIRC_MODE_LIRCCODE); err != gopi.ErrNotImplemented {
+		t.Error("Expected ErrNotImplemented, got", err)
+	}
+	if err := driver.SetRcvMode(gopi.LIRC_MODE_NONE); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := driver.SetSendCarrierHz(38000); err != gopi.ErrNotImplemented {
+		t.Error("Expected ErrNotImplemented, got", err)
+	}
+	if _, err := driver.GetRcvResolution(); err != gopi.ErrNotImplemented {
+		t.Error("Expected ErrNotImplemented, got", err)
+	}
+	t.Log(driver)
+}
+
+func TestLIRC_001(t *testing.T) {
+	dev := newPipe(t)
+	driver := openLIRC(t, dev)
+	defer driver.Close()
+
+	// Replay a captured sequence
+	words := readMode2(t, "testdata/nec.mode2")
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+	go func() {
+		buf := make([]byte, len(words)*4)
+		for i, word := range words {
+			binary.LittleEndian.PutUint32(buf[i*4:], word)
+		}
+		// Write in uneven chunks to split words between reads
+		for len(buf) > 0 {
+			n := 7
+			if n > len(buf) {
+				n = len(buf)
+			}
+			dev.w.Write(buf[:n])
+			buf = buf[n:]
+		}
+	}()
+	for i, word := range words {
+		select {
+		case evt := <-events:
+			if evt_, ok := evt.(gopi.LIRCEvent); ok == false {
+				t.Fatal("Expected LIRCEvent, got", evt)
+			} else if evt_.Type() != gopi.LIRCType(word&lirc.LIRC_MODE2_MASK) || evt_.Value() != word&lirc.LIRC_VALUE_MASK {
+				t.Errorf("Event %v: unexpected %v", i, evt)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for event", i)
+		}
+	}
+}
+
+func TestLIRC_002(t *testing.T) {
+	dev := newPipe(t)
+	driver := openLIRC(t, dev)
+	defer driver.Close()
+
+	if err := driver.PulseSend([]uint32{9000, 4500, 560}); err != nil {
+		t.Error(err)
+	} else if sent := dev.sent.Bytes(); len(sent) != 12 {
+		t.Error("Unexpected sent length", len(sent))
+	} else if value := binary.LittleEndian.Uint32(sent[4:]); value != 4500 {
+		t.Error("Unexpected value", value)
+	}
+
+	// Values must start and end with a pulse, and not be zero
+	if err := driver.PulseSend([]uint32{9000, 4500}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := driver.PulseSend([]uint32{0}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Closed driver can't send
+	driver.Close()
+	if err := driver.PulseSend([]uint32{560}); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+}
+
+func TestLIRC_003(t *testing.T) {
+	dev := newPipe(t)
+	driver := openLIRC(t, dev)
+
+	// A subscriber which doesn't receive doesn't block Close
+	driver.Subscribe()
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(gopi.LIRC_TYPE_PULSE)|560)
+	for i := 0; i < 3; i++ {
+		dev.w.Write(buf)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		driver.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for Close")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openLIRC(t *testing.T, dev lirc.Device) gopi.LIRC {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(lirc.LIRC{File: dev}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.LIRC)
+	}
+}
+
+// readMode2 reads the output of the mode2 utility as words
+func readMode2(t *testing.T, path string) []uint32 {
+	t.Helper()
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	words := []uint32{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch fields[0] {
+		case "pulse":
+			words = append(words, uint32(gopi.LIRC_TYPE_PULSE)|uint32(value))
+		case "space":
+			words = append(words, uint32(gopi.LIRC_TYPE_SPACE)|uint32(value))
+		case "timeout":
+			words = append(words, uint32(gopi.LIRC_TYPE_TIMEOUT)|uint32(value))
+		default:
+			t.Fatal("Unexpected line", scanner.Text())
+		}
+	}
+	return words
+}
@@ -0,0 +1,308 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRUCTS
+
+// The output format for log lines
+type Format uint
+
+// A key and value pair
+type field struct {
+	key   string
+	value interface{}
+}
+
+// A message with fields, which is written in one of the formats
+type entry struct {
+	ts     time.Time
+	level  Level
+	tag    string
+	name   string
+	msg    string
+	fields []field
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTS
+
+const (
+	FORMAT_TEXT   Format = iota // Human-readable text
+	FORMAT_LOGFMT               // logfmt key=value lines
+	FORMAT_JSON                 // JSON lines
+)
+
+const (
+	// Time format for logfmt and JSON lines
+	LOG_TIME_FORMAT = "2006-01-02T15:04:05.000Z07:00"
+
+	// Structured data identifier for syslog
+	SYSLOG_SD_ID = "fields@32473"
+
+	// Key for values without a key
+	KEY_MISSING = "!BADKEY"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ParseFormat returns a format from the name text, logfmt or json
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "text":
+		return FORMAT_TEXT, nil
+	case "logfmt":
+		return FORMAT_LOGFMT, nil
+	case "json":
+		return FORMAT_JSON, nil
+	default:
+		return FORMAT_TEXT, gopi.ErrBadParameter
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FIELDS
+
+// newFields returns fields from alternating key and value pairs. A
+// trailing key without a value has a nil value
+func newFields(kv []interface{}) []field {
+	if len(kv) == 0 {
+		return nil
+	}
+	fields := make([]field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		f := field{key: fmt.Sprint(kv[i])}
+		if key, ok := kv[i].(string); ok {
+			f.key = key
+		}
+		if f.key == "" {
+			f.key = KEY_MISSING
+		}
+		if i+1 < len(kv) {
+			f.value = kv[i+1]
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// joinFields returns a new slice of fields which appends b to a
+func joinFields(a, b []field) []field {
+	if len(b) == 0 {
+		return a
+	}
+	fields := make([]field, 0, len(a)+len(b))
+	return append(append(fields, a...), b...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE
+
+// text returns the message as "[LEVEL] message key=value"
+func (this *entry) text() string {
+	buf := new(bytes.Buffer)
+	if this.name != "" {
+		fmt.Fprintf(buf, "[%v] %v: %v", this.level, this.name, this.msg)
+	} else {
+		fmt.Fprintf(buf, "[%v] %v", this.level, this.msg)
+	}
+	for _, f := range this.fields {
+		buf.WriteByte(' ')
+		writeLogfmt(buf, f.key, f.value)
+	}
+	return buf.String()
+}
+
+// logfmt returns the message as a logfmt line
+func (this *entry) logfmt() string {
+	buf := new(bytes.Buffer)
+	writeLogfmt(buf, "ts", this.ts.Format(LOG_TIME_FORMAT))
+	buf.WriteByte(' ')
+	writeLogfmt(buf, "level", this.level.Name())
+	if this.tag != "" {
+		buf.WriteByte(' ')
+		writeLogfmt(buf, "tag", this.tag)
+	}
+	if this.name != "" {
+		buf.WriteByte(' ')
+		writeLogfmt(buf, "name", this.name)
+	}
+	buf.WriteByte(' ')
+	writeLogfmt(buf, "msg", this.msg)
+	for _, f := range this.fields {
+		buf.WriteByte(' ')
+		writeLogfmt(buf, f.key, f.value)
+	}
+	return buf.String()
+}
+
+// json returns the message as a JSON object on a single line
+func (this *entry) json() string {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	writeJSON(buf, "ts", this.ts.Format(LOG_TIME_FORMAT))
+	buf.WriteByte(',')
+	writeJSON(buf, "level", this.level.Name())
+	if this.tag != "" {
+		buf.WriteByte(',')
+		writeJSON(buf, "tag", this.tag)
+	}
+	if this.name != "" {
+		buf.WriteByte(',')
+		writeJSON(buf, "name", this.name)
+	}
+	buf.WriteByte(',')
+	writeJSON(buf, "msg", this.msg)
+	for _, f := range this.fields {
+		buf.WriteByte(',')
+		writeJSON(buf, f.key, f.value)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// syslog returns the message preceded by the name and fields as
+// RFC5424 structured data
+func (this *entry) syslog() string {
+	if sd := this.sd(); sd == "" {
+		return this.msg
+	} else {
+		return sd + " " + this.msg
+	}
+}
+
+// sd returns the name and fields as RFC5424 structured data, or an
+// empty string if there is no name and there are no fields
+func (this *entry) sd() string {
+	if len(this.fields) == 0 && this.name == "" {
+		return ""
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("[" + SYSLOG_SD_ID)
+	if this.name != "" {
+		fmt.Fprintf(buf, " name=\"%v\"", sdValue(this.name))
+	}
+	for _, f := range this.fields {
+		fmt.Fprintf(buf, " %v=\"%v\"", sdName(f.key), sdValue(valueString(f.value)))
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// valueString returns a value as a string
+func valueString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case error:
+		return value.Error()
+	case time.Time:
+		return value.Format(LOG_TIME_FORMAT)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// writeLogfmt writes key=value, quoting the value when required
+func writeLogfmt(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteString(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		} else {
+			return r
+		}
+	}, key))
+	buf.WriteByte('=')
+	str := valueString(value)
+	if str == "" && value != nil {
+		buf.WriteString(`""`)
+	} else if strings.IndexFunc(str, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || unicode.IsPrint(r) == false
+	}) >= 0 {
+		buf.WriteString(strconv.Quote(str))
+	} else {
+		buf.WriteString(str)
+	}
+}
+
+// writeJSON writes "key":value, where the value is encoded as JSON when
+// possible or else as a string
+func writeJSON(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	switch value.(type) {
+	case error, time.Time, time.Duration:
+		value = valueString(value)
+	}
+	if v, err := json.Marshal(value); err != nil {
+		v, _ = json.Marshal(valueString(value))
+		buf.Write(v)
+	} else {
+		buf.Write(v)
+	}
+}
+
+// sdName returns a structured data parameter name, which is up to
+// 32 printable characters except space, equals, quote and bracket
+func sdName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == '"' || r == ']' {
+			return '_'
+		} else {
+			return r
+		}
+	}, key)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// sdValue escapes quote, backslash and bracket in a structured data
+// parameter value
+func sdValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f Format) String() string {
+	switch f {
+	case FORMAT_TEXT:
+		return "FORMAT_TEXT"
+	case FORMAT_LOGFMT:
+		return "FORMAT_LOGFMT"
+	case FORMAT_JSON:
+		return "FORMAT_JSON"
+	default:
+		return "[Invalid Format value]"
+	}
+}
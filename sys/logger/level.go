@@ -0,0 +1,246 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRUCTS
+
+// Driver is the logger, which allows the level to be changed at
+// runtime for all loggers or for named loggers
+type Driver interface {
+	gopi.StructuredLogger
+
+	// Return the level for this logger
+	Level() Level
+
+	// Set the default level, or the level for named loggers. Names
+	// can be module names, reserved module names or identifiers
+	SetLevel(level Level, names ...string)
+
+	// Return recent messages which match a query, when messages
+	// are kept in memory
+	Entries(query Query) []Event
+
+	// Subscribe to messages when messages are kept in memory
+	gopi.Publisher
+}
+
+// levels are the default level and the levels for named loggers
+type levels struct {
+	level  Level
+	names  map[string]Level
+	config struct {
+		level Level
+		names map[string]Level
+	}
+	signal chan os.Signal
+	done   chan struct{}
+
+	sync.RWMutex
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTS
+
+const (
+	// Separator between names of named loggers
+	NAME_SEPARATOR = "/"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ParseLevel returns a level from a name such as debug2, debug, info,
+// warn, error, fatal or none
+func ParseLevel(value string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "any":
+		return LOG_ANY, nil
+	case "debug2":
+		return LOG_DEBUG2, nil
+	case "debug":
+		return LOG_DEBUG, nil
+	case "info":
+		return LOG_INFO, nil
+	case "warn", "warning":
+		return LOG_WARN, nil
+	case "error":
+		return LOG_ERROR, nil
+	case "fatal":
+		return LOG_FATAL, nil
+	case "none":
+		return LOG_NONE, nil
+	default:
+		return LOG_NONE, gopi.ErrBadParameter
+	}
+}
+
+// ParseLevels returns levels for named loggers from comma-separated
+// name=level pairs such as "sys/timer=debug2,gpio=warn". A level without
+// a name sets the default level, which is returned with an empty name
+func ParseLevels(value string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value := "", pair
+		if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+			if name, value = strings.TrimSpace(kv[0]), kv[1]; name == "" {
+				return nil, fmt.Errorf("%v: %v", pair, gopi.ErrBadParameter)
+			}
+		}
+		if level, err := ParseLevel(value); err != nil {
+			return nil, fmt.Errorf("%v: %v", pair, err)
+		} else {
+			levels[name] = level
+		}
+	}
+	return levels, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LEVELS
+
+// init sets the default level and the levels for names, where an
+// empty name overrides the default level. Levels are changed on SIGUSR1
+// and SIGUSR2 when signals is true
+func (this *levels) init(level Level, names map[string]Level, signals bool) {
+	this.config.level = level
+	this.config.names = make(map[string]Level, len(names))
+	for name, level := range names {
+		if name == "" {
+			this.config.level = level
+		} else {
+			this.config.names[resolveName(name)] = level
+		}
+	}
+	this.reset()
+
+	if signals {
+		this.signal = make(chan os.Signal, 1)
+		this.done = make(chan struct{})
+		signal.Notify(this.signal, syscall.SIGUSR1, syscall.SIGUSR2)
+		go this.signalTask(this.signal, this.done)
+	}
+}
+
+// close stops changing levels on signals
+func (this *levels) close() {
+	this.Lock()
+	defer this.Unlock()
+	if this.done != nil {
+		signal.Stop(this.signal)
+		close(this.done)
+		this.done = nil
+	}
+}
+
+// reset restores the configured levels
+func (this *levels) reset() {
+	this.Lock()
+	defer this.Unlock()
+	this.level = this.config.level
+	this.names = make(map[string]Level, len(this.config.names))
+	for name, level := range this.config.names {
+		this.names[name] = level
+	}
+}
+
+// increase makes all loggers more verbose, down to LOG_DEBUG2
+func (this *levels) increase() {
+	this.Lock()
+	defer this.Unlock()
+	this.level = moreVerbose(this.level)
+	for name, level := range this.names {
+		this.names[name] = moreVerbose(level)
+	}
+}
+
+// set the default level or the level for names
+func (this *levels) set(level Level, names []string) {
+	this.Lock()
+	defer this.Unlock()
+	if len(names) == 0 {
+		this.level = level
+	}
+	for _, name := range names {
+		this.names[resolveName(name)] = level
+	}
+}
+
+// get returns the level for a named logger, which is the level for
+// the name or the closest parent name, or else the default level
+func (this *levels) get(names []string) Level {
+	this.RLock()
+	defer this.RUnlock()
+	for i := len(names); i > 0; i-- {
+		if level, exists := this.names[strings.Join(names[:i], NAME_SEPARATOR)]; exists {
+			return level
+		}
+	}
+	return this.level
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// signalTask makes loggers more verbose on SIGUSR1, and restores the
+// configured levels on SIGUSR2
+func (this *levels) signalTask(signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case s := <-signals:
+			if s == syscall.SIGUSR1 {
+				this.increase()
+			} else {
+				this.reset()
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// resolveName returns the module identifier for a module name or
+// reserved module name, or else returns the name
+func resolveName(name string) string {
+	if module := gopi.ModuleByName(name); module != nil {
+		return module.Identifier()
+	} else {
+		return name
+	}
+}
+
+// moreVerbose returns the next most verbose level
+func moreVerbose(level Level) Level {
+	if level == LOG_ANY || level <= LOG_DEBUG2 {
+		return level
+	} else if level > LOG_FATAL {
+		return LOG_FATAL
+	} else {
+		return level - 1
+	}
+}
@@ -0,0 +1,117 @@
+package logger_test
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestLevel_000(t *testing.T) {
+	// Parse levels
+	if levels, err := logger.ParseLevels("info, sys/timer=debug2,gpio=WARN"); err != nil {
+		t.Fatal(err)
+	} else if len(levels) != 3 || levels[""] != logger.LOG_INFO || levels["sys/timer"] != logger.LOG_DEBUG2 || levels["gpio"] != logger.LOG_WARN {
+		t.Error("Unexpected levels", levels)
+	}
+	if levels, err := logger.ParseLevels(""); err != nil || len(levels) != 0 {
+		t.Error("Unexpected levels", levels, err)
+	}
+	for _, value := range []string{"verbose", "gpio=loud", "=info"} {
+		if _, err := logger.ParseLevels(value); err == nil {
+			t.Error("Expected error for", value)
+		}
+	}
+}
+
+func TestLevel_001(t *testing.T) {
+	// Named loggers have their own levels, and inherit the level of
+	// the parent name
+	buf := new(bytes.Buffer)
+	log := openLogger(t, logger.Config{Level: logger.LOG_WARN, Writer: buf, Levels: map[string]logger.Level{
+		"a":      logger.LOG_DEBUG,
+		"logger": logger.LOG_ERROR,
+	}}).(logger.Driver)
+	defer log.Close()
+
+	a := log.Named("a")
+	ab := a.Named("b").(logger.Driver)
+	sys := log.Named(gopi.ModuleByName("sys/logger").Identifier()).(logger.Driver)
+	if level := ab.Level(); level != logger.LOG_DEBUG {
+		t.Error("Unexpected level", level)
+	} else if level := sys.Level(); level != logger.LOG_ERROR {
+		t.Error("Unexpected level", level)
+	}
+	a.Debug("one")
+	ab.Infow("two", "k", "v")
+	sys.Warn("not written")
+	log.Info("not written")
+	if lines := readLines(buf); len(lines) != 3 {
+		t.Fatal("Unexpected lines", lines)
+	} else if lines[1] != "[DEBUG] a: one" || lines[2] != "[INFO] a/b: two k=v" {
+		t.Error("Unexpected lines", lines)
+	}
+
+	// Change the levels at runtime
+	buf.Reset()
+	log.SetLevel(logger.LOG_INFO, "a/b")
+	log.SetLevel(logger.LOG_DEBUG)
+	ab.Debug("not written")
+	log.Debug("three")
+	if ab.IsDebug() || log.IsDebug() == false {
+		t.Error("Unexpected IsDebug")
+	} else if lines := readLines(buf); len(lines) != 1 || lines[0] != "[DEBUG] three" {
+		t.Error("Unexpected lines", lines)
+	}
+}
+
+func TestLevel_002(t *testing.T) {
+	// Increase verbosity on SIGUSR1 and restore on SIGUSR2
+	log := openLogger(t, logger.Config{Level: logger.LOG_WARN, Writer: new(bytes.Buffer), Signals: true, Levels: map[string]logger.Level{
+		"a": logger.LOG_ERROR,
+	}}).(logger.Driver)
+	defer log.Close()
+
+	a := log.Named("a").(logger.Driver)
+	sendSignal(t, syscall.SIGUSR1)
+	waitLevel(t, log, logger.LOG_INFO)
+	if level := a.Level(); level != logger.LOG_WARN {
+		t.Error("Unexpected level", level)
+	}
+	sendSignal(t, syscall.SIGUSR1)
+	waitLevel(t, log, logger.LOG_DEBUG)
+	sendSignal(t, syscall.SIGUSR2)
+	waitLevel(t, log, logger.LOG_WARN)
+	if level := a.Level(); level != logger.LOG_ERROR {
+		t.Error("Unexpected level", level)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func sendSignal(t *testing.T, s syscall.Signal) {
+	t.Helper()
+	if err := syscall.Kill(os.Getpid(), s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitLevel(t *testing.T, log logger.Driver, level logger.Level) {
+	t.Helper()
+	timeout := time.Now().Add(time.Second)
+	for log.Level() != level {
+		if time.Now().After(timeout) {
+			t.Fatal("Timeout waiting for level", level, "got", log.Level())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -10,10 +10,13 @@
 package logger
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log/syslog"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,16 +38,52 @@ type Config struct {
 	Append bool
 	Syslog string
 	Tag    string
+	Format Format    // Output format for file and stderr logging
+	Writer io.Writer // Output when Path is empty (default: stderr)
+
+	// Rotation of the log file when Path is set
+	MaxSize  int64            // Rotate when the file exceeds a size in bytes, or zero
+	Rotate   time.Duration    // Rotate after an interval, or zero
+	Keep     uint             // Number of rotated files to keep (default: LOG_KEEP_DEFAULT)
+	Compress bool             // Compress rotated files with gzip
+	Now      func() time.Time // Clock for timestamps and rotation (default: time.Now)
+
+	// Levels for named loggers, where an empty name overrides Level
+	Levels  map[string]Level
+	Signals bool // Increase verbosity on SIGUSR1 and restore levels on SIGUSR2
+
+	// Remote logging when Path is a udp://, tcp:// or tls:// URL
+	Facility   string        // Syslog facility (default: user)
+	TLS        *tls.Config   // Configuration for tls:// (default: verify the host)
+	Buffer     int           // Messages buffered while disconnected (default: REMOTE_BUFFER_SIZE)
+	Backoff    time.Duration // Initial retry backoff (default: REMOTE_BACKOFF)
+	MaxBackoff time.Duration // Maximum retry backoff (default: REMOTE_MAX_BACKOFF)
+	Timer      gopi.Timer    // Timer for retries with buffered events (default: open a timer)
+
+	// Number of recent messages kept in memory, or zero
+	Memory uint
 }
 
-// The driver for the logging
+// The driver for the logging, which shares the output with
+// loggers returned by Named and With
 type driver struct {
-	level  Level
-	device *os.File
+	*output
+	names  []string
+	fields []field
+}
+
+// The output and levels for the logging
+type output struct {
+	levels
+	format Format
+	device io.Writer
 	syslog *syslog.Writer
+	remote *remote
+	memory *memory
 	mutex  sync.Mutex
 	delta  time.Time
 	tag    string
+	now    func() time.Time
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -82,9 +121,17 @@ func init() {
 // CONFIG AND NEW
 
 func configLogger(config *gopi.AppConfig) {
-	config.AppFlags.FlagString("log.file", "", "Log to syslog facility (user,daemon,local0...local7) or file (default: log to stderr)")
+	config.AppFlags.FlagString("log.file", "", "Log to syslog facility (user,daemon,local0...local7), remote collector (udp://, tcp:// or tls://host:port) or file (default: log to stderr)")
+	config.AppFlags.FlagUint("log.memory", 0, "Number of recent log messages kept in memory")
+	config.AppFlags.FlagString("log.facility", REMOTE_FACILITY, "Syslog facility when logging to a remote collector")
 	config.AppFlags.FlagString("log.tag", "", "Tag for logging (default: name of application)")
 	config.AppFlags.FlagBool("log.append", false, "When writing log to file, append output to end of file")
+	config.AppFlags.FlagString("log.format", "text", "Log format (text, logfmt, json)")
+	config.AppFlags.FlagUint("log.maxsize", 0, "When writing log to file, rotate when size in bytes is exceeded")
+	config.AppFlags.FlagDuration("log.rotate", 0, "When writing log to file, rotate after an interval")
+	config.AppFlags.FlagUint("log.keep", LOG_KEEP_DEFAULT, "Number of rotated log files to keep")
+	config.AppFlags.FlagBool("log.compress", false, "Compress rotated log files with gzip")
+	config.AppFlags.FlagString("log.level", "", "Log levels for modules, for example sys/timer=debug2,gpio=warn")
 }
 
 func newLogger(app *gopi.AppInstance) (gopi.Driver, error) {
@@ -94,11 +141,36 @@ func newLogger(app *gopi.AppInstance) (gopi.Driver, error) {
 	if exists == false {
 		tag = app.AppFlags.Name()
 	}
+	value, _ := app.AppFlags.GetString("log.format")
+	format, err := ParseFormat(value)
+	if err != nil {
+		return nil, fmt.Errorf("-log.format: %v: %v", value, err)
+	}
+	value, _ = app.AppFlags.GetString("log.level")
+	levels, err := ParseLevels(value)
+	if err != nil {
+		return nil, fmt.Errorf("-log.level: %v", err)
+	}
+	maxsize, _ := app.AppFlags.GetUint("log.maxsize")
+	rotate, _ := app.AppFlags.GetDuration("log.rotate")
+	keep, _ := app.AppFlags.GetUint("log.keep")
+	compress, _ := app.AppFlags.GetBool("log.compress")
+	facility, _ := app.AppFlags.GetString("log.facility")
+	memory, _ := app.AppFlags.GetUint("log.memory")
 	return gopi.Open(Config{
-		Path:   path,
-		Append: append,
-		Level:  getLevelForApp(app),
-		Tag:    tag,
+		Path:     path,
+		Append:   append,
+		Level:    getLevelForApp(app),
+		Tag:      tag,
+		Format:   format,
+		MaxSize:  int64(maxsize),
+		Rotate:   rotate,
+		Keep:     keep,
+		Compress: compress,
+		Levels:   levels,
+		Signals:  true,
+		Facility: facility,
+		Memory:   memory,
 	}, nil)
 }
 
@@ -107,9 +179,16 @@ func newLogger(app *gopi.AppInstance) (gopi.Driver, error) {
 
 // Open a logger
 func (config Config) Open(_ gopi.Logger) (gopi.Driver, error) {
-	this := new(driver)
-	this.level = config.Level
+	this := &driver{output: new(output)}
 	this.tag = config.Tag
+	this.format = config.Format
+	this.now = config.Now
+	if this.now == nil {
+		this.now = time.Now
+	}
+	if this.format > FORMAT_JSON || config.MaxSize < 0 || config.Rotate < 0 {
+		return nil, gopi.ErrBadParameter
+	}
 
 	if facility, err := getSyslogPriority(config.Path); err != nil && err != gopi.ErrBadParameter {
 		// Unknown syslog error
@@ -121,30 +200,56 @@ func (config Config) Open(_ gopi.Logger) (gopi.Driver, error) {
 		} else {
 			this.syslog = syslog
 		}
+	} else if isRemote(config.Path) {
+		// Remote logging
+		if remote, err := newRemote(config); err != nil {
+			return nil, err
+		} else {
+			this.remote = remote
+		}
+	} else if strings.TrimSpace(config.Path) == "" && config.Writer != nil {
+		// Writer logging
+		this.device = config.Writer
 	} else if strings.TrimSpace(config.Path) == "" {
 		// Stderr logging
 		this.device = os.Stderr
+	} else if device, err := newRotator(config, this.now); err != nil {
+		return nil, err
 	} else {
-		flag := os.O_RDWR | os.O_CREATE
-		if config.Append {
-			flag |= os.O_APPEND
-		}
-		if this.device, err = os.OpenFile(config.Path, flag, 0666); err != nil {
-			return nil, err
-		}
+		// File logging
+		this.device = device
+	}
+
+	// Keep recent messages in memory
+	if config.Memory > 0 {
+		this.memory = newMemory(this, config.Memory)
 	}
+
+	// Set levels
+	this.levels.init(config.Level, config.Levels, config.Signals)
+
+	// Success
 	return this, nil
 }
 
 // Close a logger
 func (this *driver) Close() error {
+	this.levels.close()
+	if this.memory != nil {
+		this.memory.Close()
+	}
 	if this.syslog != nil {
 		if err := this.syslog.Close(); err != nil {
 			return err
 		}
 	}
-	if this.device != nil && this.device != os.Stdout && this.device != os.Stderr {
-		if err := this.device.Close(); err != nil {
+	if this.remote != nil {
+		if err := this.remote.Close(); err != nil {
+			return err
+		}
+	}
+	if device, ok := this.device.(*rotator); ok {
+		if err := device.Close(); err != nil {
 			return err
 		}
 	}
@@ -154,63 +259,139 @@ func (this *driver) Close() error {
 ////////////////////////////////////////////////////////////////////////////////
 // LOGGING INTERFACE
 
-// Level gets logging level
+// Level gets logging level for this logger
 func (this *driver) Level() Level {
-	return this.level
+	return this.levels.get(this.names)
 }
 
-// Set logging level
-func (this *driver) SetLevel(level Level) {
-	this.level = level
+// Set default logging level, or the level for named loggers
+func (this *driver) SetLevel(level Level, names ...string) {
+	this.levels.set(level, names)
 }
 
 func (this *driver) Info(format string, v ...interface{}) {
-	if this.level <= LOG_INFO || this.level == LOG_ANY {
-		this.log(LOG_INFO, fmt.Sprintf(format, v...))
+	if this.enabled(LOG_INFO) {
+		this.log(LOG_INFO, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 func (this *driver) Debug(format string, v ...interface{}) {
-	if this.level <= LOG_DEBUG || this.level == LOG_ANY {
-		this.log(LOG_DEBUG, fmt.Sprintf(format, v...))
+	if this.enabled(LOG_DEBUG) {
+		this.log(LOG_DEBUG, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 func (this *driver) Debug2(format string, v ...interface{}) {
-	if this.level <= LOG_DEBUG2 || this.level == LOG_ANY {
-		this.log(LOG_DEBUG2, fmt.Sprintf(format, v...))
+	if this.enabled(LOG_DEBUG2) {
+		this.log(LOG_DEBUG2, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 func (this *driver) Warn(format string, v ...interface{}) {
-	if this.level <= LOG_WARN || this.level == LOG_ANY {
-		this.log(LOG_WARN, fmt.Sprintf(format, v...))
+	if this.enabled(LOG_WARN) {
+		this.log(LOG_WARN, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 func (this *driver) Error(format string, v ...interface{}) error {
 	message := fmt.Sprintf(format, v...)
-	if this.level <= LOG_ERROR || this.level == LOG_ANY {
-		this.log(LOG_ERROR, message)
+	if this.enabled(LOG_ERROR) {
+		this.log(LOG_ERROR, message, nil)
 	}
 	return errors.New(message)
 }
 
 func (this *driver) Fatal(format string, v ...interface{}) error {
 	message := fmt.Sprintf(format, v...)
-	if this.level <= LOG_FATAL || this.level == LOG_ANY {
-		this.log(LOG_FATAL, message)
+	if this.enabled(LOG_FATAL) {
+		this.log(LOG_FATAL, message, nil)
 	}
 	return errors.New(message)
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// STRUCTURED LOGGING INTERFACE
+
+func (this *driver) Infow(msg string, kv ...interface{}) {
+	if this.enabled(LOG_INFO) {
+		this.log(LOG_INFO, msg, kv)
+	}
+}
+
+func (this *driver) Debugw(msg string, kv ...interface{}) {
+	if this.enabled(LOG_DEBUG) {
+		this.log(LOG_DEBUG, msg, kv)
+	}
+}
+
+func (this *driver) Warnw(msg string, kv ...interface{}) {
+	if this.enabled(LOG_WARN) {
+		this.log(LOG_WARN, msg, kv)
+	}
+}
+
+func (this *driver) Errorw(msg string, kv ...interface{}) error {
+	if this.enabled(LOG_ERROR) {
+		this.log(LOG_ERROR, msg, kv)
+	}
+	return errors.New(msg)
+}
+
+// With returns a logger which shares the output and adds
+// key and value pairs to every message
+func (this *driver) With(kv ...interface{}) gopi.StructuredLogger {
+	return &driver{this.output, this.names, joinFields(this.fields, newFields(kv))}
+}
+
+// Named returns a logger which shares the output, and has a level
+// which can be set separately. Messages are tagged with the name
+func (this *driver) Named(name string) gopi.StructuredLogger {
+	names := make([]string, 0, len(this.names)+1)
+	return &driver{this.output, append(append(names, this.names...), name), this.fields}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MEMORY INTERFACE
+
+// Entries returns recent messages which match a query, oldest first,
+// or nil if messages are not kept in memory
+func (this *driver) Entries(query Query) []Event {
+	if this.memory == nil {
+		return nil
+	}
+	return this.memory.Entries(query)
+}
+
+// Subscribe returns a channel on which messages are emitted as
+// Event values, or nil if messages are not kept in memory
+func (this *driver) Subscribe() <-chan gopi.Event {
+	if this.memory == nil {
+		return nil
+	}
+	return this.memory.Subscribe()
+}
+
+// Unsubscribe from messages
+func (this *driver) Unsubscribe(subscriber <-chan gopi.Event) {
+	if this.memory != nil {
+		this.memory.Unsubscribe(subscriber)
+	}
+}
+
 func (this *driver) IsDebug() bool {
-	return (this.level == LOG_DEBUG || this.level == LOG_DEBUG2)
+	level := this.Level()
+	return (level == LOG_DEBUG || level == LOG_DEBUG2)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// enabled returns true if messages at a level are logged
+func (this *driver) enabled(l Level) bool {
+	level := this.Level()
+	return level <= l || level == LOG_ANY
+}
+
 func getLevelForApp(app *gopi.AppInstance) Level {
 	if app.Debug() {
 		if app.Verbose() {
@@ -251,18 +432,32 @@ func getSyslogPriority(value string) (syslog.Priority, error) {
 	}
 }
 
-func (this *driver) log(l Level, message string) {
+func (this *driver) log(l Level, message string, kv []interface{}) {
 	this.mutex.Lock()
 	defer this.mutex.Unlock()
+	entry := &entry{this.now(), l, this.tag, strings.Join(this.names, NAME_SEPARATOR), message, joinFields(this.fields, newFields(kv))}
 	if this.device != nil {
-		now := time.Now()
-		if this.delta.IsZero() || this.delta.Add(time.Second*DELTA_TIMESTAMP_SECS).Before(now) {
-			this.delta = now
-			fmt.Fprintf(this.device, "== %v %v ==\n", this.delta.Format(time.RFC3339), this.tag)
+		switch this.format {
+		case FORMAT_LOGFMT:
+			fmt.Fprintln(this.device, entry.logfmt())
+		case FORMAT_JSON:
+			fmt.Fprintln(this.device, entry.json())
+		default:
+			if this.delta.IsZero() || this.delta.Add(time.Second*DELTA_TIMESTAMP_SECS).Before(entry.ts) {
+				this.delta = entry.ts
+				fmt.Fprintf(this.device, "== %v %v ==\n", this.delta.Format(time.RFC3339), this.tag)
+			}
+			fmt.Fprintln(this.device, entry.text())
 		}
-		fmt.Fprintf(this.device, "[%v] %v\n", l, message)
+	}
+	if this.remote != nil {
+		this.remote.Write(entry)
+	}
+	if this.memory != nil {
+		this.memory.Add(entry)
 	}
 	if this.syslog != nil {
+		message := entry.syslog()
 		switch l {
 		case LOG_DEBUG2, LOG_DEBUG:
 			this.syslog.Debug(message)
@@ -281,6 +476,11 @@ func (this *driver) log(l Level, message string) {
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
+// Name returns the lowercase level name used in logfmt and JSON lines
+func (l Level) Name() string {
+	return strings.ToLower(l.String())
+}
+
 func (l Level) String() string {
 	switch l {
 	case LOG_DEBUG2:
@@ -301,5 +501,9 @@ func (l Level) String() string {
 }
 
 func (this *driver) String() string {
-	return fmt.Sprintf("sys.logger{ level=%v }", this.level)
+	if len(this.names) == 0 {
+		return fmt.Sprintf("sys.logger{ level=%v format=%v }", this.Level(), this.format)
+	} else {
+		return fmt.Sprintf("sys.logger{ name=%v level=%v format=%v }", strconv.Quote(strings.Join(this.names, NAME_SEPARATOR)), this.Level(), this.format)
+	}
 }
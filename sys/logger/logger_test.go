@@ -0,0 +1,125 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestLogger_000(t *testing.T) {
+	// Parse formats
+	for value, format := range map[string]logger.Format{
+		"":       logger.FORMAT_TEXT,
+		"text":   logger.FORMAT_TEXT,
+		"logfmt": logger.FORMAT_LOGFMT,
+		"JSON":   logger.FORMAT_JSON,
+	} {
+		if format_, err := logger.ParseFormat(value); err != nil {
+			t.Error(err)
+		} else if format_ != format {
+			t.Error("Unexpected format", format_, "for", value)
+		}
+	}
+	if _, err := logger.ParseFormat("xml"); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestLogger_001(t *testing.T) {
+	// Text format appends fields to the message
+	buf := new(bytes.Buffer)
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Tag: "test", Writer: buf})
+	defer log.Close()
+
+	log.Infow("hello world", "count", 10, "name", "gopi pi")
+	log.Debugw("not written", "count", 20)
+	log.With("module", "test").Warn("value=%v", 42)
+	lines := readLines(buf)
+	if len(lines) != 3 {
+		t.Fatal("Unexpected lines", lines)
+	} else if strings.HasPrefix(lines[0], "== ") == false || strings.HasSuffix(lines[0], " test ==") == false {
+		t.Error("Unexpected header", lines[0])
+	} else if lines[1] != `[INFO] hello world count=10 name="gopi pi"` {
+		t.Error("Unexpected line", lines[1])
+	} else if lines[2] != `[WARN] value=42 module=test` {
+		t.Error("Unexpected line", lines[2])
+	}
+}
+
+func TestLogger_002(t *testing.T) {
+	// logfmt format
+	buf := new(bytes.Buffer)
+	log := openLogger(t, logger.Config{Level: logger.LOG_DEBUG, Tag: "test", Format: logger.FORMAT_LOGFMT, Writer: buf})
+	defer log.Close()
+
+	child := log.With("module", "sys/test", "empty", "")
+	child.Debugw(`say "hi"`, "err", errors.New("bad thing"), "delay", time.Second, "dangling")
+	child.Info("printf %v", 1)
+	lines := readLines(buf)
+	if len(lines) != 2 {
+		t.Fatal("Unexpected lines", lines)
+	}
+	for i, suffix := range []string{
+		` level=debug tag=test msg="say \"hi\"" module=sys/test empty="" err="bad thing" delay=1s dangling=`,
+		` level=info tag=test msg="printf 1" module=sys/test empty=""`,
+	} {
+		if strings.HasPrefix(lines[i], "ts=") == false || strings.HasSuffix(lines[i], suffix) == false {
+			t.Errorf("Unexpected line %q", lines[i])
+		}
+	}
+}
+
+func TestLogger_003(t *testing.T) {
+	// JSON format
+	buf := new(bytes.Buffer)
+	log := openLogger(t, logger.Config{Level: logger.LOG_WARN, Format: logger.FORMAT_JSON, Writer: buf})
+	defer log.Close()
+
+	if err := log.With("module", "test").Errorw("failed", "code", 500, "ok", false, 3, []int{1, 2}); err == nil || err.Error() != "failed" {
+		t.Error("Unexpected error", err)
+	}
+	log.Infow("not written")
+	lines := readLines(buf)
+	if len(lines) != 1 {
+		t.Fatal("Unexpected lines", lines)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &obj); err != nil {
+		t.Fatal(err, lines[0])
+	} else if _, err := time.Parse(logger.LOG_TIME_FORMAT, obj["ts"].(string)); err != nil {
+		t.Error(err)
+	} else if obj["level"] != "error" || obj["msg"] != "failed" || obj["module"] != "test" {
+		t.Error("Unexpected line", lines[0])
+	} else if obj["code"] != float64(500) || obj["ok"] != false || len(obj["3"].([]interface{})) != 2 {
+		t.Error("Unexpected line", lines[0])
+	} else if _, exists := obj["tag"]; exists {
+		t.Error("Unexpected tag", lines[0])
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openLogger(t *testing.T, config logger.Config) gopi.StructuredLogger {
+	t.Helper()
+	if log, err := gopi.Open(config, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.StructuredLogger)
+	}
+}
+
+func readLines(buf *bytes.Buffer) []string {
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
@@ -0,0 +1,248 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRUCTS
+
+// Event is a logged message, which is returned by Entries and
+// emitted to subscribers when messages are kept in memory
+type Event interface {
+	gopi.Event
+
+	// Timestamp for the message
+	Timestamp() time.Time
+
+	// Level for the message
+	Level() Level
+
+	// Module returns the name of the named logger, or an empty string
+	Module() string
+
+	// Message without key and value pairs
+	Message() string
+
+	// Fields returns alternating key and value pairs
+	Fields() []interface{}
+}
+
+// Query selects messages kept in memory
+type Query struct {
+	Level  Level     // Messages at or above a level, or LOG_ANY
+	Module string    // Messages from a named logger and its children, or empty
+	Since  time.Time // Messages after a time, or zero
+	Limit  uint      // Maximum number of most recent messages, or zero
+}
+
+// memory keeps the most recent messages in a ring buffer, and emits
+// them to subscribers. Messages are emitted in the background, and
+// dropped when subscribers are too slow, so logging never blocks
+type memory struct {
+	source  gopi.Driver
+	entries []*entry
+	next    int
+	count   int
+	emit    chan *entry
+	done    chan struct{}
+	wait    sync.WaitGroup
+	dropped uint
+	mutex   sync.RWMutex
+
+	event.Publisher
+}
+
+// memoryEvent is a message emitted to subscribers
+type memoryEvent struct {
+	*entry
+	source gopi.Driver
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTS
+
+const (
+	// Number of messages queued for subscribers
+	MEMORY_EMIT_QUEUE = 100
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// newMemory returns a ring buffer which keeps size messages, which
+// are emitted from the source driver
+func newMemory(source gopi.Driver, size uint) *memory {
+	this := new(memory)
+	this.source = source
+	this.entries = make([]*entry, size)
+	this.emit = make(chan *entry, MEMORY_EMIT_QUEUE)
+	this.done = make(chan struct{})
+	this.wait.Add(1)
+	go this.emitTask(this.emit, this.done)
+	return this
+}
+
+// Close stops emitting messages and unsubscribes all subscribers
+func (this *memory) Close() {
+	this.mutex.Lock()
+	if this.done == nil {
+		this.mutex.Unlock()
+		return
+	}
+	close(this.done)
+	this.done = nil
+	this.mutex.Unlock()
+
+	// Unsubscribe before waiting, which ends emitting to subscribers
+	// which are not receiving
+	this.Publisher.Close()
+	this.wait.Wait()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Add a message to the ring buffer, and queue it for subscribers
+func (this *memory) Add(e *entry) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.entries[this.next] = e
+	this.next = (this.next + 1) % len(this.entries)
+	if this.count < len(this.entries) {
+		this.count++
+	}
+	if this.done != nil {
+		select {
+		case this.emit <- e:
+		default:
+			this.dropped++
+		}
+	}
+}
+
+// Entries returns messages which match a query, oldest first
+func (this *memory) Entries(query Query) []Event {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	if query.Module != "" {
+		query.Module = resolveName(query.Module)
+	}
+	events := make([]Event, 0, this.count)
+	for i := this.count; i > 0; i-- {
+		e := this.entries[(this.next-i+len(this.entries))%len(this.entries)]
+		if query.matches(e) {
+			events = append(events, &memoryEvent{e, this.source})
+		}
+	}
+	if query.Limit > 0 && uint(len(events)) > query.Limit {
+		events = events[uint(len(events))-query.Limit:]
+	}
+	return events
+}
+
+// Dropped returns the number of messages which were not emitted
+// because subscribers were too slow
+func (this *memory) Dropped() uint {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.dropped
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENT INTERFACE
+
+func (this *memoryEvent) Name() string {
+	return "LogEvent"
+}
+
+func (this *memoryEvent) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *memoryEvent) Timestamp() time.Time {
+	return this.ts
+}
+
+func (this *memoryEvent) Level() Level {
+	return this.level
+}
+
+func (this *memoryEvent) Module() string {
+	return this.name
+}
+
+func (this *memoryEvent) Message() string {
+	return this.msg
+}
+
+func (this *memoryEvent) Fields() []interface{} {
+	kv := make([]interface{}, 0, len(this.fields)*2)
+	for _, f := range this.fields {
+		kv = append(kv, f.key, f.value)
+	}
+	return kv
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *memoryEvent) String() string {
+	return fmt.Sprintf("<sys.logger.event>{ ts=%v %v }", this.ts.Format(LOG_TIME_FORMAT), this.text())
+}
+
+func (this *memory) String() string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return fmt.Sprintf("<sys.logger.memory>{ size=%v count=%v dropped=%v }", len(this.entries), this.count, this.dropped)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// emitTask emits queued messages to subscribers
+func (this *memory) emitTask(emit <-chan *entry, done <-chan struct{}) {
+	defer this.wait.Done()
+	for {
+		select {
+		case e := <-emit:
+			this.Emit(&memoryEvent{e, this.source})
+		case <-done:
+			return
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// matches returns true if a message matches the query
+func (this Query) matches(e *entry) bool {
+	if this.Level != LOG_ANY && e.level < this.Level {
+		return false
+	} else if this.Since.IsZero() == false && e.ts.After(this.Since) == false {
+		return false
+	} else if this.Module == "" || e.name == this.Module || strings.HasPrefix(e.name, this.Module+NAME_SEPARATOR) {
+		return true
+	} else {
+		return false
+	}
+}
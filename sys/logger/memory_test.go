@@ -0,0 +1,170 @@
+package logger_test
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestMemory_000(t *testing.T) {
+	// Keep the last three messages alongside stderr output
+	buf := new(bytes.Buffer)
+	clock := newClock()
+	log := openLogger(t, logger.Config{Level: logger.LOG_DEBUG, Writer: buf, Memory: 3, Now: clock.Now}).(logger.Driver)
+	defer log.Close()
+
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+		log.Debug("message %v", i)
+	}
+	if lines := readLines(buf); len(lines) != 6 {
+		t.Error("Unexpected lines", lines)
+	}
+	if entries := log.Entries(logger.Query{}); len(entries) != 3 {
+		t.Fatal("Unexpected entries", entries)
+	} else {
+		for i, entry := range entries {
+			if entry.Message() != "message "+strconv.Itoa(2+i) {
+				t.Error("Unexpected message", entry)
+			} else if entry.Level() != logger.LOG_DEBUG {
+				t.Error("Unexpected level", entry)
+			} else if entry.Timestamp().Equal(newClock().Now().Add(time.Duration(3+i)*time.Second)) == false {
+				t.Error("Unexpected timestamp", entry)
+			} else if entry.Source() != log {
+				t.Error("Unexpected source", entry)
+			}
+		}
+	}
+}
+
+func TestMemory_001(t *testing.T) {
+	// Query by level, module, time and limit
+	clock := newClock()
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Writer: new(bytes.Buffer), Memory: 10, Now: clock.Now}).(logger.Driver)
+	defer log.Close()
+
+	since := clock.Now()
+	log.Info("one")
+	clock.Advance(time.Second)
+	log.Named("a").Warnw("two", "k", "v")
+	log.Named("a").Named("b").Error("three")
+	log.Named("ab").Info("four")
+	timer := log.Named(gopi.ModuleByName("timer").Identifier())
+	timer.Info("five")
+
+	for _, test := range []struct {
+		query    logger.Query
+		messages string
+	}{
+		{logger.Query{}, "one,two,three,four,five"},
+		{logger.Query{Level: logger.LOG_WARN}, "two,three"},
+		{logger.Query{Module: "a"}, "two,three"},
+		{logger.Query{Module: "a/b"}, "three"},
+		{logger.Query{Module: "timer"}, "five"},
+		{logger.Query{Since: since}, "two,three,four,five"},
+		{logger.Query{Limit: 2}, "four,five"},
+		{logger.Query{Module: "a", Limit: 1}, "three"},
+	} {
+		if messages := joinMessages(log.Entries(test.query)); messages != test.messages {
+			t.Errorf("%+v: Unexpected messages %v", test.query, messages)
+		}
+	}
+
+	entries := log.Entries(logger.Query{Module: "a", Limit: 1})
+	if kv := log.Entries(logger.Query{Module: "a/b"})[0].Module(); kv != "a/b" {
+		t.Error("Unexpected module", kv)
+	} else if kv := log.Entries(logger.Query{Level: logger.LOG_WARN})[0].Fields(); len(kv) != 2 || kv[0] != "k" || kv[1] != "v" {
+		t.Error("Unexpected fields", kv)
+	} else if len(entries) != 1 || entries[0].Name() != "LogEvent" {
+		t.Error("Unexpected entries", entries)
+	}
+}
+
+func TestMemory_002(t *testing.T) {
+	// Subscribe to messages
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Writer: new(bytes.Buffer), Memory: 10}).(logger.Driver)
+	events := log.Subscribe()
+	if events == nil {
+		t.Fatal("Expected subscription")
+	}
+
+	log.Named("a").Infow("hello", "k", 1)
+	select {
+	case evt := <-events:
+		if evt_, ok := evt.(logger.Event); ok == false {
+			t.Error("Unexpected event", evt)
+		} else if evt_.Message() != "hello" || evt_.Module() != "a" || evt_.Level() != logger.LOG_INFO {
+			t.Error("Unexpected event", evt_)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+
+	// Closing the logger closes the subscription
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected closed channel")
+	}
+}
+
+func TestMemory_003(t *testing.T) {
+	// Messages are not kept without Memory
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Writer: new(bytes.Buffer)}).(logger.Driver)
+	defer log.Close()
+	log.Info("hello")
+	if entries := log.Entries(logger.Query{}); entries != nil {
+		t.Error("Unexpected entries", entries)
+	} else if events := log.Subscribe(); events != nil {
+		t.Error("Unexpected subscription")
+	}
+}
+
+func TestMemory_004(t *testing.T) {
+	// Closing the logger does not wait for a subscriber which never receives
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Writer: new(bytes.Buffer), Memory: 10}).(logger.Driver)
+	events := log.Subscribe()
+	for i := 0; i < 3; i++ {
+		log.Info("message %v", i)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		done <- log.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for close")
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected closed channel")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func joinMessages(entries []logger.Event) string {
+	messages := ""
+	for i, entry := range entries {
+		if i > 0 {
+			messages += ","
+		}
+		messages += entry.Message()
+	}
+	return messages
+}
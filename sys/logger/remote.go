@@ -0,0 +1,439 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package logger
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/timer"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRUCTS
+
+// remote sends messages to a syslog collector with RFC5424 framing
+// over UDP, TCP or TLS. Messages are buffered while the collector is
+// unreachable, and connection retries use a timer backoff
+type remote struct {
+	network    string
+	addr       string
+	tls        *tls.Config
+	facility   int
+	hostname   string
+	app        string
+	size       int
+	backoff    time.Duration
+	maxbackoff time.Duration
+	timer      gopi.Timer
+	closetimer bool
+	events     <-chan gopi.Event
+	retry      gopi.TimerHandle
+	conn       net.Conn
+	buffer     [][]byte
+	dropped    uint
+	send       chan struct{}
+	done       chan struct{}
+	wait       sync.WaitGroup
+
+	sync.Mutex
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTS
+
+const (
+	REMOTE_SCHEME_UDP = "udp"
+	REMOTE_SCHEME_TCP = "tcp"
+	REMOTE_SCHEME_TLS = "tls"
+)
+
+const (
+	REMOTE_BUFFER_SIZE = 1024                   // Default number of buffered messages
+	REMOTE_BACKOFF     = 500 * time.Millisecond // Default initial retry backoff
+	REMOTE_MAX_BACKOFF = time.Minute            // Default maximum retry backoff
+	REMOTE_TIMEOUT     = 5 * time.Second        // Timeout for connect and write
+	REMOTE_FACILITY    = "user"                 // Default facility
+	REMOTE_TIME_FORMAT = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// isRemote returns true if the path is a udp://, tcp:// or tls:// URL
+func isRemote(path string) bool {
+	for _, scheme := range []string{REMOTE_SCHEME_UDP, REMOTE_SCHEME_TCP, REMOTE_SCHEME_TLS} {
+		if strings.HasPrefix(path, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// newRemote returns a remote sink for a udp://, tcp:// or tls:// URL
+func newRemote(config Config) (*remote, error) {
+	this := new(remote)
+
+	// Parse the URL
+	if u, err := url.Parse(config.Path); err != nil {
+		return nil, err
+	} else if u.Host == "" || u.Port() == "" {
+		return nil, fmt.Errorf("%v: %v", config.Path, gopi.ErrBadParameter)
+	} else if u.Scheme == REMOTE_SCHEME_TLS {
+		this.network, this.addr = "tcp", u.Host
+		this.tls = config.TLS
+		if this.tls == nil {
+			this.tls = &tls.Config{ServerName: u.Hostname()}
+		}
+	} else {
+		this.network, this.addr = u.Scheme, u.Host
+	}
+
+	// Set the facility, hostname and application name
+	facility := config.Facility
+	if facility == "" {
+		facility = REMOTE_FACILITY
+	}
+	if priority, err := getSyslogPriority(facility); err != nil {
+		return nil, fmt.Errorf("%v: %v", facility, err)
+	} else {
+		this.facility = int(priority)
+	}
+	if hostname, err := os.Hostname(); err != nil || hostname == "" {
+		this.hostname = "-"
+	} else {
+		this.hostname = sdHeader(hostname, 255)
+	}
+	this.app = sdHeader(config.Tag, 48)
+
+	// Set buffer and backoff
+	this.size = config.Buffer
+	if this.size <= 0 {
+		this.size = REMOTE_BUFFER_SIZE
+	}
+	this.backoff, this.maxbackoff = config.Backoff, config.MaxBackoff
+	if this.backoff <= 0 {
+		this.backoff = REMOTE_BACKOFF
+	}
+	if this.maxbackoff <= this.backoff {
+		this.maxbackoff = REMOTE_MAX_BACKOFF
+	}
+	if this.maxbackoff <= this.backoff {
+		this.maxbackoff = this.backoff * 2
+	}
+
+	// Use a timer for the backoff which buffers events, or open one which
+	// logs warnings to stderr
+	if _, ok := config.Timer.(gopi.PublisherWithOptions); ok {
+		this.timer = config.Timer
+	} else {
+		if timer, err := gopi.Open(timer.Timer{}, stderrLogger(LOG_WARN)); err != nil {
+			return nil, err
+		} else {
+			this.timer = timer.(gopi.Timer)
+			this.closetimer = true
+		}
+	}
+
+	// Send messages in the background. Timer events are buffered, so the
+	// timer does not block when the sender is not receiving
+	this.buffer = make([][]byte, 0, this.size)
+	if events, err := this.timer.(gopi.PublisherWithOptions).SubscribeWithOptions(gopi.SubscribeOptions{
+		Buffer:   1,
+		Overflow: gopi.SUBSCRIBE_OVERFLOW_DROP_NEWEST,
+	}); err != nil {
+		if this.closetimer {
+			this.timer.Close()
+		}
+		return nil, err
+	} else {
+		this.events = events
+	}
+	this.send = make(chan struct{}, 1)
+	this.done = make(chan struct{})
+	this.wait.Add(1)
+	go this.sendTask(this.done)
+
+	// Success
+	return this, nil
+}
+
+// Close sends buffered messages if connected, and closes the connection
+func (this *remote) Close() error {
+	this.Lock()
+	if this.done == nil {
+		this.Unlock()
+		return nil
+	}
+	close(this.done)
+	this.done = nil
+	this.Unlock()
+
+	// Wait for the sender to end, then cancel retrying and close the timer
+	this.wait.Wait()
+	this.stopBackoff()
+	this.timer.Unsubscribe(this.events)
+	if this.closetimer {
+		if err := this.timer.Close(); err != nil {
+			return err
+		}
+	}
+	if this.conn != nil {
+		err := this.conn.Close()
+		this.conn = nil
+		return err
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write a message to the buffer, dropping the oldest message if
+// the buffer is full
+func (this *remote) Write(e *entry) {
+	message := this.format(e)
+
+	this.Lock()
+	defer this.Unlock()
+	if this.done == nil {
+		return
+	}
+	if len(this.buffer) >= this.size {
+		this.buffer = this.buffer[1:]
+		this.dropped++
+	}
+	this.buffer = append(this.buffer, message)
+
+	// Signal the sender
+	select {
+	case this.send <- struct{}{}:
+	default:
+	}
+}
+
+// Dropped returns the number of messages dropped because the
+// buffer was full
+func (this *remote) Dropped() uint {
+	this.Lock()
+	defer this.Unlock()
+	return this.dropped
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *remote) String() string {
+	this.Lock()
+	defer this.Unlock()
+	return fmt.Sprintf("<sys.logger.remote>{ network=%v addr=%v tls=%v buffered=%v dropped=%v }", this.network, strconv.Quote(this.addr), this.tls != nil, len(this.buffer), this.dropped)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+// sendTask connects and sends buffered messages. When the collector is
+// unreachable, messages are buffered and the connection is retried on
+// backoff timer events until it succeeds
+func (this *remote) sendTask(done <-chan struct{}) {
+	defer this.wait.Done()
+
+	// Connect immediately
+	if this.flush() == false {
+		this.startBackoff()
+	}
+FOR_LOOP:
+	for {
+		select {
+		case <-this.send:
+			if this.retrying() == false && this.flush() == false {
+				this.startBackoff()
+			}
+		case evt := <-this.events:
+			// The timer may be shared, so only retry on backoff events
+			if evt_, ok := evt.(gopi.TimerEvent); ok && evt_.UserInfo() == this && this.retrying() {
+				if this.flush() {
+					this.stopBackoff()
+				}
+			}
+		case <-done:
+			break FOR_LOOP
+		}
+	}
+
+	// Send any buffered messages if connected
+	if this.conn != nil {
+		this.flush()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// flush connects if required and sends the buffered messages. Returns
+// false if the collector is unreachable
+func (this *remote) flush() bool {
+	if this.conn == nil {
+		if conn, err := this.dial(); err != nil {
+			return false
+		} else {
+			this.conn = conn
+		}
+	}
+	for {
+		message := this.next()
+		if message == nil {
+			return true
+		}
+		this.conn.SetWriteDeadline(time.Now().Add(REMOTE_TIMEOUT))
+		if _, err := this.conn.Write(this.frame(message)); err != nil {
+			this.conn.Close()
+			this.conn = nil
+			this.restore(message)
+			return false
+		}
+	}
+}
+
+// next removes the oldest message from the buffer, or returns nil
+// if the buffer is empty
+func (this *remote) next() []byte {
+	this.Lock()
+	defer this.Unlock()
+	if len(this.buffer) == 0 {
+		return nil
+	}
+	message := this.buffer[0]
+	this.buffer = this.buffer[1:]
+	return message
+}
+
+// restore returns a message which could not be sent to the front of
+// the buffer, or drops it if the buffer is full
+func (this *remote) restore(message []byte) {
+	this.Lock()
+	defer this.Unlock()
+	if len(this.buffer) >= this.size {
+		this.dropped++
+	} else {
+		this.buffer = append([][]byte{message}, this.buffer...)
+	}
+}
+
+// startBackoff starts the backoff timer for retrying the connection
+func (this *remote) startBackoff() {
+	if handle, err := this.timer.NewBackoff(this.backoff, this.maxbackoff, this); err != nil {
+		fmt.Fprintf(os.Stderr, "sys.logger: %v: %v\n", this.addr, err)
+	} else {
+		this.Lock()
+		this.retry = handle
+		this.Unlock()
+	}
+}
+
+// stopBackoff cancels the backoff timer after the collector is reachable
+// or when the sink is closed
+func (this *remote) stopBackoff() {
+	this.Lock()
+	handle := this.retry
+	this.retry = nil
+	this.Unlock()
+	if handle != nil {
+		handle.Cancel()
+	}
+}
+
+// retrying returns true if the backoff timer has been started
+func (this *remote) retrying() bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.retry != nil
+}
+
+// dial connects to the collector
+func (this *remote) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: REMOTE_TIMEOUT}
+	if this.tls != nil {
+		return tls.DialWithDialer(dialer, this.network, this.addr, this.tls)
+	} else {
+		return dialer.Dial(this.network, this.addr)
+	}
+}
+
+// frame returns a message with octet counting for stream connections
+func (this *remote) frame(message []byte) []byte {
+	if this.network == REMOTE_SCHEME_UDP {
+		return message
+	} else {
+		return append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+}
+
+// format returns an RFC5424 message
+func (this *remote) format(e *entry) []byte {
+	sd := e.sd()
+	if sd == "" {
+		sd = "-"
+	}
+	return []byte(fmt.Sprintf("<%d>1 %v %v %v %v - %v %v", this.facility|severity(e.level), e.ts.Format(REMOTE_TIME_FORMAT), this.hostname, this.app, os.Getpid(), sd, e.msg))
+}
+
+// severity returns the syslog severity for a level
+func severity(level Level) int {
+	switch level {
+	case LOG_DEBUG2, LOG_DEBUG:
+		return 7
+	case LOG_INFO:
+		return 6
+	case LOG_WARN:
+		return 4
+	case LOG_ERROR:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// sdHeader returns a header field of printable characters, or "-"
+// if the value is empty
+func sdHeader(value string, n int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		} else {
+			return r
+		}
+	}, value)
+	if value == "" {
+		return "-"
+	} else if len(value) > n {
+		return value[:n]
+	} else {
+		return value
+	}
+}
+
+// stderrLogger returns a logger which writes to stderr
+func stderrLogger(level Level) gopi.Logger {
+	this := &driver{output: new(output)}
+	this.device = os.Stderr
+	this.now = time.Now
+	this.levels.init(level, nil, false)
+	return this
+}
@@ -0,0 +1,201 @@
+package logger_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+	timer "github.com/djthorpe/gopi/sys/timer"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestRemote_000(t *testing.T) {
+	// Log to a UDP collector, one message per datagram
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Path: "udp://" + conn.LocalAddr().String(), Tag: "test", Facility: "local0"})
+	defer log.Close()
+
+	log.Named("sys/timer").Warnw("hello world", "a", 1)
+	log.Error("goodbye")
+
+	// PRI is facility (local0=16) * 8 + severity (warning=4, error=3)
+	for i, pattern := range []string{
+		`^<132>1 \S+ \S+ test \d+ - \[fields@32473 name="sys/timer" a="1"\] hello world$`,
+		`^<131>1 \S+ \S+ test \d+ - - goodbye$`,
+	} {
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if n, _, err := conn.ReadFrom(buf); err != nil {
+			t.Fatal(i, err)
+		} else if regexp.MustCompile(pattern).Match(buf[:n]) == false {
+			t.Errorf("%v: Unexpected message %q", i, string(buf[:n]))
+		}
+	}
+}
+
+func TestRemote_001(t *testing.T) {
+	// Buffer messages while a TCP collector is unreachable, then send
+	// them with octet counting when it starts
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Path: "tcp://" + addr, Tag: "test", Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+	defer log.Close()
+	for i := 0; i < 3; i++ {
+		log.Info("message %v", i)
+	}
+
+	// Start the collector
+	time.Sleep(50 * time.Millisecond)
+	if listener, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	conn, err := accept(listener, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Read the messages
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 3; i++ {
+		if message, err := readFrame(reader); err != nil {
+			t.Fatal(i, err)
+		} else if strings.HasPrefix(message, "<14>1 ") == false || strings.HasSuffix(message, " test "+strconv.Itoa(os.Getpid())+" - - message "+strconv.Itoa(i)) == false {
+			t.Errorf("%v: Unexpected message %q", i, message)
+		}
+	}
+
+	// Messages are sent when connected
+	log.Info("connected")
+	if message, err := readFrame(reader); err != nil {
+		t.Fatal(err)
+	} else if strings.HasSuffix(message, " - - connected") == false {
+		t.Errorf("Unexpected message %q", message)
+	}
+}
+
+func TestRemote_002(t *testing.T) {
+	// Drop the oldest messages when the buffer is full
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Path: "tcp://" + addr, Buffer: 2, Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	defer log.Close()
+	for i := 0; i < 5; i++ {
+		log.Info("message %v", i)
+	}
+
+	if listener, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	conn, err := accept(listener, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	reader := bufio.NewReader(conn)
+	for _, i := range []int{3, 4} {
+		if message, err := readFrame(reader); err != nil {
+			t.Fatal(i, err)
+		} else if strings.HasSuffix(message, " message "+strconv.Itoa(i)) == false {
+			t.Errorf("%v: Unexpected message %q", i, message)
+		}
+	}
+}
+
+func TestRemote_003(t *testing.T) {
+	// Invalid URL and facility
+	if _, err := openRemote("tcp://localhost", ""); err == nil {
+		t.Error("Expected error for missing port")
+	}
+	if _, err := openRemote("udp://localhost:514", "nothing"); err == nil {
+		t.Error("Expected error for invalid facility")
+	}
+}
+
+func TestRemote_004(t *testing.T) {
+	// Cancel retrying when closed, with a shared timer
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	timer_, err := gopi.Open(timer.Timer{}, openLogger(t, logger.Config{Level: logger.LOG_WARN}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer timer_.Close()
+
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Path: "tcp://" + addr, Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, Timer: timer_.(gopi.Timer)})
+	log.Info("message")
+	time.Sleep(50 * time.Millisecond)
+	if strings.Contains(fmt.Sprint(timer_), "timers=1") == false {
+		t.Error("Expected backoff timer", timer_)
+	}
+	if err := log.Close(); err != nil {
+		t.Error(err)
+	} else if strings.Contains(fmt.Sprint(timer_), "timers=0") == false {
+		t.Error("Expected backoff timer to be cancelled", timer_)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openRemote(path, facility string) (interface{}, error) {
+	return logger.Config{Path: path, Facility: facility}.Open(nil)
+}
+
+// accept returns a connection or an error after a timeout
+func accept(listener net.Listener, timeout time.Duration) (net.Conn, error) {
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
+	return listener.Accept()
+}
+
+// readFrame returns a message with octet counting
+func readFrame(reader *bufio.Reader) (string, error) {
+	if length, err := reader.ReadString(' '); err != nil {
+		return "", err
+	} else if n, err := strconv.Atoi(strings.TrimSuffix(length, " ")); err != nil {
+		return "", err
+	} else {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+}
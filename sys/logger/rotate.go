@@ -0,0 +1,278 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRUCTS
+
+// rotator writes to a log file, which is rotated when it exceeds a size
+// or an interval has passed since it was opened. Rotated files are named
+// path.1, path.2 and so forth, with the most recent file first
+type rotator struct {
+	path     string
+	maxsize  int64
+	interval time.Duration
+	keep     uint
+	compress bool
+	now      func() time.Time
+	file     *os.File
+	size     int64
+	opened   time.Time
+	signal   chan os.Signal
+	done     chan struct{}
+
+	sync.Mutex
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTS
+
+const (
+	// Default number of rotated files to keep
+	LOG_KEEP_DEFAULT = 5
+
+	// File extension for compressed files
+	LOG_GZIP_EXT = ".gz"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// newRotator opens a log file, and reopens it on SIGHUP
+func newRotator(config Config, now func() time.Time) (*rotator, error) {
+	this := new(rotator)
+	this.path = config.Path
+	this.maxsize = config.MaxSize
+	this.interval = config.Rotate
+	this.keep = config.Keep
+	if this.keep == 0 {
+		this.keep = LOG_KEEP_DEFAULT
+	}
+	this.compress = config.Compress
+	this.now = now
+
+	// Open the file, truncating it unless appending
+	flag := os.O_WRONLY | os.O_CREATE
+	if config.Append {
+		flag |= os.O_APPEND
+	} else {
+		flag |= os.O_TRUNC
+	}
+	if err := this.open(flag); err != nil {
+		return nil, err
+	}
+
+	// Reopen the file on SIGHUP, for logrotate
+	this.signal = make(chan os.Signal, 1)
+	this.done = make(chan struct{})
+	signal.Notify(this.signal, syscall.SIGHUP)
+	go this.signalTask(this.signal, this.done)
+
+	// Success
+	return this, nil
+}
+
+// Close the log file
+func (this *rotator) Close() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.done != nil {
+		signal.Stop(this.signal)
+		close(this.done)
+		this.done = nil
+	}
+	if this.file != nil {
+		err := this.file.Close()
+		this.file = nil
+		return err
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write data to the log file, rotating the file first if required
+func (this *rotator) Write(data []byte) (int, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.file == nil {
+		return 0, os.ErrClosed
+	}
+	if this.rotateDue(int64(len(data))) {
+		if err := this.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := this.file.Write(data)
+	this.size += int64(n)
+	return n, err
+}
+
+// Rotate the log file
+func (this *rotator) Rotate() error {
+	this.Lock()
+	defer this.Unlock()
+	if this.file == nil {
+		return os.ErrClosed
+	}
+	return this.rotate()
+}
+
+// Reopen the log file, which may have been moved
+func (this *rotator) Reopen() error {
+	this.Lock()
+	defer this.Unlock()
+	if this.file == nil {
+		return os.ErrClosed
+	}
+	if err := this.file.Close(); err != nil {
+		return err
+	}
+	return this.open(os.O_WRONLY | os.O_CREATE | os.O_APPEND)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *rotator) String() string {
+	return fmt.Sprintf("<sys.logger.rotator>{ path=%v maxsize=%v rotate=%v keep=%v compress=%v }", strconv.Quote(this.path), this.maxsize, this.interval, this.keep, this.compress)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+func (this *rotator) signalTask(signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case <-signals:
+			if err := this.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "sys.logger: %v: %v\n", this.path, err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// open the log file and set the size and opened time
+func (this *rotator) open(flag int) error {
+	if file, err := os.OpenFile(this.path, flag, 0666); err != nil {
+		return err
+	} else if stat, err := file.Stat(); err != nil {
+		file.Close()
+		return err
+	} else {
+		this.file = file
+		this.size = stat.Size()
+		this.opened = this.now()
+	}
+	return nil
+}
+
+// rotateDue returns true if writing a number of bytes exceeds the
+// maximum size, or the interval has passed since the file was opened
+func (this *rotator) rotateDue(n int64) bool {
+	if this.maxsize > 0 && this.size > 0 && this.size+n > this.maxsize {
+		return true
+	} else if this.interval > 0 && this.now().Sub(this.opened) >= this.interval {
+		return true
+	} else {
+		return false
+	}
+}
+
+// rotate renames the log file and older rotated files, removes the
+// oldest file, and opens a new log file. If the files can't be renamed,
+// the existing log file is reopened
+func (this *rotator) rotate() error {
+	if err := this.file.Close(); err != nil {
+		return err
+	}
+	this.file = nil
+	if err := this.shift(); err != nil {
+		if err_ := this.open(os.O_WRONLY | os.O_CREATE | os.O_APPEND); err_ != nil {
+			return err_
+		}
+		return err
+	}
+	return this.open(os.O_WRONLY | os.O_CREATE | os.O_TRUNC)
+}
+
+// shift removes the oldest file and renames the others. The most
+// recent rotated file is compressed when compression is enabled
+func (this *rotator) shift() error {
+	for _, ext := range []string{"", LOG_GZIP_EXT} {
+		if err := os.Remove(this.rotated(this.keep) + ext); err != nil && os.IsNotExist(err) == false {
+			return err
+		}
+	}
+	for i := this.keep - 1; i > 0; i-- {
+		for _, ext := range []string{"", LOG_GZIP_EXT} {
+			if err := os.Rename(this.rotated(i)+ext, this.rotated(i+1)+ext); err != nil && os.IsNotExist(err) == false {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(this.path, this.rotated(1)); err != nil {
+		return err
+	} else if this.compress {
+		return compressFile(this.rotated(1))
+	} else {
+		return nil
+	}
+}
+
+// rotated returns the path for a rotated file
+func (this *rotator) rotated(i uint) string {
+	return this.path + "." + strconv.FormatUint(uint64(i), 10)
+}
+
+// compressFile compresses a file with gzip and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+LOG_GZIP_EXT, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		return err
+	} else if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	} else if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,193 @@
+package logger_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	// Frameworks
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestRotate_000(t *testing.T) {
+	// Rotate by size and keep two files
+	path := tempDir(t)
+	defer os.RemoveAll(path)
+
+	clock := newClock()
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Format: logger.FORMAT_LOGFMT, Path: filepath.Join(path, "test.log"), MaxSize: 120, Keep: 2, Now: clock.Now})
+	for i := 0; i < 4; i++ {
+		// Each line is 55 bytes, so rotates every two lines
+		log.Infow("message", "i", i)
+		log.Infow("message", "i", i)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if files := listFiles(t, path); strings.Join(files, ",") != "test.log,test.log.1,test.log.2" {
+		t.Error("Unexpected files", files)
+	} else if data := readFile(t, filepath.Join(path, "test.log")); strings.Count(data, "i=3\n") != 2 {
+		t.Errorf("Unexpected contents %q", data)
+	} else if data := readFile(t, filepath.Join(path, "test.log.1")); strings.Count(data, "i=2\n") != 2 {
+		t.Errorf("Unexpected contents %q", data)
+	} else if data := readFile(t, filepath.Join(path, "test.log.2")); strings.Count(data, "i=1\n") != 2 {
+		t.Errorf("Unexpected contents %q", data)
+	}
+}
+
+func TestRotate_001(t *testing.T) {
+	// Rotate by time and compress
+	path := tempDir(t)
+	defer os.RemoveAll(path)
+
+	clock := newClock()
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Format: logger.FORMAT_LOGFMT, Path: filepath.Join(path, "test.log"), Rotate: time.Hour, Compress: true, Now: clock.Now})
+	defer log.Close()
+
+	log.Info("first")
+	clock.Advance(59 * time.Minute)
+	log.Info("second")
+	if files := listFiles(t, path); len(files) != 1 {
+		t.Error("Unexpected files", files)
+	}
+	clock.Advance(time.Minute)
+	log.Info("third")
+	if files := listFiles(t, path); strings.Join(files, ",") != "test.log,test.log.1.gz" {
+		t.Error("Unexpected files", files)
+	} else if data := readGzip(t, filepath.Join(path, "test.log.1.gz")); strings.Contains(data, "msg=first") == false || strings.Contains(data, "msg=second") == false {
+		t.Errorf("Unexpected contents %q", data)
+	} else if data := readFile(t, filepath.Join(path, "test.log")); strings.Contains(data, "msg=third") == false || strings.Contains(data, "first") {
+		t.Errorf("Unexpected contents %q", data)
+	}
+	clock.Advance(time.Hour)
+	log.Info("fourth")
+	if files := listFiles(t, path); strings.Join(files, ",") != "test.log,test.log.1.gz,test.log.2.gz" {
+		t.Error("Unexpected files", files)
+	}
+}
+
+func TestRotate_002(t *testing.T) {
+	// Append to an existing file, and reopen on SIGHUP after the
+	// file has been moved
+	path := tempDir(t)
+	defer os.RemoveAll(path)
+
+	file := filepath.Join(path, "test.log")
+	if err := ioutil.WriteFile(file, []byte("existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	log := openLogger(t, logger.Config{Level: logger.LOG_INFO, Format: logger.FORMAT_LOGFMT, Path: file, Append: true})
+	defer log.Close()
+
+	log.Info("first")
+	if err := os.Rename(file, file+".old"); err != nil {
+		t.Fatal(err)
+	} else if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the file to be reopened
+	timeout := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(file); err == nil {
+			break
+		} else if time.Now().After(timeout) {
+			t.Fatal("Timeout waiting for reopen")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	log.Info("second")
+	if data := readFile(t, file+".old"); strings.HasPrefix(data, "existing\n") == false || strings.Contains(data, "msg=first") == false {
+		t.Errorf("Unexpected contents %q", data)
+	} else if data := readFile(t, file); strings.Contains(data, "msg=second") == false || strings.Contains(data, "first") {
+		t.Errorf("Unexpected contents %q", data)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE CLOCK
+
+type clock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (this *clock) Now() time.Time {
+	this.Lock()
+	defer this.Unlock()
+	return this.now
+}
+
+func (this *clock) Advance(d time.Duration) {
+	this.Lock()
+	defer this.Unlock()
+	this.now = this.now.Add(d)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	if path, err := ioutil.TempDir("", "logger"); err != nil {
+		t.Fatal(err)
+		return ""
+	} else {
+		return path
+	}
+}
+
+func listFiles(t *testing.T, path string) []string {
+	t.Helper()
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	if data, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+		return ""
+	} else {
+		return string(data)
+	}
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	zr, err := gzip.NewReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadAll(zr); err != nil {
+		t.Fatal(err)
+		return ""
+	} else {
+		return string(data)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Prefix for all metric names
+	PROMETHEUS_PREFIX = "gopi_"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write the uptimes, load averages and all metrics in text exposition
+// format. Each metric is exposed as a gauge with the last value, and
+// metrics with statistics also have gauges for the minimum, maximum,
+// mean and number of samples over the metric rate period
+func Write(w io.Writer, metrics gopi.Metrics) error {
+	buf := bufio.NewWriter(w)
+
+	// Uptimes and load averages
+	writeFamily(buf, "uptime_host_seconds", "Time since the host was started")
+	writeSample(buf, "uptime_host_seconds", "", metrics.UptimeHost().Seconds())
+	writeFamily(buf, "uptime_app_seconds", "Time since the application was started")
+	writeSample(buf, "uptime_app_seconds", "", metrics.UptimeApp().Seconds())
+	l1, l5, l15 := metrics.LoadAverage()
+	writeFamily(buf, "load_average", "Load average")
+	writeSample(buf, "load_average", `period="1m"`, l1)
+	writeSample(buf, "load_average", `period="5m"`, l5)
+	writeSample(buf, "load_average", `period="15m"`, l15)
+
+	// Metrics ordered by name. When a family name is already used after
+	// invalid characters are replaced, including the statistics families,
+	// later metrics have a numeric suffix
+	all := metrics.Metrics(gopi.METRIC_TYPE_NONE)
+	names := make(map[string]gopi.Metric, len(all))
+	used := map[string]bool{"uptime_host_seconds": true, "uptime_app_seconds": true, "load_average": true}
+	for _, metric := range all {
+		if name := MetricName(metric); name == "" {
+			continue
+		} else {
+			unique := name
+			for i := 2; isUsed(used, families(unique, metric)); i++ {
+				unique = name + "_" + strconv.Itoa(i)
+			}
+			for _, family := range families(unique, metric) {
+				used[family] = true
+			}
+			names[unique] = metric
+		}
+	}
+	keys := make([]string, 0, len(names))
+	for name := range names {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		metric := names[name]
+		help := metric.Name()
+		if unit := metric.Unit(); unit != "" {
+			help += " (" + unit + ")"
+		}
+		writeFamily(buf, name, help)
+		writeSample(buf, name, "", metric.FloatValue())
+
+		// Write statistics for metrics which keep values
+		if stats, ok := metric.(gopi.MetricWithStats); ok {
+			labels := fmt.Sprintf("rate=%v", strconv.Quote(rateLabel(metric.Rate())))
+			writeFamily(buf, name+"_min", "Minimum of "+help)
+			writeSample(buf, name+"_min", labels, stats.Min())
+			writeFamily(buf, name+"_max", "Maximum of "+help)
+			writeSample(buf, name+"_max", labels, stats.Max())
+			writeFamily(buf, name+"_mean", "Mean of "+help)
+			writeSample(buf, name+"_mean", labels, stats.Mean())
+			writeFamily(buf, name+"_samples", "Number of samples of "+help)
+			writeSample(buf, name+"_samples", labels, float64(stats.Count()))
+		}
+	}
+
+	return buf.Flush()
+}
+
+// MetricName returns the exposition name for a metric without the
+// prefix, which is the name with invalid characters replaced and a
+// suffix for the unit, or an empty string if the name is empty
+func MetricName(metric gopi.Metric) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		} else {
+			return '_'
+		}
+	}, metric.Name())
+	if strings.Trim(name, "_") == "" {
+		return ""
+	}
+	if suffix := UnitSuffix(metric.Type()); suffix != "" && strings.HasSuffix(name, "_"+suffix) == false {
+		name += "_" + suffix
+	}
+	return name
+}
+
+// UnitSuffix returns the base unit used in exposition names for
+// a metric type, or an empty string for a pure number
+func UnitSuffix(t gopi.MetricType) string {
+	switch t {
+	case gopi.METRIC_TYPE_CELCIUS:
+		return "celsius"
+	case gopi.METRIC_TYPE_HUMIDITY, gopi.METRIC_TYPE_PERCENT:
+		return "percent"
+	case gopi.METRIC_TYPE_PRESSURE:
+		return "pascals"
+	case gopi.METRIC_TYPE_VOLTAGE:
+		return "volts"
+	case gopi.METRIC_TYPE_CURRENT:
+		return "amperes"
+	case gopi.METRIC_TYPE_LUX:
+		return "lux"
+	case gopi.METRIC_TYPE_RPM:
+		return "rpm"
+	case gopi.METRIC_TYPE_BYTES:
+		return "bytes"
+	default:
+		return ""
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// families returns the family names written for a metric
+func families(name string, metric gopi.Metric) []string {
+	if _, ok := metric.(gopi.MetricWithStats); ok {
+		return []string{name, name + "_min", name + "_max", name + "_mean", name + "_samples"}
+	} else {
+		return []string{name}
+	}
+}
+
+// isUsed returns true if any family name is already used
+func isUsed(used map[string]bool, families []string) bool {
+	for _, family := range families {
+		if used[family] {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFamily(w io.Writer, name, help string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %v%v %v\n", PROMETHEUS_PREFIX, name, help)
+	fmt.Fprintf(w, "# TYPE %v%v gauge\n", PROMETHEUS_PREFIX, name)
+}
+
+func writeSample(w io.Writer, name, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%v%v%v %v\n", PROMETHEUS_PREFIX, name, labels, formatValue(value))
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func rateLabel(rate gopi.MetricRate) string {
+	switch rate {
+	case gopi.METRIC_RATE_MINUTE:
+		return "minute"
+	case gopi.METRIC_RATE_HOUR:
+		return "hour"
+	case gopi.METRIC_RATE_DAY:
+		return "day"
+	default:
+		return "none"
+	}
+}
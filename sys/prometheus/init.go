@@ -0,0 +1,40 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package prometheus
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register exporter, which is returned by app.ModuleInstance("metrics/prometheus")
+	gopi.RegisterModule(gopi.Module{
+		Name:     "metrics/prometheus",
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Requires: []string{"hw"},
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagString("metrics.addr", "", "Address for Prometheus metrics, or empty to disable")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			addr, _ := app.AppFlags.GetString("metrics.addr")
+			if metrics, ok := app.Hardware.(gopi.Metrics); ok == false {
+				return nil, gopi.ErrNotImplemented
+			} else {
+				return gopi.Open(Prometheus{
+					Metrics: metrics,
+					Addr:    addr,
+				}, app.Logger)
+			}
+		},
+	})
+}
@@ -0,0 +1,172 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Prometheus is the configuration for exposing metrics in the Prometheus
+// text exposition format. When Addr is empty, no listener is started
+// but the driver can still be used as an http.Handler
+type Prometheus struct {
+	Metrics gopi.Metrics // Metrics driver
+	Addr    string       // Address for the HTTP listener, or empty
+}
+
+// Driver exposes metrics over HTTP
+type Driver interface {
+	gopi.Driver
+	http.Handler
+
+	// Return the address of the listener, or nil if there is no listener
+	Addr() net.Addr
+}
+
+type prometheus struct {
+	log      gopi.Logger
+	metrics  gopi.Metrics
+	listener net.Listener
+	server   *http.Server
+
+	sync.Mutex
+	event.Tasks
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PROMETHEUS_PATH         = "/metrics"
+	PROMETHEUS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the exporter, and listen for connections if an address is set
+func (config Prometheus) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.prometheus.Open{ addr=%v }", strconv.Quote(config.Addr))
+
+	if config.Metrics == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(prometheus)
+	this.log = log
+	this.metrics = config.Metrics
+
+	if config.Addr != "" {
+		if listener, err := net.Listen("tcp", config.Addr); err != nil {
+			return nil, err
+		} else {
+			mux := http.NewServeMux()
+			mux.Handle(PROMETHEUS_PATH, this)
+			this.listener = listener
+			this.server = &http.Server{Handler: mux}
+			this.Tasks.Start(this.serveTask)
+		}
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the exporter and the listener
+func (this *prometheus) Close() error {
+	this.log.Debug("sys.prometheus.Close{ }")
+
+	this.Lock()
+	defer this.Unlock()
+
+	// Close the listener, then stop serving even if that fails
+	var result error
+	if this.server != nil {
+		result = this.server.Close()
+		this.server = nil
+	}
+	if err := this.Tasks.Close(); err != nil && result == nil {
+		result = err
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - DRIVER
+
+// Addr returns the address of the listener, or nil
+func (this *prometheus) Addr() net.Addr {
+	if this.listener == nil {
+		return nil
+	} else {
+		return this.listener.Addr()
+	}
+}
+
+// ServeHTTP writes the metrics in text exposition format
+func (this *prometheus) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	this.log.Debug2("sys.prometheus.ServeHTTP{ remote=%v }", req.RemoteAddr)
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", PROMETHEUS_CONTENT_TYPE)
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		if err := Write(w, this.metrics); err != nil {
+			this.log.Warn("sys.prometheus: %v", err)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *prometheus) String() string {
+	if addr := this.Addr(); addr != nil {
+		return fmt.Sprintf("<sys.prometheus>{ addr=%v metrics=%v }", strconv.Quote(addr.String()), this.metrics)
+	} else {
+		return fmt.Sprintf("<sys.prometheus>{ metrics=%v }", this.metrics)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+func (this *prometheus) serveTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+
+	errs := make(chan error, 1)
+	go func(server *http.Server) {
+		errs <- server.Serve(this.listener)
+	}(this.server)
+
+	// Wait for the server to be closed
+	<-stop
+	if err := <-errs; err != http.ErrServerClosed {
+		return err
+	}
+
+	// Success
+	return nil
+}
@@ -0,0 +1,209 @@
+package prometheus_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/prometheus"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestPrometheus_000(t *testing.T) {
+	// Metric names have a unit suffix
+	if name := prometheus.MetricName(&metric{name: "cpu temperature", t: gopi.METRIC_TYPE_CELCIUS}); name != "cpu_temperature_celsius" {
+		t.Errorf("Unexpected name %q", name)
+	} else if name := prometheus.MetricName(&metric{name: "temp_celsius", t: gopi.METRIC_TYPE_CELCIUS}); name != "temp_celsius" {
+		t.Errorf("Unexpected name %q", name)
+	} else if name := prometheus.MetricName(&metric{name: "memory.used", t: gopi.METRIC_TYPE_PURE}); name != "memory_used" {
+		t.Errorf("Unexpected name %q", name)
+	} else if name := prometheus.MetricName(&metric{name: "--", t: gopi.METRIC_TYPE_PURE}); name != "" {
+		t.Errorf("Unexpected name %q", name)
+	}
+}
+
+func TestPrometheus_001(t *testing.T) {
+	// Exposition through an httptest server
+	driver := openPrometheus(t, prometheus.Prometheus{Metrics: newMetrics()})
+	defer driver.Close()
+
+	server := httptest.NewServer(driver)
+	defer server.Close()
+
+	body := get(t, server.URL)
+	for _, line := range []string{
+		"# TYPE gopi_uptime_host_seconds gauge",
+		"gopi_uptime_host_seconds 3600",
+		"gopi_uptime_app_seconds 90",
+		`gopi_load_average{period="1m"} 0.5`,
+		`gopi_load_average{period="15m"} 0.25`,
+		"# HELP gopi_cpu_temperature_celsius cpu_temperature (°C)",
+		"# TYPE gopi_cpu_temperature_celsius gauge",
+		"gopi_cpu_temperature_celsius 48.5",
+		`gopi_cpu_temperature_celsius_min{rate="hour"} 40`,
+		`gopi_cpu_temperature_celsius_max{rate="hour"} 50`,
+		`gopi_cpu_temperature_celsius_mean{rate="hour"} 45`,
+		`gopi_cpu_temperature_celsius_samples{rate="hour"} 10`,
+		"gopi_count 7",
+		`gopi_count_samples{rate="minute"} 1`,
+		"gopi_fan_rpm 1200",
+		"# HELP gopi_cpu_temperature_celsius_2 cpu temperature (°C)",
+		"gopi_cpu_temperature_celsius_2 49",
+		`gopi_count_min{rate="minute"} 7`,
+		"# HELP gopi_count_min_2 count_min",
+		"gopi_count_min_2 3",
+	} {
+		if strings.Contains(body, line+"\n") == false {
+			t.Errorf("Missing %q in:\n%v", line, body)
+		}
+	}
+	if strings.Contains(body, "gopi_fan_rpm_min") {
+		t.Error("Unexpected statistics for metric without stats")
+	}
+	if count := strings.Count(body, "# TYPE gopi_count_min gauge\n"); count != 1 {
+		t.Error("Expected one gopi_count_min family, got", count)
+	}
+	if strings.Index(body, "gopi_count ") > strings.Index(body, "gopi_cpu_temperature_celsius ") {
+		t.Error("Expected metrics ordered by name")
+	}
+
+	// Only GET and HEAD are allowed
+	if resp, err := http.Post(server.URL, "text/plain", nil); err != nil {
+		t.Error(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Error("Unexpected status", resp.Status)
+	}
+}
+
+func TestPrometheus_002(t *testing.T) {
+	// Listener serves metrics on the metrics path
+	driver := openPrometheus(t, prometheus.Prometheus{Metrics: newMetrics(), Addr: "127.0.0.1:0"})
+	addr := driver.Addr()
+	if addr == nil {
+		t.Fatal("Expected listener address")
+	}
+	if body := get(t, "http://"+addr.String()+prometheus.PROMETHEUS_PATH); strings.Contains(body, "gopi_count 7\n") == false {
+		t.Error("Unexpected body", body)
+	}
+	if err := driver.Close(); err != nil {
+		t.Error(err)
+	} else if _, err := http.Get("http://" + addr.String() + prometheus.PROMETHEUS_PATH); err == nil {
+		t.Error("Expected error after close")
+	}
+
+	// Metrics are required
+	if _, err := gopi.Open(prometheus.Prometheus{}, openLogger(t)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE METRICS
+
+type metrics struct {
+	metrics []gopi.Metric
+}
+
+// plainMetric is a metric without statistics
+type plainMetric struct {
+	gopi.Metric
+}
+
+type metric struct {
+	name                 string
+	t                    gopi.MetricType
+	rate                 gopi.MetricRate
+	last, min, max, mean float64
+	count                uint
+}
+
+func newMetrics() *metrics {
+	return &metrics{[]gopi.Metric{
+		&metric{name: "cpu_temperature", t: gopi.METRIC_TYPE_CELCIUS, rate: gopi.METRIC_RATE_HOUR, last: 48.5, min: 40, max: 50, mean: 45, count: 10},
+		&metric{name: "count", t: gopi.METRIC_TYPE_PURE, rate: gopi.METRIC_RATE_MINUTE, last: 7, min: 7, max: 7, mean: 7, count: 1},
+		&metric{name: "cpu temperature", t: gopi.METRIC_TYPE_CELCIUS, rate: gopi.METRIC_RATE_HOUR, last: 49, min: 49, max: 49, mean: 49, count: 1},
+		plainMetric{&metric{name: "fan", t: gopi.METRIC_TYPE_RPM, rate: gopi.METRIC_RATE_MINUTE, last: 1200}},
+		plainMetric{&metric{name: "count_min", t: gopi.METRIC_TYPE_PURE, rate: gopi.METRIC_RATE_MINUTE, last: 3}},
+	}}
+}
+
+func (this *metrics) Close() error                             { return nil }
+func (this *metrics) UptimeHost() time.Duration                { return time.Hour }
+func (this *metrics) UptimeApp() time.Duration                 { return 90 * time.Second }
+func (this *metrics) LoadAverage() (float64, float64, float64) { return 0.5, 0.75, 0.25 }
+func (this *metrics) NewMetricUint(gopi.MetricType, gopi.MetricRate, string) (chan<- uint, error) {
+	return nil, gopi.ErrNotImplemented
+}
+func (this *metrics) NewMetricFloat64(gopi.MetricType, gopi.MetricRate, string) (chan<- float64, error) {
+	return nil, gopi.ErrNotImplemented
+}
+func (this *metrics) Metrics(gopi.MetricType) []gopi.Metric { return this.metrics }
+
+func (this *metric) Rate() gopi.MetricRate { return this.rate }
+func (this *metric) Type() gopi.MetricType { return this.t }
+func (this *metric) Name() string          { return this.name }
+func (this *metric) Unit() string {
+	if this.t == gopi.METRIC_TYPE_CELCIUS {
+		return "°C"
+	} else {
+		return ""
+	}
+}
+func (this *metric) UintValue() uint     { return uint(this.last) }
+func (this *metric) FloatValue() float64 { return this.last }
+func (this *metric) Min() float64        { return this.min }
+func (this *metric) Max() float64        { return this.max }
+func (this *metric) Mean() float64       { return this.mean }
+func (this *metric) Count() uint         { return this.count }
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.Logger)
+	}
+}
+
+func openPrometheus(t *testing.T, config prometheus.Prometheus) prometheus.Driver {
+	t.Helper()
+	if driver, err := gopi.Open(config, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(prometheus.Driver)
+	}
+}
+
+func get(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Unexpected status", resp.Status)
+	} else if resp.Header.Get("Content-Type") != prometheus.PROMETHEUS_CONTENT_TYPE {
+		t.Error("Unexpected content type", resp.Header.Get("Content-Type"))
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
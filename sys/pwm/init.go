@@ -0,0 +1,47 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package pwm
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register PWM
+	gopi.RegisterModule(gopi.Module{
+		Name: "sys/pwm",
+		Type: gopi.MODULE_TYPE_PWM,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("pwm.chip", 0, "PWM chip number")
+			config.AppFlags.FlagString("pwm.pins", "", "PWM pin to channel mapping (eg, 18:0,19:1)")
+			config.AppFlags.FlagDuration("pwm.period", PWM_DEFAULT_PERIOD, "PWM default period")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			chip, _ := app.AppFlags.GetUint("pwm.chip")
+			value, _ := app.AppFlags.GetString("pwm.pins")
+			period, _ := app.AppFlags.GetDuration("pwm.period")
+			if pins, err := ParsePins(value); err != nil {
+				return nil, err
+			} else {
+				return gopi.Open(PWM{
+					Chip:   chip,
+					Pins:   pins,
+					Period: period,
+				}, app.Logger)
+			}
+		},
+	})
+}
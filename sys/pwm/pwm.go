@@ -0,0 +1,417 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package pwm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PWM is the configuration for the sysfs PWM driver. Each channel of
+// the chip is mapped to a GPIO pin, and channels are exported when
+// the driver is opened
+type PWM struct {
+	Chip      uint                  // Chip number
+	SysfsPath string                // Path to sysfs (default: /sys/class/pwm)
+	Pins      map[gopi.GPIOPin]uint // Map of pins to channels, or nil for the default mapping
+	Period    time.Duration         // Period for channels which have none set
+}
+
+type pwm struct {
+	log      gopi.Logger
+	chip     uint
+	path     string
+	npwm     uint
+	channels map[gopi.GPIOPin]uint
+	exported []uint
+
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PWM_SYSFS_PATH      = "/sys/class/pwm"
+	PWM_DEFAULT_PERIOD  = time.Millisecond
+	PWM_EXPORT_TIMEOUT  = time.Second
+	PWM_CHIP_NAME       = "pwmchip%v"
+	PWM_CHANNEL_NAME    = "pwm%v"
+	PWM_PIN_SEPARATOR   = ","
+	PWM_PIN_CHANNEL_SEP = ":"
+)
+
+var (
+	// PWM_DEFAULT_PINS maps the Raspberry Pi PWM0 and PWM1 channels
+	// to GPIO18 and GPIO19
+	PWM_DEFAULT_PINS = map[gopi.GPIOPin]uint{
+		18: 0,
+		19: 1,
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the PWM driver
+func (config PWM) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.pwm.Open{ chip=%v pins=%v }", config.Chip, config.Pins)
+
+	this := new(pwm)
+	this.log = log
+	this.chip = config.Chip
+	this.channels = make(map[gopi.GPIOPin]uint)
+
+	root := config.SysfsPath
+	if root == "" {
+		root = PWM_SYSFS_PATH
+	}
+	this.path = filepath.Join(root, fmt.Sprintf(PWM_CHIP_NAME, config.Chip))
+	if npwm, err := readUint(filepath.Join(this.path, "npwm")); err != nil {
+		return nil, err
+	} else {
+		this.npwm = npwm
+	}
+
+	// Map pins to channels. The default mapping ignores channels which
+	// the chip doesn't have
+	pins := config.Pins
+	if pins == nil {
+		pins = make(map[gopi.GPIOPin]uint)
+		for pin, channel := range PWM_DEFAULT_PINS {
+			if channel < this.npwm {
+				pins[pin] = channel
+			}
+		}
+	}
+	for pin, channel := range pins {
+		if channel >= this.npwm || pin == gopi.GPIO_PIN_NONE {
+			return nil, gopi.ErrBadParameter
+		}
+		this.channels[pin] = channel
+	}
+
+	// Export channels and set the period where none is set
+	period := config.Period
+	if period == 0 {
+		period = PWM_DEFAULT_PERIOD
+	} else if period < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	for _, channel := range this.channels {
+		if err := this.export(channel); err != nil {
+			this.unexport()
+			return nil, err
+		} else if value, err := readUint(this.channelPath(channel, "period")); err != nil {
+			this.unexport()
+			return nil, err
+		} else if value == 0 {
+			if err := writeUint(this.channelPath(channel, "period"), uint(period.Nanoseconds())); err != nil {
+				this.unexport()
+				return nil, err
+			}
+		}
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the PWM driver
+func (this *pwm) Close() error {
+	this.log.Debug("sys.pwm.Close{ chip=%v }", this.chip)
+
+	this.Lock()
+	defer this.Unlock()
+
+	err := this.unexport()
+	this.channels = nil
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - PINS
+
+// Pins returns the pins which are mapped to PWM channels, in order
+func (this *pwm) Pins() []gopi.GPIOPin {
+	this.Lock()
+	defer this.Unlock()
+
+	pins := make([]gopi.GPIOPin, 0, len(this.channels))
+	for pin := range this.channels {
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i] < pins[j] })
+	return pins
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - PERIOD
+
+// Period returns the period for a pin
+func (this *pwm) Period(pin gopi.GPIOPin) (time.Duration, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, err := this.channel(pin); err != nil {
+		return 0, err
+	} else if period, err := readUint(this.channelPath(channel, "period")); err != nil {
+		return 0, err
+	} else {
+		return time.Duration(period), nil
+	}
+}
+
+// SetPeriod sets the period for one or more pins, or all pins if none
+// are given. The duty cycle is kept in proportion to the period
+func (this *pwm) SetPeriod(period time.Duration, pins ...gopi.GPIOPin) error {
+	this.log.Debug2("sys.pwm.SetPeriod{ period=%v pins=%v }", period, pins)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if period <= 0 {
+		return gopi.ErrBadParameter
+	}
+	channels, err := this.channelsForPins(pins)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if old, err := readUint(this.channelPath(channel, "period")); err != nil {
+			return err
+		} else if duty, err := readUint(this.channelPath(channel, "duty_cycle")); err != nil {
+			return err
+		} else {
+			// The duty cycle can never exceed the period, so the order
+			// of writes depends on whether the period is shrinking
+			period_ := uint(period.Nanoseconds())
+			duty_ := uint(0)
+			if old != 0 {
+				duty_ = uint(math.Round(float64(duty) * float64(period_) / float64(old)))
+			}
+			if duty_ > period_ {
+				duty_ = period_
+			}
+			if period_ < old {
+				if err := writeUint(this.channelPath(channel, "duty_cycle"), duty_); err != nil {
+					return err
+				} else if err := writeUint(this.channelPath(channel, "period"), period_); err != nil {
+					return err
+				}
+			} else if err := writeUint(this.channelPath(channel, "period"), period_); err != nil {
+				return err
+			} else if err := writeUint(this.channelPath(channel, "duty_cycle"), duty_); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - DUTY CYCLE
+
+// DutyCycle returns the duty cycle for a pin between 0.0 and 1.0,
+// which is always 0.0 when the channel is disabled
+func (this *pwm) DutyCycle(pin gopi.GPIOPin) (float32, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, err := this.channel(pin); err != nil {
+		return 0, err
+	} else if enable, err := readUint(this.channelPath(channel, "enable")); err != nil {
+		return 0, err
+	} else if enable == 0 {
+		return 0, nil
+	} else if period, err := readUint(this.channelPath(channel, "period")); err != nil {
+		return 0, err
+	} else if period == 0 {
+		return 0, nil
+	} else if duty, err := readUint(this.channelPath(channel, "duty_cycle")); err != nil {
+		return 0, err
+	} else {
+		return float32(duty) / float32(period), nil
+	}
+}
+
+// SetDutyCycle sets the duty cycle between 0.0 and 1.0 for one or more
+// pins, or all pins if none are given, and enables the channels
+func (this *pwm) SetDutyCycle(value float32, pins ...gopi.GPIOPin) error {
+	this.log.Debug2("sys.pwm.SetDutyCycle{ value=%v pins=%v }", value, pins)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value < 0 || value > 1 || value != value {
+		return gopi.ErrBadParameter
+	}
+	channels, err := this.channelsForPins(pins)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if period, err := readUint(this.channelPath(channel, "period")); err != nil {
+			return err
+		} else if period == 0 {
+			return gopi.ErrOutOfOrder
+		} else if err := writeUint(this.channelPath(channel, "duty_cycle"), uint(math.Round(float64(value)*float64(period)))); err != nil {
+			return err
+		} else if err := writeUint(this.channelPath(channel, "enable"), 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *pwm) String() string {
+	pins := make([]string, 0, len(this.channels))
+	for pin, channel := range this.channels {
+		pins = append(pins, fmt.Sprintf("%v=pwm%v", pin, channel))
+	}
+	sort.Strings(pins)
+	return fmt.Sprintf("<sys.pwm>{ chip=%v npwm=%v pins=[%v] }", this.chip, this.npwm, strings.Join(pins, " "))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ParsePins parses a mapping of pins to channels in the form
+// "18:0,19:1", returning nil for an empty string
+func ParsePins(value string) (map[gopi.GPIOPin]uint, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	pins := make(map[gopi.GPIOPin]uint)
+	for _, field := range strings.Split(value, PWM_PIN_SEPARATOR) {
+		if parts := strings.SplitN(strings.TrimSpace(field), PWM_PIN_CHANNEL_SEP, 2); len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid pin mapping: %v", strconv.Quote(field))
+		} else if pin, err := strconv.ParseUint(parts[0], 10, 8); err != nil {
+			return nil, fmt.Errorf("Invalid pin: %v", strconv.Quote(parts[0]))
+		} else if channel, err := strconv.ParseUint(parts[1], 10, 32); err != nil {
+			return nil, fmt.Errorf("Invalid channel: %v", strconv.Quote(parts[1]))
+		} else {
+			pins[gopi.GPIOPin(pin)] = uint(channel)
+		}
+	}
+	return pins, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *pwm) channel(pin gopi.GPIOPin) (uint, error) {
+	if this.channels == nil {
+		return 0, gopi.ErrOutOfOrder
+	} else if channel, exists := this.channels[pin]; exists == false {
+		return 0, gopi.ErrBadParameter
+	} else {
+		return channel, nil
+	}
+}
+
+func (this *pwm) channelsForPins(pins []gopi.GPIOPin) ([]uint, error) {
+	if this.channels == nil {
+		return nil, gopi.ErrOutOfOrder
+	}
+	channels := make([]uint, 0, len(pins))
+	if len(pins) == 0 {
+		for _, channel := range this.channels {
+			channels = append(channels, channel)
+		}
+	}
+	for _, pin := range pins {
+		if channel, err := this.channel(pin); err != nil {
+			return nil, err
+		} else {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+func (this *pwm) channelPath(channel uint, name string) string {
+	return filepath.Join(this.path, fmt.Sprintf(PWM_CHANNEL_NAME, channel), name)
+}
+
+func (this *pwm) export(channel uint) error {
+	path := filepath.Join(this.path, fmt.Sprintf(PWM_CHANNEL_NAME, channel))
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if err := writeUint(filepath.Join(this.path, "export"), channel); err != nil {
+		return err
+	}
+
+	// Wait for the channel to appear
+	timeout := time.Now().Add(PWM_EXPORT_TIMEOUT)
+	for time.Now().Before(timeout) {
+		if _, err := os.Stat(filepath.Join(path, "period")); err == nil {
+			this.exported = append(this.exported, channel)
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return gopi.ErrDeadlineExceeded
+}
+
+// unexport channels which were exported by this driver, which
+// also disables them
+func (this *pwm) unexport() error {
+	var err error
+	for _, channel := range this.exported {
+		if err_ := writeUint(filepath.Join(this.path, "unexport"), channel); err_ != nil && err == nil {
+			err = err_
+		}
+	}
+	this.exported = nil
+	return err
+}
+
+func readUint(path string) (uint, error) {
+	if value, err := ioutil.ReadFile(path); err != nil {
+		return 0, err
+	} else if value_, err := strconv.ParseUint(strings.TrimSpace(string(value)), 10, 64); err != nil {
+		return 0, err
+	} else {
+		return uint(value_), nil
+	}
+}
+
+func writeUint(path string, value uint) error {
+	if fh, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0); err != nil {
+		return err
+	} else if _, err := fh.WriteString(fmt.Sprint(value)); err != nil {
+		fh.Close()
+		return err
+	} else {
+		return fh.Close()
+	}
+}
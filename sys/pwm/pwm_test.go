@@ -0,0 +1,234 @@
+//go:build linux
+// +build linux
+
+package pwm_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/pwm"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN DRIVER
+
+func TestPWM_000(t *testing.T) {
+	root := fakeChip(t, 0, 2, 0, 1)
+	defer os.RemoveAll(root)
+
+	// Default mapping is GPIO18 and GPIO19, with the default period
+	driver := openPWM(t, pwm.PWM{SysfsPath: root})
+	defer driver.Close()
+
+	if pins := driver.Pins(); fmt.Sprint(pins) != fmt.Sprint([]gopi.GPIOPin{18, 19}) {
+		t.Error("Unexpected pins", pins)
+	}
+	if period, err := driver.Period(18); err != nil {
+		t.Error(err)
+	} else if period != pwm.PWM_DEFAULT_PERIOD {
+		t.Error("Unexpected period", period)
+	}
+	if _, err := driver.Period(17); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	t.Log(driver)
+}
+
+func TestPWM_001(t *testing.T) {
+	root := fakeChip(t, 1, 1)
+	defer os.RemoveAll(root)
+
+	// Channel is exported on open and unexported on close
+	exported := make(chan struct{})
+	go func() {
+		defer close(exported)
+		timeout := time.Now().Add(time.Second)
+		for time.Now().Before(timeout) {
+			if value, _ := ioutil.ReadFile(filepath.Join(root, "pwmchip1", "export")); string(value) == "0" {
+				writeFile(t, root, "pwmchip1/pwm0/duty_cycle", "0")
+				writeFile(t, root, "pwmchip1/pwm0/enable", "0")
+				writeFile(t, root, "pwmchip1/pwm0/period", "0")
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	driver := openPWM(t, pwm.PWM{Chip: 1, SysfsPath: root, Pins: map[gopi.GPIOPin]uint{12: 0}, Period: 40 * time.Microsecond})
+	<-exported
+
+	if period := readFile(t, root, "pwmchip1/pwm0/period"); period != "40000" {
+		t.Error("Unexpected period", period)
+	}
+	if err := driver.Close(); err != nil {
+		t.Error(err)
+	} else if channel := readFile(t, root, "pwmchip1/unexport"); channel != "0" {
+		t.Error("Unexpected unexport", channel)
+	}
+}
+
+func TestPWM_002(t *testing.T) {
+	root := fakeChip(t, 0, 1, 0)
+	defer os.RemoveAll(root)
+
+	// Channels which don't exist can't be mapped
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+	} else if _, err := gopi.Open(pwm.PWM{SysfsPath: root, Pins: map[gopi.GPIOPin]uint{18: 1}}, log.(gopi.Logger)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PERIOD AND DUTY CYCLE
+
+func TestPWM_003(t *testing.T) {
+	root := fakeChip(t, 0, 2, 0, 1)
+	defer os.RemoveAll(root)
+
+	driver := openPWM(t, pwm.PWM{SysfsPath: root})
+	defer driver.Close()
+
+	if value, err := driver.DutyCycle(18); err != nil {
+		t.Error(err)
+	} else if value != 0 {
+		t.Error("Expected 0, got", value)
+	}
+	if err := driver.SetDutyCycle(0.25, 18); err != nil {
+		t.Error(err)
+	} else if duty := readFile(t, root, "pwmchip0/pwm0/duty_cycle"); duty != "250000" {
+		t.Error("Unexpected duty_cycle", duty)
+	} else if enable := readFile(t, root, "pwmchip0/pwm0/enable"); enable != "1" {
+		t.Error("Unexpected enable", enable)
+	} else if value, err := driver.DutyCycle(18); err != nil {
+		t.Error(err)
+	} else if value != 0.25 {
+		t.Error("Expected 0.25, got", value)
+	}
+	if enable := readFile(t, root, "pwmchip0/pwm1/enable"); enable != "0" {
+		t.Error("Unexpected enable", enable)
+	}
+
+	// Duty cycle is validated
+	for _, value := range []float32{-0.1, 1.1} {
+		if err := driver.SetDutyCycle(value); err != gopi.ErrBadParameter {
+			t.Error("Expected ErrBadParameter, got", err)
+		}
+	}
+	if err := driver.SetDutyCycle(1, 20); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// No pins sets all pins
+	if err := driver.SetDutyCycle(1); err != nil {
+		t.Error(err)
+	} else if duty := readFile(t, root, "pwmchip0/pwm1/duty_cycle"); duty != "1000000" {
+		t.Error("Unexpected duty_cycle", duty)
+	}
+}
+
+func TestPWM_004(t *testing.T) {
+	root := fakeChip(t, 0, 1, 0)
+	defer os.RemoveAll(root)
+
+	driver := openPWM(t, pwm.PWM{SysfsPath: root})
+	defer driver.Close()
+
+	// Duty cycle is kept in proportion to the period
+	driver.SetDutyCycle(0.5, 18)
+	if err := driver.SetPeriod(100*time.Microsecond, 18); err != nil {
+		t.Error(err)
+	} else if period, err := driver.Period(18); err != nil {
+		t.Error(err)
+	} else if period != 100*time.Microsecond {
+		t.Error("Unexpected period", period)
+	} else if duty := readFile(t, root, "pwmchip0/pwm0/duty_cycle"); duty != "50000" {
+		t.Error("Unexpected duty_cycle", duty)
+	}
+	if err := driver.SetPeriod(0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestPWM_005(t *testing.T) {
+	if pins, err := pwm.ParsePins("18:0, 13:1"); err != nil {
+		t.Error(err)
+	} else if len(pins) != 2 || pins[18] != 0 || pins[13] != 1 {
+		t.Error("Unexpected pins", pins)
+	}
+	if pins, err := pwm.ParsePins(""); err != nil || pins != nil {
+		t.Error("Unexpected pins", pins, err)
+	}
+	for _, value := range []string{"18", "x:0", "18:y"} {
+		if _, err := pwm.ParsePins(value); err == nil {
+			t.Error("Expected error for", value)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// fakeChip creates a chip with npwm channels, of which some are
+// already exported
+func fakeChip(t *testing.T, chip, npwm uint, exported ...uint) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "pwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("pwmchip%v", chip)
+	writeFile(t, root, filepath.Join(path, "npwm"), fmt.Sprint(npwm))
+	writeFile(t, root, filepath.Join(path, "export"), "")
+	writeFile(t, root, filepath.Join(path, "unexport"), "")
+	for _, channel := range exported {
+		dir := filepath.Join(path, fmt.Sprintf("pwm%v", channel))
+		writeFile(t, root, filepath.Join(dir, "period"), "0")
+		writeFile(t, root, filepath.Join(dir, "duty_cycle"), "0")
+		writeFile(t, root, filepath.Join(dir, "enable"), "0")
+	}
+	return root
+}
+
+func openPWM(t *testing.T, config pwm.PWM) gopi.PWM {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(config, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.PWM)
+	}
+}
+
+func readFile(t *testing.T, root, path string) string {
+	t.Helper()
+	if value, err := ioutil.ReadFile(filepath.Join(root, path)); err != nil {
+		t.Fatal(err)
+		return ""
+	} else {
+		return strings.TrimSpace(string(value))
+	}
+}
+
+func writeFile(t *testing.T, root, path, value string) {
+	t.Helper()
+	path = filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
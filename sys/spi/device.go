@@ -0,0 +1,202 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Device is the spidev ioctl layer, which can be replaced in order
+// to test without /dev/spidevB.C
+type Device interface {
+	// Close the device
+	Close() error
+
+	// Get and set mode (SPI_IOC_RD_MODE and SPI_IOC_WR_MODE)
+	Mode() (gopi.SPIMode, error)
+	SetMode(gopi.SPIMode) error
+
+	// Get and set speed (SPI_IOC_RD_MAX_SPEED_HZ and SPI_IOC_WR_MAX_SPEED_HZ)
+	MaxSpeedHz() (uint32, error)
+	SetMaxSpeedHz(uint32) error
+
+	// Get and set bits per word (SPI_IOC_RD_BITS_PER_WORD and SPI_IOC_WR_BITS_PER_WORD)
+	BitsPerWord() (uint8, error)
+	SetBitsPerWord(uint8) error
+
+	// Transfer performs a full-duplex transfer (SPI_IOC_MESSAGE). Either
+	// buffer may be nil for a half-duplex transfer, otherwise they are
+	// the same length. Zero speed or bits uses the device settings
+	Transfer(tx, rx []byte, speed uint32, bits uint8) error
+}
+
+type device struct {
+	fh *os.File
+}
+
+type spi_ioc_transfer struct {
+	tx_buf        uint64
+	rx_buf        uint64
+	len           uint32
+	speed_hz      uint32
+	delay_usecs   uint16
+	bits_per_word uint8
+	cs_change     uint8
+	tx_nbits      uint8
+	rx_nbits      uint8
+	pad           uint16
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	SPI_DEV_PATH  = "/dev"
+	SPI_DEV_NAME  = "spidev%v.%v"
+	SPI_IOC_MAGIC = 0x6B
+)
+
+const (
+	_IOC_WRITE = 1
+	_IOC_READ  = 2
+)
+
+var (
+	SPI_IOC_RD_MODE          = ioctl(_IOC_READ, 1, 1)
+	SPI_IOC_WR_MODE          = ioctl(_IOC_WRITE, 1, 1)
+	SPI_IOC_RD_BITS_PER_WORD = ioctl(_IOC_READ, 3, 1)
+	SPI_IOC_WR_BITS_PER_WORD = ioctl(_IOC_WRITE, 3, 1)
+	SPI_IOC_RD_MAX_SPEED_HZ  = ioctl(_IOC_READ, 4, 4)
+	SPI_IOC_WR_MAX_SPEED_HZ  = ioctl(_IOC_WRITE, 4, 4)
+	SPI_IOC_MESSAGE_1        = ioctl(_IOC_WRITE, 0, unsafe.Sizeof(spi_ioc_transfer{}))
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// OpenDevice opens /dev/spidevB.C for a bus and slave
+func OpenDevice(path string, bus, slave uint) (Device, error) {
+	this := new(device)
+	if path == "" {
+		path = SPI_DEV_PATH
+	}
+	if fh, err := os.OpenFile(filepath.Join(path, fmt.Sprintf(SPI_DEV_NAME, bus, slave)), os.O_RDWR|syscall.O_CLOEXEC, 0); err != nil {
+		return nil, err
+	} else {
+		this.fh = fh
+	}
+	return this, nil
+}
+
+func (this *device) Close() error {
+	return this.fh.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE INTERFACE
+
+func (this *device) Mode() (gopi.SPIMode, error) {
+	var mode uint8
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_RD_MODE, uintptr(unsafe.Pointer(&mode))); errno != 0 {
+		return gopi.SPI_MODE_NONE, errno
+	} else {
+		return gopi.SPIMode(mode), nil
+	}
+}
+
+func (this *device) SetMode(mode gopi.SPIMode) error {
+	value := uint8(mode)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_WR_MODE, uintptr(unsafe.Pointer(&value))); errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+func (this *device) MaxSpeedHz() (uint32, error) {
+	var speed uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_RD_MAX_SPEED_HZ, uintptr(unsafe.Pointer(&speed))); errno != 0 {
+		return 0, errno
+	} else {
+		return speed, nil
+	}
+}
+
+func (this *device) SetMaxSpeedHz(speed uint32) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_WR_MAX_SPEED_HZ, uintptr(unsafe.Pointer(&speed))); errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+func (this *device) BitsPerWord() (uint8, error) {
+	var bits uint8
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_RD_BITS_PER_WORD, uintptr(unsafe.Pointer(&bits))); errno != 0 {
+		return 0, errno
+	} else {
+		return bits, nil
+	}
+}
+
+func (this *device) SetBitsPerWord(bits uint8) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_WR_BITS_PER_WORD, uintptr(unsafe.Pointer(&bits))); errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+func (this *device) Transfer(tx, rx []byte, speed uint32, bits uint8) error {
+	message := spi_ioc_transfer{
+		speed_hz:      speed,
+		bits_per_word: bits,
+	}
+	if len(tx) > 0 {
+		message.tx_buf = uint64(uintptr(unsafe.Pointer(&tx[0])))
+		message.len = uint32(len(tx))
+	}
+	if len(rx) > 0 {
+		message.rx_buf = uint64(uintptr(unsafe.Pointer(&rx[0])))
+		message.len = uint32(len(rx))
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, this.fh.Fd(), SPI_IOC_MESSAGE_1, uintptr(unsafe.Pointer(&message)))
+
+	// Ensure the buffers are not collected during the transfer
+	runtime.KeepAlive(tx)
+	runtime.KeepAlive(rx)
+
+	if errno != 0 {
+		return errno
+	} else {
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func ioctl(dir, nr, size uintptr) uintptr {
+	return (dir << 30) | (size << 16) | (SPI_IOC_MAGIC << 8) | nr
+}
@@ -0,0 +1,43 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register SPI
+	gopi.RegisterModule(gopi.Module{
+		Name: "sys/spi",
+		Type: gopi.MODULE_TYPE_SPI,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("spi.bus", 0, "SPI Bus")
+			config.AppFlags.FlagUint("spi.slave", 0, "SPI Slave")
+			config.AppFlags.FlagUint("spi.speed", 0, "SPI Maximum Speed (Hz)")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			bus, _ := app.AppFlags.GetUint("spi.bus")
+			slave, _ := app.AppFlags.GetUint("spi.slave")
+			speed, _ := app.AppFlags.GetUint("spi.speed")
+			return gopi.Open(SPI{
+				Bus:   bus,
+				Slave: slave,
+				Speed: uint32(speed),
+			}, app.Logger)
+		},
+	})
+}
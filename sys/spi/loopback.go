@@ -0,0 +1,139 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Loopback is a fake device with MOSI connected to MISO, so that
+// every word sent is received in the same transfer
+type Loopback struct {
+	mode  gopi.SPIMode
+	speed uint32
+	bits  uint8
+	sent  [][]byte
+
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	SPI_LOOPBACK_SPEED = 500000
+	SPI_LOOPBACK_BITS  = 8
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// NewLoopback returns a loopback device with spidev defaults
+func NewLoopback() *Loopback {
+	return &Loopback{
+		mode:  gopi.SPI_MODE_0,
+		speed: SPI_LOOPBACK_SPEED,
+		bits:  SPI_LOOPBACK_BITS,
+	}
+}
+
+func (this *Loopback) Close() error {
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE INTERFACE
+
+func (this *Loopback) Mode() (gopi.SPIMode, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.mode, nil
+}
+
+func (this *Loopback) SetMode(mode gopi.SPIMode) error {
+	this.Lock()
+	defer this.Unlock()
+	this.mode = mode
+	return nil
+}
+
+func (this *Loopback) MaxSpeedHz() (uint32, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.speed, nil
+}
+
+func (this *Loopback) SetMaxSpeedHz(speed uint32) error {
+	this.Lock()
+	defer this.Unlock()
+	this.speed = speed
+	return nil
+}
+
+func (this *Loopback) BitsPerWord() (uint8, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.bits, nil
+}
+
+func (this *Loopback) SetBitsPerWord(bits uint8) error {
+	this.Lock()
+	defer this.Unlock()
+	this.bits = bits
+	return nil
+}
+
+// Transfer copies the transmit buffer into the receive buffer. When
+// nothing is transmitted the line is idle and zeros are received
+func (this *Loopback) Transfer(tx, rx []byte, speed uint32, bits uint8) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if len(tx) == 0 && len(rx) == 0 {
+		return gopi.ErrBadParameter
+	} else if len(tx) > 0 && len(rx) > 0 && len(tx) != len(rx) {
+		return gopi.ErrBadParameter
+	}
+	if len(tx) > 0 {
+		sent := make([]byte, len(tx))
+		copy(sent, tx)
+		this.sent = append(this.sent, sent)
+	} else {
+		this.sent = append(this.sent, make([]byte, len(rx)))
+	}
+	if len(rx) > 0 {
+		for i := range rx {
+			rx[i] = 0
+		}
+		copy(rx, tx)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Sent returns the data transmitted by each transfer, in order
+func (this *Loopback) Sent() [][]byte {
+	this.Lock()
+	defer this.Unlock()
+	sent := make([][]byte, len(this.sent))
+	copy(sent, this.sent)
+	return sent
+}
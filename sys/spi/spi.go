@@ -0,0 +1,269 @@
+//go:build linux
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	"fmt"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SPI is the configuration for the spidev driver
+type SPI struct {
+	Bus     uint   // Bus number
+	Slave   uint   // Slave (chip select) number
+	Speed   uint32 // Maximum speed in Hz, or zero to keep the device setting
+	DevPath string // Path to device (default: /dev)
+	Device  Device // Device, or nil to open /dev/spidevB.C
+}
+
+type spi struct {
+	log   gopi.Logger
+	bus   uint
+	slave uint
+	dev   Device
+	mode  gopi.SPIMode
+	speed uint32
+	bits  uint8
+
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	SPI_BITS_PER_WORD_MAX = 32
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open the SPI driver
+func (config SPI) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("sys.spi.Open{ bus=%v slave=%v speed=%v }", config.Bus, config.Slave, config.Speed)
+
+	this := new(spi)
+	this.log = log
+	this.bus = config.Bus
+	this.slave = config.Slave
+
+	// Open the device
+	if config.Device != nil {
+		this.dev = config.Device
+	} else if dev, err := OpenDevice(config.DevPath, config.Bus, config.Slave); err != nil {
+		return nil, err
+	} else {
+		this.dev = dev
+	}
+
+	// Set speed and read back the device settings
+	if config.Speed != 0 {
+		if err := this.dev.SetMaxSpeedHz(config.Speed); err != nil {
+			this.dev.Close()
+			return nil, err
+		}
+	}
+	if mode, err := this.dev.Mode(); err != nil {
+		this.dev.Close()
+		return nil, err
+	} else if speed, err := this.dev.MaxSpeedHz(); err != nil {
+		this.dev.Close()
+		return nil, err
+	} else if bits, err := this.dev.BitsPerWord(); err != nil {
+		this.dev.Close()
+		return nil, err
+	} else {
+		this.mode = mode & gopi.SPI_MODE_3
+		this.speed = speed
+		this.bits = bits
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close the SPI driver
+func (this *spi) Close() error {
+	this.log.Debug("sys.spi.Close{ bus=%v slave=%v }", this.bus, this.slave)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return nil
+	}
+	err := this.dev.Close()
+	this.dev = nil
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - GET
+
+// Mode returns the SPI mode
+func (this *spi) Mode() gopi.SPIMode {
+	return this.mode
+}
+
+// MaxSpeedHz returns the maximum transfer speed
+func (this *spi) MaxSpeedHz() uint32 {
+	return this.speed
+}
+
+// BitsPerWord returns the word size, where zero means eight bits
+func (this *spi) BitsPerWord() uint8 {
+	return this.bits
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - SET
+
+// SetMode sets the clock polarity and phase
+func (this *spi) SetMode(mode gopi.SPIMode) error {
+	this.log.Debug2("sys.spi.SetMode{ mode=%v }", mode)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if mode > gopi.SPI_MODE_3 {
+		return gopi.ErrBadParameter
+	} else if err := this.dev.SetMode(mode); err != nil {
+		return err
+	} else {
+		this.mode = mode
+		return nil
+	}
+}
+
+// SetMaxSpeedHz sets the maximum transfer speed
+func (this *spi) SetMaxSpeedHz(speed uint32) error {
+	this.log.Debug2("sys.spi.SetMaxSpeedHz{ speed=%v }", speed)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if speed == 0 {
+		return gopi.ErrBadParameter
+	} else if err := this.dev.SetMaxSpeedHz(speed); err != nil {
+		return err
+	} else {
+		this.speed = speed
+		return nil
+	}
+}
+
+// SetBitsPerWord sets the word size
+func (this *spi) SetBitsPerWord(bits uint8) error {
+	this.log.Debug2("sys.spi.SetBitsPerWord{ bits=%v }", bits)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if bits == 0 || bits > SPI_BITS_PER_WORD_MAX {
+		return gopi.ErrBadParameter
+	} else if err := this.dev.SetBitsPerWord(bits); err != nil {
+		return err
+	} else {
+		this.bits = bits
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - TRANSFER
+
+// Transfer sends data and returns the data received at the same time
+func (this *spi) Transfer(send []byte) ([]byte, error) {
+	this.log.Debug2("sys.spi.Transfer{ send=%v }", strbytes(send))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return nil, gopi.ErrOutOfOrder
+	} else if len(send) == 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	recv := make([]byte, len(send))
+	if err := this.dev.Transfer(send, recv, this.speed, this.bits); err != nil {
+		return nil, err
+	} else {
+		return recv, nil
+	}
+}
+
+// Read receives data while sending zeros
+func (this *spi) Read(length uint32) ([]byte, error) {
+	this.log.Debug2("sys.spi.Read{ length=%v }", length)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return nil, gopi.ErrOutOfOrder
+	} else if length == 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	recv := make([]byte, length)
+	if err := this.dev.Transfer(nil, recv, this.speed, this.bits); err != nil {
+		return nil, err
+	} else {
+		return recv, nil
+	}
+}
+
+// Write sends data and discards the data received
+func (this *spi) Write(send []byte) error {
+	this.log.Debug2("sys.spi.Write{ send=%v }", strbytes(send))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.dev == nil {
+		return gopi.ErrOutOfOrder
+	} else if len(send) == 0 {
+		return gopi.ErrBadParameter
+	} else {
+		return this.dev.Transfer(send, nil, this.speed, this.bits)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *spi) String() string {
+	return fmt.Sprintf("<sys.spi>{ bus=%v slave=%v mode=%v speed=%vHz bits_per_word=%v }", this.bus, this.slave, this.mode, this.speed, this.bits)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func strbytes(data []byte) string {
+	str := ""
+	for _, b := range data {
+		str += fmt.Sprintf("%02X ", b)
+	}
+	return "[ " + str + "]"
+}
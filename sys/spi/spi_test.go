@@ -0,0 +1,120 @@
+//go:build linux
+// +build linux
+
+package spi_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/spi"
+
+	// Modules
+	logger "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestSPI_000(t *testing.T) {
+	dev := spi.NewLoopback()
+	driver := openSPI(t, spi.SPI{Device: dev, Speed: 1000000})
+	defer driver.Close()
+
+	if mode := driver.Mode(); mode != gopi.SPI_MODE_0 {
+		t.Error("Unexpected mode", mode)
+	}
+	if speed := driver.MaxSpeedHz(); speed != 1000000 {
+		t.Error("Unexpected speed", speed)
+	}
+	if bits := driver.BitsPerWord(); bits != 8 {
+		t.Error("Unexpected bits per word", bits)
+	}
+}
+
+func TestSPI_001(t *testing.T) {
+	dev := spi.NewLoopback()
+	driver := openSPI(t, spi.SPI{Device: dev})
+	defer driver.Close()
+
+	if err := driver.SetMode(gopi.SPI_MODE_3); err != nil {
+		t.Error(err)
+	} else if mode, _ := dev.Mode(); mode != gopi.SPI_MODE_3 || driver.Mode() != gopi.SPI_MODE_3 {
+		t.Error("Unexpected mode", mode)
+	}
+	if err := driver.SetMode(gopi.SPI_MODE_NONE); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := driver.SetMaxSpeedHz(0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := driver.SetBitsPerWord(16); err != nil {
+		t.Error(err)
+	} else if bits, _ := dev.BitsPerWord(); bits != 16 {
+		t.Error("Unexpected bits per word", bits)
+	}
+	if err := driver.SetBitsPerWord(33); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestSPI_002(t *testing.T) {
+	dev := spi.NewLoopback()
+	driver := openSPI(t, spi.SPI{Device: dev})
+	defer driver.Close()
+
+	// Full-duplex transfer receives what was sent
+	send := []byte{0x01, 0x80, 0xFF, 0x55}
+	if recv, err := driver.Transfer(send); err != nil {
+		t.Error(err)
+	} else if string(recv) != string(send) {
+		t.Error("Unexpected receive", recv)
+	}
+	if _, err := driver.Transfer(nil); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestSPI_003(t *testing.T) {
+	dev := spi.NewLoopback()
+	driver := openSPI(t, spi.SPI{Device: dev})
+	defer driver.Close()
+
+	// Read sends zeros, write discards the receive buffer
+	if recv, err := driver.Read(3); err != nil {
+		t.Error(err)
+	} else if string(recv) != string([]byte{0, 0, 0}) {
+		t.Error("Unexpected receive", recv)
+	}
+	if err := driver.Write([]byte{0xAA, 0xBB}); err != nil {
+		t.Error(err)
+	}
+	if sent := dev.Sent(); len(sent) != 2 {
+		t.Error("Unexpected number of transfers", len(sent))
+	} else if string(sent[1]) != string([]byte{0xAA, 0xBB}) {
+		t.Error("Unexpected send", sent[1])
+	}
+
+	// Transfers fail after close
+	driver.Close()
+	if err := driver.Write([]byte{0x00}); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openSPI(t *testing.T, config spi.SPI) gopi.SPI {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if driver, err := gopi.Open(config, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.SPI)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package timer
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Default multiplier for backoff delays
+	BACKOFF_MULTIPLIER = 2.0
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// restartBackoff starts the delays for a backoff unit again from a
+// duration, and returns the first delay. Called with the mutex held
+func (this *timer) restartBackoff(u *unit, duration time.Duration) time.Duration {
+	if u.duration = duration; u.duration > u.policy.MaxDuration {
+		u.duration = u.policy.MaxDuration
+	}
+	u.delay = u.policy.Duration
+	return this.nextBackoff(u)
+}
+
+// nextBackoff returns the next delay for a backoff unit, with jitter,
+// and multiplies the delay without jitter for the delay after that.
+// Called with the mutex held
+func (this *timer) nextBackoff(u *unit) time.Duration {
+	delay := u.duration
+	switch u.policy.Jitter {
+	case gopi.BACKOFF_JITTER_FULL:
+		// Between zero and the delay without jitter
+		delay = time.Duration(this.rand.Int63n(int64(delay) + 1))
+	case gopi.BACKOFF_JITTER_DECORRELATED:
+		// Between the initial duration and the previous delay times
+		// the multiplier, up to the maximum
+		min, max := u.policy.Duration, u.policy.MaxDuration
+		if upper := time.Duration(float64(u.delay) * u.policy.Multiplier); upper < max {
+			max = upper
+		}
+		if delay = min; max > min {
+			delay = min + time.Duration(this.rand.Int63n(int64(max-min)+1))
+		}
+	}
+	u.delay = delay
+
+	// Multiply the delay without jitter, up to the maximum
+	if u.duration = time.Duration(float64(u.duration) * u.policy.Multiplier); u.duration > u.policy.MaxDuration || u.duration <= 0 {
+		u.duration = u.policy.MaxDuration
+	}
+
+	// Return the delay
+	return delay
+}
@@ -0,0 +1,141 @@
+package timer_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+	timer "github.com/djthorpe/gopi/sys/timer"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// selectTimer is the previous timer driver, which has a runtime timer
+// for each unit and waits on all of them with reflect.Select, rebuilding
+// the cases whenever a unit is added
+type selectTimer struct {
+	mutex  sync.Mutex
+	timers []*time.Timer
+	reload chan struct{}
+	stop   chan struct{}
+	fired  chan int
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BENCHMARKS
+
+func BenchmarkTimer_Heap_100(b *testing.B)    { benchmarkHeap(b, 100) }
+func BenchmarkTimer_Heap_1000(b *testing.B)   { benchmarkHeap(b, 1000) }
+func BenchmarkTimer_Select_100(b *testing.B)  { benchmarkSelect(b, 100) }
+func BenchmarkTimer_Select_1000(b *testing.B) { benchmarkSelect(b, 1000) }
+
+func benchmarkHeap(b *testing.B, count int) {
+	log, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer log.Close()
+	driver, err := gopi.Open(timer.Timer{}, log.(gopi.Logger))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < count; j++ {
+			if _, err := timer_.NewTimeout(time.Millisecond, j); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for j := 0; j < count; j++ {
+			<-events
+		}
+	}
+}
+
+func benchmarkSelect(b *testing.B, count int) {
+	timer_ := newSelectTimer()
+	defer timer_.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < count; j++ {
+			timer_.NewTimeout(time.Millisecond)
+		}
+		for j := 0; j < count; j++ {
+			<-timer_.fired
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SELECT TIMER
+
+func newSelectTimer() *selectTimer {
+	this := new(selectTimer)
+	this.reload = make(chan struct{}, 1)
+	this.stop = make(chan struct{})
+	this.fired = make(chan int)
+	go this.wait_for_timers()
+	return this
+}
+
+func (this *selectTimer) Close() {
+	close(this.stop)
+}
+
+func (this *selectTimer) NewTimeout(duration time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.timers = append(this.timers, time.NewTimer(duration))
+	select {
+	case this.reload <- struct{}{}:
+	default:
+	}
+}
+
+func (this *selectTimer) wait_for_timers() {
+	for {
+		this.mutex.Lock()
+		timers := this.timers
+		this.mutex.Unlock()
+
+		cases := make([]reflect.SelectCase, len(timers)+2)
+		cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(this.reload)}
+		cases[1] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(this.stop)}
+		for i, t := range timers {
+			cases[i+2] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(t.C)}
+		}
+		switch chosen, _, _ := reflect.Select(cases); chosen {
+		case 0:
+			continue
+		case 1:
+			return
+		default:
+			// Remove the timer which fired, creating a new slice
+			this.mutex.Lock()
+			units := make([]*time.Timer, 0, len(this.timers))
+			for _, t := range this.timers {
+				if t != timers[chosen-2] {
+					units = append(units, t)
+				}
+			}
+			this.timers = units
+			this.mutex.Unlock()
+			select {
+			case this.fired <- chosen - 2:
+			case <-this.stop:
+				return
+			}
+		}
+	}
+}
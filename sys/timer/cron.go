@@ -0,0 +1,317 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package timer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// cron is a schedule of minutes, hours, days of the month, months and
+// days of the week in a time zone. Fields are bit sets
+type cron struct {
+	spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	wildcard bool
+	location *time.Location
+}
+
+// A field of a cron expression
+type cronField struct {
+	min, max uint
+	names    []string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Number of days searched for the next time
+	CRON_MAX_DAYS = 366 * 5
+
+	// Window either side of a wall-clock time which includes
+	// changes of time zone offset
+	CRON_ZONE_WINDOW = 3 * time.Hour
+)
+
+var (
+	cronFields = []cronField{
+		{0, 59, nil}, // Minute
+		{0, 23, nil}, // Hour
+		{1, 31, nil}, // Day of month
+		{1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+		{0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+	}
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ParseCron returns a schedule from a cron expression with five fields
+// for minute, hour, day of month, month and day of week. Fields can be
+// lists, ranges and steps such as "0,30", "9-17" and "*/15", and months
+// and days of week can be names. The macros @yearly, @monthly, @weekly,
+// @daily and @hourly are also accepted. Times are in the location, or
+// the local time zone if nil, unless the expression is prefixed with a
+// time zone such as "TZ=Europe/London".
+//
+// When clocks go forward, times which don't exist fire after the change
+// with the same offset. When clocks go back, times which occur twice fire
+// once, unless the minute or hour is a wildcard
+func ParseCron(spec string, location *time.Location) (gopi.Schedule, error) {
+	this := new(cron)
+	this.spec = spec
+	this.location = location
+	if this.location == nil {
+		this.location = time.Local
+	}
+
+	// Time zone prefix
+	fields := strings.Fields(spec)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		if location, err := time.LoadLocation(fields[0][strings.Index(fields[0], "=")+1:]); err != nil {
+			return nil, fmt.Errorf("%v: %v", fields[0], err)
+		} else {
+			this.location = location
+			fields = fields[1:]
+		}
+	}
+
+	// Macros
+	if len(fields) == 1 {
+		if macro, exists := cronMacros[strings.ToLower(fields[0])]; exists {
+			fields = strings.Fields(macro)
+		}
+	}
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%v: %v", strconv.Quote(spec), gopi.ErrBadParameter)
+	}
+
+	// Fields
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		if value, err := cronFields[i].parse(field); err != nil {
+			return nil, fmt.Errorf("%v: %v: %v", strconv.Quote(spec), field, err)
+		} else {
+			bits[i] = value
+		}
+	}
+	this.minute, this.hour, this.dom, this.month, this.dow = bits[0], bits[1], bits[2], bits[3], bits[4]
+
+	// Sunday is day zero or seven
+	if this.dow&(1<<7) != 0 {
+		this.dow |= 1
+	}
+	this.domStar = strings.HasPrefix(fields[2], "*")
+	this.dowStar = strings.HasPrefix(fields[4], "*")
+	this.wildcard = strings.HasPrefix(fields[0], "*") || strings.HasPrefix(fields[1], "*")
+
+	// Success
+	return this, nil
+}
+
+// Daily returns a schedule which fires every day at a wall-clock time
+// in the location, or the local time zone if nil
+func Daily(hour, minute uint, location *time.Location) (gopi.Schedule, error) {
+	if hour > 23 || minute > 59 {
+		return nil, gopi.ErrBadParameter
+	}
+	return ParseCron(fmt.Sprintf("%d %d * * *", minute, hour), location)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - SCHEDULE
+
+// Next returns the first time after t, or the zero time if there is no
+// time within CRON_MAX_DAYS
+func (this *cron) Next(t time.Time) time.Time {
+	wall := wallClock(t.In(this.location))
+
+	// Start the day before, since times can move across days when
+	// the time zone offset changes
+	day := time.Date(wall.Year(), wall.Month(), wall.Day()-1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < CRON_MAX_DAYS; i++ {
+		if this.matchDay(day) {
+			if next := this.nextInDay(day, t, wall); next.IsZero() == false {
+				return next
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *cron) String() string {
+	return fmt.Sprintf("<sys.timer.cron>{ spec=%v location=%v }", strconv.Quote(this.spec), this.location)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parse returns the bit set for a field
+func (this cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		// Step
+		step := uint(1)
+		if i := strings.Index(item, "/"); i >= 0 {
+			if value, err := strconv.ParseUint(item[i+1:], 10, 8); err != nil || value == 0 {
+				return 0, gopi.ErrBadParameter
+			} else {
+				step, item = uint(value), item[:i]
+			}
+		}
+		// Wildcard, range or value
+		min, max := this.min, this.max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			if value, err := this.value(bounds[0]); err != nil {
+				return 0, err
+			} else {
+				min = value
+			}
+			if len(bounds) == 2 {
+				if value, err := this.value(bounds[1]); err != nil {
+					return 0, err
+				} else {
+					max = value
+				}
+			} else if step == 1 {
+				max = min
+			}
+		}
+		if min > max {
+			return 0, gopi.ErrBadParameter
+		}
+		for value := min; value <= max; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// value returns a number or name within the bounds of a field
+func (this cronField) value(value string) (uint, error) {
+	for i, name := range this.names {
+		if strings.EqualFold(value, name) {
+			return this.min + uint(i), nil
+		}
+	}
+	if number, err := strconv.ParseUint(value, 10, 8); err != nil {
+		return 0, gopi.ErrBadParameter
+	} else if uint(number) < this.min || uint(number) > this.max {
+		return 0, gopi.ErrBadParameter
+	} else {
+		return uint(number), nil
+	}
+}
+
+// matchDay returns true if the schedule fires on a day. When both the day
+// of month and day of week are restricted, either can match
+func (this *cron) matchDay(day time.Time) bool {
+	if this.month&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+	dom := this.dom&(1<<uint(day.Day())) != 0
+	dow := this.dow&(1<<uint(day.Weekday())) != 0
+	if this.domStar || this.dowStar {
+		return dom && dow
+	} else {
+		return dom || dow
+	}
+}
+
+// nextInDay returns the first time after t on a day, where wall is the
+// wall-clock time for t, or the zero time
+func (this *cron) nextInDay(day, t, wall time.Time) time.Time {
+	var next time.Time
+	for h := uint(0); h < 24; h++ {
+		if this.hour&(1<<h) == 0 {
+			continue
+		}
+		for m := uint(0); m < 60; m++ {
+			if this.minute&(1<<m) == 0 {
+				continue
+			}
+			naive := day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+			if naive.Add(CRON_ZONE_WINDOW).Before(wall) {
+				continue
+			} else if next.IsZero() == false && naive.After(wallClock(next.In(this.location)).Add(CRON_ZONE_WINDOW)) {
+				return next
+			}
+			for _, instant := range this.instants(naive) {
+				if instant.After(t) && (next.IsZero() || instant.Before(next)) {
+					next = instant
+				}
+			}
+		}
+	}
+	return next
+}
+
+// instants returns the times for a wall-clock time, which is none or one
+// when clocks go forward and one or two when clocks go back
+func (this *cron) instants(naive time.Time) []time.Time {
+	t := time.Date(naive.Year(), naive.Month(), naive.Day(), naive.Hour(), naive.Minute(), 0, 0, this.location)
+	instants := make([]time.Time, 0, 2)
+	for _, offset := range []time.Time{t.Add(-CRON_ZONE_WINDOW), t, t.Add(CRON_ZONE_WINDOW)} {
+		_, seconds := offset.Zone()
+		instant := naive.Add(-time.Duration(seconds) * time.Second).In(this.location)
+		if wallClock(instant).Equal(naive) == false {
+			continue
+		} else if len(instants) > 0 && instants[len(instants)-1].Equal(instant) {
+			continue
+		} else if len(instants) > 0 && instant.Before(instants[0]) {
+			instants = append([]time.Time{instant}, instants...)
+		} else {
+			instants = append(instants, instant)
+		}
+	}
+	if len(instants) == 0 && this.wildcard == false {
+		// Clocks go forward: use the offset before the change
+		_, seconds := t.Add(-CRON_ZONE_WINDOW).Zone()
+		instants = append(instants, naive.Add(-time.Duration(seconds)*time.Second).In(this.location))
+	} else if len(instants) > 1 && this.wildcard == false {
+		// Clocks go back: use the first time
+		instants = instants[:1]
+	}
+	return instants
+}
+
+// wallClock returns the wall-clock time in UTC, truncated to the minute
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
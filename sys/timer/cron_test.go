@@ -0,0 +1,208 @@
+package timer_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+	timer "github.com/djthorpe/gopi/sys/timer"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestCron_000(t *testing.T) {
+	// Parse valid and invalid expressions
+	for _, spec := range []string{"* * * * *", "*/15 9-17 * * mon-fri", "0,30 6 1 jan,JUL *", "5/10 * * * 7", "@daily", "TZ=UTC 30 6 * * *"} {
+		if _, err := timer.ParseCron(spec, time.UTC); err != nil {
+			t.Error(spec, err)
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "TZ=Nowhere/City * * * * *", "@never"} {
+		if _, err := timer.ParseCron(spec, time.UTC); err == nil {
+			t.Error("Expected error for", spec)
+		}
+	}
+	if _, err := timer.Daily(24, 0, nil); err == nil {
+		t.Error("Expected error for hour 24")
+	}
+}
+
+func TestCron_001(t *testing.T) {
+	// Next times in UTC
+	from := time.Date(2019, 1, 31, 23, 59, 30, 0, time.UTC) // Thursday
+	for _, test := range []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 6 * * *", time.Date(2019, 2, 1, 6, 30, 0, 0, time.UTC)},
+		{"59 23 * * *", time.Date(2019, 2, 1, 23, 59, 0, 0, time.UTC)},
+		{"*/15 9-17 * * mon-fri", time.Date(2019, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * sat,sun", time.Date(2019, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 feb *", time.Time{}},
+	} {
+		if schedule, err := timer.ParseCron(test.spec, time.UTC); err != nil {
+			t.Error(test.spec, err)
+		} else if next := schedule.Next(from); next.Equal(test.next) == false {
+			t.Errorf("%v: Expected %v, got %v", test.spec, test.next, next)
+		}
+	}
+}
+
+func TestCron_002(t *testing.T) {
+	// Times in a time zone when clocks go forward and back
+	london := loadLocation(t, "Europe/London")
+	for _, test := range []struct {
+		spec string
+		from time.Time
+		next []string
+	}{
+		// Every day at 06:30 local time, across the change to BST
+		{"30 6 * * *", time.Date(2019, 3, 30, 7, 0, 0, 0, london), []string{
+			"2019-03-31T06:30:00+01:00", "2019-04-01T06:30:00+01:00",
+		}},
+		// 01:30 doesn't exist when clocks go forward, so fires an hour later
+		{"30 1 * * *", time.Date(2019, 3, 30, 2, 0, 0, 0, london), []string{
+			"2019-03-31T02:30:00+01:00", "2019-04-01T01:30:00+01:00",
+		}},
+		// 01:30 occurs twice when clocks go back, and fires once
+		{"30 1 * * *", time.Date(2019, 10, 26, 2, 0, 0, 0, london), []string{
+			"2019-10-27T01:30:00+01:00", "2019-10-28T01:30:00Z",
+		}},
+		// Wildcards fire in both hours when clocks go back
+		{"30 * * * *", time.Date(2019, 10, 27, 0, 0, 0, 0, london), []string{
+			"2019-10-27T00:30:00+01:00", "2019-10-27T01:30:00+01:00", "2019-10-27T01:30:00Z", "2019-10-27T02:30:00Z",
+		}},
+		// The time zone prefix overrides the location
+		{"TZ=America/New_York 0 9 * * *", time.Date(2019, 3, 9, 15, 0, 0, 0, time.UTC), []string{
+			"2019-03-10T09:00:00-04:00",
+		}},
+	} {
+		schedule, err := timer.ParseCron(test.spec, london)
+		if err != nil {
+			t.Fatal(test.spec, err)
+		}
+		next := test.from
+		for _, expected := range test.next {
+			if next = schedule.Next(next); next.Format(time.RFC3339) != expected {
+				t.Errorf("%v: Expected %v, got %v", test.spec, expected, next.Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+func TestCron_003(t *testing.T) {
+	// Fire a daily schedule with a clock set just before the time
+	clock := newClock(time.Date(2019, 6, 1, 6, 29, 59, 900000000, time.UTC))
+	driver, err := gopi.Open(timer.Timer{Clock: clock}, openLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+	if schedule, err := timer.Daily(6, 30, time.UTC); err != nil {
+		t.Fatal(err)
+	} else if _, err := timer_.NewSchedule(schedule, "daily"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-events:
+		if evt_, ok := evt.(gopi.TimerEvent); ok == false {
+			t.Error("Unexpected event", evt)
+		} else if evt_.UserInfo() != "daily" || evt_.Counter() != 1 {
+			t.Error("Unexpected event", evt_)
+		} else if evt_.Timestamp().Equal(time.Date(2019, 6, 1, 6, 30, 0, 0, time.UTC)) == false {
+			t.Error("Unexpected timestamp", evt_.Timestamp())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+}
+
+func TestCron_004(t *testing.T) {
+	// Fire a cron schedule every minute in local time
+	clock := newClock(time.Date(2019, 6, 1, 0, 0, 59, 950000000, time.Local))
+	driver, err := gopi.Open(timer.Timer{Clock: clock}, openLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+	if _, err := timer_.NewCron("* * * * *", "minute"); err != nil {
+		t.Fatal(err)
+	} else if _, err := timer_.NewCron("* * *", "minute"); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+
+	select {
+	case evt := <-events:
+		if evt_ := evt.(gopi.TimerEvent); evt_.UserInfo() != "minute" || evt_.Counter() != 1 {
+			t.Error("Unexpected event", evt_)
+		} else if evt_.Timestamp().Equal(time.Date(2019, 6, 1, 0, 1, 0, 0, time.Local)) == false {
+			t.Error("Unexpected timestamp", evt_.Timestamp())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CLOCK
+
+// clock runs in real time from a start time
+type clock struct {
+	sync.Mutex
+	offset time.Duration
+}
+
+func newClock(start time.Time) *clock {
+	return &clock{offset: start.Sub(time.Now())}
+}
+
+func (this *clock) Now() time.Time {
+	this.Lock()
+	defer this.Unlock()
+	return time.Now().Add(this.offset)
+}
+
+func (this *clock) AfterFunc(d time.Duration, f func()) gopi.ClockTimer {
+	return gopi.SystemClock.AfterFunc(d, f)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	if location, err := time.LoadLocation(name); err != nil {
+		t.Skip(name, err)
+		return nil
+	} else {
+		return location
+	}
+}
+
+func openLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.Logger)
+	}
+}
@@ -23,6 +23,9 @@ import (
 type evt struct {
 	source    gopi.Driver
 	info      *unit
+	counter   uint
+	attempt   uint
+	delay     time.Duration
 	timestamp time.Time
 }
 
@@ -30,7 +33,13 @@ type evt struct {
 // EVENT INTERFACE
 
 func NewTimerEvent(source gopi.Timer, u *unit, ts time.Time) gopi.Event {
-	return &evt{source, u, ts}
+	var delay time.Duration
+	if u.done == false && u.paused == false {
+		if delay = u.next.Add(u.jitter).Sub(u.parent.clock.Now()); delay < 0 {
+			delay = 0
+		}
+	}
+	return &evt{source, u, u.counter, u.attempt, delay, ts}
 }
 
 func (this *evt) Name() string {
@@ -50,11 +59,19 @@ func (this *evt) UserInfo() interface{} {
 }
 
 func (this *evt) String() string {
-	return fmt.Sprintf("<sys.timer.event>{ ts=%v counter=%v userInfo=%v }", this.timestamp.Format(time.Kitchen), this.info.counter, this.info.userInfo)
+	return fmt.Sprintf("<sys.timer.event>{ ts=%v counter=%v attempt=%v delay=%v userInfo=%v }", this.timestamp.Format(time.Kitchen), this.counter, this.attempt, this.delay, this.info.userInfo)
 }
 
 func (this *evt) Counter() uint {
-	return this.info.counter
+	return this.counter
+}
+
+func (this *evt) Attempt() uint {
+	return this.attempt
+}
+
+func (this *evt) Delay() time.Duration {
+	return this.delay
 }
 
 func (this *evt) Cancel() {
@@ -0,0 +1,63 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package timer
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// unitHeap is a min-heap of units ordered by deadline, which implements
+// heap.Interface. Each unit holds its index in the heap, or -1 when it
+// is not in the heap
+type unitHeap []*unit
+
+////////////////////////////////////////////////////////////////////////////////
+// HEAP INTERFACE
+
+func (this unitHeap) Len() int {
+	return len(this)
+}
+
+func (this unitHeap) Less(i, j int) bool {
+	return this[i].deadline.Before(this[j].deadline)
+}
+
+func (this unitHeap) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+	this[i].index = i
+	this[j].index = j
+}
+
+func (this *unitHeap) Push(x interface{}) {
+	u := x.(*unit)
+	u.index = len(*this)
+	*this = append(*this, u)
+}
+
+func (this *unitHeap) Pop() interface{} {
+	old := *this
+	n := len(old)
+	u := old[n-1]
+	old[n-1] = nil
+	u.index = -1
+	*this = old[:n-1]
+	return u
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// peek returns the unit with the earliest deadline, or nil
+func (this unitHeap) peek() *unit {
+	if len(this) == 0 {
+		return nil
+	} else {
+		return this[0]
+	}
+}
@@ -21,8 +21,18 @@ func init() {
 	gopi.RegisterModule(gopi.Module{
 		Name: "sys/timer",
 		Type: gopi.MODULE_TYPE_TIMER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagDuration("timer.jitter", 0, "Maximum random delay added when timers fire")
+			config.AppFlags.FlagDuration("timer.coalesce", 0, "Window in which due timers fire together")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
-			return gopi.Open(Timer{}, app.Logger)
+			jitter, _ := app.AppFlags.GetDuration("timer.jitter")
+			coalesce, _ := app.AppFlags.GetDuration("timer.coalesce")
+			return gopi.Open(Timer{
+				Clock:    app.Clock,
+				Jitter:   jitter,
+				Coalesce: coalesce,
+			}, app.Logger)
 		},
 	})
 }
@@ -10,8 +10,10 @@
 package timer
 
 import (
+	"container/heap"
 	"fmt"
-	"reflect"
+	"math/rand"
+	"sync"
 	"time"
 
 	// Frameworks
@@ -22,24 +24,27 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 // TYPES
 
-type Timer struct{}
+type Timer struct {
+	Clock    gopi.Clock    // Clock for timers and schedules (default: gopi.SystemClock)
+	Jitter   time.Duration // Maximum random delay added when timers fire
+	Coalesce time.Duration // Timers due within this window fire together
+}
 
+// timer keeps units in a min-heap ordered by deadline, and waits for
+// the earliest deadline with a single clock timer
 type timer struct {
 	log      gopi.Logger
-	channels []reflect.SelectCase
-	units    map[int]*unit
+	clock    gopi.Clock
+	jitter   time.Duration
+	coalesce time.Duration
+	rand     *rand.Rand
+	wake     gopi.ClockTimer
+	units    unitHeap
+	firing   bool
+	closed   bool
+	mutex    sync.Mutex
 
 	event.Publisher
-	event.Tasks
-}
-
-type unit struct {
-	timer        *time.Timer
-	ticker       *time.Ticker
-	userInfo     interface{}
-	counter      uint
-	duration     time.Duration
-	max_duration time.Duration
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -47,19 +52,26 @@ type unit struct {
 
 // Open the timer
 func (config Timer) Open(log gopi.Logger) (gopi.Driver, error) {
-	log.Debug("sys.timer.Open{ }")
+	log.Debug("sys.timer.Open{ jitter=%v coalesce=%v }", config.Jitter, config.Coalesce)
+
+	if config.Jitter < 0 || config.Coalesce < 0 {
+		return nil, gopi.ErrBadParameter
+	}
 
 	this := new(timer)
 	this.log = log
-	this.channels = make([]reflect.SelectCase, 1)
-	this.channels[0] = reflect.SelectCase{
-		Dir:  reflect.SelectRecv,
-		Chan: reflect.ValueOf(make(chan event.Signal)),
+	this.clock = config.Clock
+	if this.clock == nil {
+		this.clock = gopi.SystemClock
 	}
-	this.units = make(map[int]*unit)
+	this.jitter = config.Jitter
+	this.coalesce = config.Coalesce
+	this.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	this.units = make(unitHeap, 0)
 
-	// Background go routine - waits for reload, stop or ticker events
-	this.Tasks.Start(this.wait_for_timers)
+	// The clock timer fires units at the earliest deadline
+	this.wake = this.clock.AfterFunc(time.Hour, this.fire)
+	this.wake.Stop()
 
 	return this, nil
 }
@@ -68,23 +80,20 @@ func (config Timer) Open(log gopi.Logger) (gopi.Driver, error) {
 func (this *timer) Close() error {
 	this.log.Debug("sys.timer.Close{ }")
 
-	// Cancel all the timers
+	// Remove all the timers
+	this.mutex.Lock()
 	for _, unit := range this.units {
-		unit.Cancel()
-	}
-
-	// End the task
-	if err := this.Tasks.Close(); err != nil {
-		this.log.Warn("sys.timer.Close: %v", err)
+		unit.done = true
+		unit.index = -1
 	}
+	this.units = nil
+	this.closed = true
+	this.wake.Stop()
+	this.mutex.Unlock()
 
 	// Unsubscribe and close
 	this.Publisher.Close()
 
-	// Blank out instance variables
-	this.channels = nil
-	this.units = nil
-
 	return nil
 }
 
@@ -92,153 +101,288 @@ func (this *timer) Close() error {
 // INTERFACE - TIMERS
 
 // NewTimeout schedules a on-shot timer
-func (this *timer) NewTimeout(duration time.Duration, userInfo interface{}) error {
+func (this *timer) NewTimeout(duration time.Duration, userInfo interface{}) (gopi.TimerHandle, error) {
 	this.log.Debug2("sys.timer.NewTimeout{ duration=%v userInfo=%v }", duration, userInfo)
 
-	// Create the timeout, append channel and reload
-	timer := time.NewTimer(duration)
-	this.append(reflect.ValueOf(timer.C), &unit{
-		timer:    timer,
+	// Create the timeout
+	return this.add(&unit{
 		userInfo: userInfo,
-	})
-	// Success
-	return nil
+	}, duration)
 }
 
 // NewInterval schedules a periodic firing, which can fire immediately
-func (this *timer) NewInterval(duration time.Duration, userInfo interface{}, immediately bool) error {
+func (this *timer) NewInterval(duration time.Duration, userInfo interface{}, immediately bool) (gopi.TimerHandle, error) {
 	this.log.Debug2("sys.timer.NewInterval{ duration=%v userInfo=%v immediately=%v }", duration, userInfo, immediately)
 
-	// Create the ticker, append channel and reload
-	ticker := time.NewTicker(duration)
-	idx := this.append(reflect.ValueOf(ticker.C), &unit{
-		ticker:   ticker,
+	// Check for zero duration
+	if duration <= 0 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create the interval
+	u, err := this.add(&unit{
 		userInfo: userInfo,
-	})
+		duration: duration,
+		interval: true,
+	}, duration)
+	if err != nil {
+		return nil, err
+	}
 	if immediately {
-		this.emit(idx, time.Now())
+		this.emit(u, this.clock.Now())
 	}
 	// Success
-	return nil
+	return u, nil
 }
 
 // NewBackoff schedules a backoff timer with maximum backoff duration
-func (this *timer) NewBackoff(duration time.Duration, max_duration time.Duration, userInfo interface{}) error {
+func (this *timer) NewBackoff(duration time.Duration, max_duration time.Duration, userInfo interface{}) (gopi.TimerHandle, error) {
 	this.log.Debug2("sys.timer.NewBackoff{ duration=%v max_duration=%v userInfo=%v }", duration, max_duration, userInfo)
 
 	// Check for zero durations
-	if duration == 0 || max_duration == 0 {
-		return gopi.ErrBadParameter
+	if duration <= 0 || max_duration <= 0 {
+		return nil, gopi.ErrBadParameter
 	}
 	if max_duration <= duration {
-		return gopi.ErrBadParameter
+		return nil, gopi.ErrBadParameter
 	}
 
-	// Create the timeout, append channel and reload
-	timer := time.NewTimer(duration)
-	idx := this.append(reflect.ValueOf(timer.C), &unit{
-		timer:        timer,
-		userInfo:     userInfo,
-		duration:     duration,
-		max_duration: max_duration,
-	})
+	// Create the backoff, which doubles the interval
+	return this.NewBackoffWithPolicy(gopi.BackoffPolicy{
+		Duration:    duration,
+		MaxDuration: max_duration,
+	}, userInfo)
+}
+
+// NewBackoffWithPolicy schedules a backoff timer which fires immediately
+// and then after delays set by the policy
+func (this *timer) NewBackoffWithPolicy(policy gopi.BackoffPolicy, userInfo interface{}) (gopi.TimerHandle, error) {
+	this.log.Debug2("sys.timer.NewBackoffWithPolicy{ policy=%+v userInfo=%v }", policy, userInfo)
+
+	// Check the policy
+	if policy.Multiplier == 0 {
+		policy.Multiplier = BACKOFF_MULTIPLIER
+	}
+	if policy.Duration <= 0 || policy.MaxDuration < policy.Duration {
+		return nil, gopi.ErrBadParameter
+	} else if policy.Multiplier < 1 {
+		return nil, gopi.ErrBadParameter
+	} else if policy.Jitter > gopi.BACKOFF_JITTER_DECORRELATED {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create the backoff with the first delay
+	u := &unit{
+		userInfo: userInfo,
+		backoff:  true,
+		policy:   policy,
+	}
+	this.mutex.Lock()
+	delay := this.restartBackoff(u, policy.Duration)
+	this.mutex.Unlock()
+	if _, err := this.add(u, delay); err != nil {
+		return nil, err
+	}
 
 	// Emit the event immediately
-	this.emit(idx, time.Now())
+	this.emit(u, this.clock.Now())
 
 	// Success
-	return nil
+	return u, nil
+}
+
+// NewSchedule schedules a timer which fires at wall-clock times. The
+// timestamp for each event is the scheduled time
+func (this *timer) NewSchedule(schedule gopi.Schedule, userInfo interface{}) (gopi.TimerHandle, error) {
+	this.log.Debug2("sys.timer.NewSchedule{ schedule=%v userInfo=%v }", schedule, userInfo)
+
+	// Check for schedule
+	if schedule == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Create the timeout for the next time
+	now := this.clock.Now()
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return nil, gopi.ErrBadParameter
+	}
+	return this.add(&unit{
+		userInfo: userInfo,
+		schedule: schedule,
+		next:     next,
+	}, next.Sub(now))
+}
+
+// NewCron schedules a timer which fires at times set by a cron expression
+func (this *timer) NewCron(spec string, userInfo interface{}) (gopi.TimerHandle, error) {
+	if schedule, err := ParseCron(spec, nil); err != nil {
+		return nil, err
+	} else {
+		return this.NewSchedule(schedule, userInfo)
+	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
 func (this *timer) String() string {
-	return fmt.Sprintf("<sys.timer>{ timers=%v }", this.units)
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return fmt.Sprintf("<sys.timer>{ timers=%v jitter=%v coalesce=%v }", len(this.units), this.jitter, this.coalesce)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func (this *timer) emit(idx int, ts time.Time) {
-	if u, ok := this.units[idx]; ok == false {
-		this.log.Warn("sys.timer.emit: Invalid index, %v", idx)
-	} else {
-		// Increment the counter (number of times fired)
-		u.counter = u.counter + 1
-		// Emit the event
-		this.Emit(NewTimerEvent(this, u, ts))
-		// If this is a backoff timeout and the counter is above 1
-		// (it's not the immediate firing) then reset the backoff to double
-		// the current interval, up to a maximum of max_duration
-		if u.max_duration > 0 && u.counter > 1 && u.timer != nil {
-			u.duration *= 2
-			if u.duration > u.max_duration {
-				u.duration = u.max_duration
-			}
-			this.log.Debug2("sys.timer.emit: backoff interval=%v for %v", u.duration, u)
-			u.timer.Reset(u.duration)
-		}
+// add a unit which fires after a duration. The time the unit next
+// fires is set from the duration unless already set
+func (this *timer) add(u *unit, duration time.Duration) (*unit, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.closed {
+		return nil, gopi.ErrOutOfOrder
 	}
+	u.parent = this
+	u.index = -1
+	if u.next.IsZero() {
+		u.next = this.clock.Now().Add(duration)
+	}
+	this.arm(u, u.next)
+
+	// Return the unit
+	return u, nil
 }
 
-func (this *timer) append(c reflect.Value, u *unit) int {
-	// append channel and reload
-	this.channels = append(this.channels, reflect.SelectCase{
-		Dir:  reflect.SelectRecv,
-		Chan: c,
-	})
-	idx := len(this.channels) - 1
-	if u != nil {
-		this.units[idx] = u
-		// send a signal to the zero'th channel
-		this.channels[0].Chan.Send(reflect.ValueOf(gopi.DONE))
+// arm sets the time a unit next fires, adding jitter, and adds the unit
+// to the heap or moves it within the heap. Called with the mutex held
+func (this *timer) arm(u *unit, next time.Time) {
+	u.next = next
+	if this.jitter > 0 {
+		u.jitter = time.Duration(this.rand.Int63n(int64(this.jitter)))
 	}
-	// return the index of the channel
-	return idx
+	u.deadline = next.Add(u.jitter)
+	if u.index < 0 {
+		heap.Push(&this.units, u)
+	} else {
+		heap.Fix(&this.units, u.index)
+	}
+	this.signal()
 }
 
-// wait_for_timers will wait for an event on any channel in the list of
-// channels
-func (this *timer) wait_for_timers(start chan<- event.Signal, stop <-chan event.Signal) error {
-	// Indicate we are now running
-	start <- gopi.DONE
+// disarm removes a unit from the heap. Called with the mutex held
+func (this *timer) disarm(u *unit) {
+	if u.index >= 0 {
+		heap.Remove(&this.units, u.index)
+		this.signal()
+	}
+}
 
-	// Append stop signal but don't reload
-	stop_idx := this.append(reflect.ValueOf(stop), nil)
+// signal that the earliest deadline may have changed, and set the clock
+// timer for the earliest deadline. The clock timer is not set while
+// events are emitted, so only one call to fire happens at once and
+// events are emitted in order. Called with the mutex held
+func (this *timer) signal() {
+	if this.firing {
+		return
+	} else if u := this.units.peek(); u == nil {
+		this.wake.Stop()
+	} else {
+		this.wake.Reset(u.deadline.Sub(this.clock.Now()))
+	}
+}
 
-FOR_LOOP:
-	for {
-		// Wait for reload, stop or a timer maturing
-		chosen, _, ok := reflect.Select(this.channels)
-		// We received a reload signal
-		if chosen == 0 && ok {
-			continue
-		}
-		// We received a stop signal so break out of the loop
-		if chosen == stop_idx {
-			break FOR_LOOP
-		}
-		// Otherwise, assume we received a timer maturing
-		if ok {
-			this.emit(chosen, time.Now())
-		}
+// emit an event for a unit immediately
+func (this *timer) emit(u *unit, ts time.Time) {
+	this.mutex.Lock()
+	u.counter = u.counter + 1
+	u.attempt = u.attempt + 1
+	if u.backoff && u.policy.MaxAttempts > 0 && u.attempt >= u.policy.MaxAttempts {
+		u.done = true
+		this.disarm(u)
 	}
-	return nil
+	evt := NewTimerEvent(this, u, ts)
+	this.mutex.Unlock()
+
+	this.Emit(evt)
 }
 
-////////////////////////////////////////////////////////////////////////////////
-// PRIVATE METHODS
+// fire emits events for units which are due, including units due within
+// the coalescing window, and sets the time each unit next fires. It is
+// called by the clock timer, which is set again when the events have
+// been emitted
+func (this *timer) fire() {
+	this.mutex.Lock()
+	if this.firing {
+		this.mutex.Unlock()
+		return
+	}
+	this.firing = true
+	now := this.clock.Now()
+	limit := now.Add(this.coalesce)
+	events := make([]gopi.Event, 0, 1)
+	for u := this.units.peek(); u != nil && u.deadline.After(limit) == false; u = this.units.peek() {
+		// Increment the counter (number of times fired) and attempt
+		u.counter = u.counter + 1
+		u.attempt = u.attempt + 1
+		ts := now
+		if u.schedule != nil {
+			ts = u.next
+		}
 
-func (this *unit) Cancel() {
-	if this.timer != nil {
-		this.timer.Stop()
+		// Set the time the unit next fires
+		switch {
+		case u.schedule != nil:
+			// Schedule the next time, skipping times which have passed
+			next := u.schedule.Next(u.next)
+			if next.IsZero() == false && next.Before(now) {
+				next = u.schedule.Next(now)
+			}
+			if next.IsZero() {
+				u.done = true
+				this.disarm(u)
+			} else {
+				this.arm(u, next)
+			}
+		case u.interval:
+			// Fire at the next interval, skipping intervals which have passed
+			next := u.next.Add(u.duration)
+			if next.After(now) == false {
+				next = now.Add(u.duration)
+			}
+			this.arm(u, next)
+		case u.backoff:
+			// Fire after the next delay, unless there are no more attempts
+			if u.policy.MaxAttempts > 0 && u.attempt >= u.policy.MaxAttempts {
+				u.done = true
+				this.disarm(u)
+			} else {
+				delay := this.nextBackoff(u)
+				this.log.Debug2("sys.timer.fire: backoff attempt=%v delay=%v for %v", u.attempt, delay, u)
+				this.arm(u, now.Add(delay))
+			}
+		default:
+			// Timeouts fire once
+			u.done = true
+			this.disarm(u)
+		}
+
+		// Create the event, which includes the delay until the unit next fires
+		events = append(events, NewTimerEvent(this, u, ts))
 	}
-	if this.ticker != nil {
-		this.ticker.Stop()
+	this.mutex.Unlock()
+
+	// Emit the events
+	for _, evt := range events {
+		this.Emit(evt)
 	}
-}
 
-func (this *unit) String() string {
-	return fmt.Sprintf("<sys.timer>{ userInfo=%v }", this.userInfo)
+	// Set the clock timer for the earliest deadline
+	this.mutex.Lock()
+	this.firing = false
+	if this.closed == false {
+		this.signal()
+	}
+	this.mutex.Unlock()
 }
@@ -0,0 +1,481 @@
+package timer_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	timer "github.com/djthorpe/gopi/sys/timer"
+	fakeclock "github.com/djthorpe/gopi/util/clock"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestTimer_000(t *testing.T) {
+	// Timeouts fire once, and are removed when they have fired
+	timer_ := openTimer(t)
+	defer timer_.Close()
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	handles := make([]gopi.TimerHandle, 0, 100)
+	for i := 0; i < 100; i++ {
+		if handle, err := timer_.NewTimeout(time.Millisecond, i); err != nil {
+			t.Fatal(err)
+		} else if handle.Next().IsZero() {
+			t.Error("Expected next time")
+		} else {
+			handles = append(handles, handle)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if evt := waitForEvent(t, events, time.Second); evt.Counter() != 1 {
+			t.Error("Unexpected event", evt)
+		}
+	}
+	for _, handle := range handles {
+		if handle.Next().IsZero() == false {
+			t.Error("Expected zero next time")
+		} else if err := handle.Reset(time.Second); err != gopi.ErrOutOfOrder {
+			t.Error("Expected ErrOutOfOrder, got", err)
+		}
+	}
+	if str := timer_.(interface{ String() string }).String(); strings.Contains(str, "timers=0") == false {
+		t.Error("Expected no timers", str)
+	}
+}
+
+func TestTimer_001(t *testing.T) {
+	// Cancel a timeout and a backoff before they fire
+	timer_ := openTimer(t)
+	defer timer_.Close()
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	timeout, err := timer_.NewTimeout(20*time.Millisecond, "timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout.Cancel()
+	done := make(chan gopi.TimerHandle)
+	go func() {
+		backoff, err := timer_.NewBackoff(20*time.Millisecond, time.Second, "backoff")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- backoff
+	}()
+	if evt := waitForEvent(t, events, time.Second); evt.UserInfo() != "backoff" || evt.Counter() != 1 {
+		t.Error("Unexpected event", evt)
+	}
+	backoff := <-done
+	backoff.Cancel()
+	backoff.Cancel()
+	if timeout.Next().IsZero() == false || backoff.Next().IsZero() == false {
+		t.Error("Expected zero next time")
+	}
+	select {
+	case evt := <-events:
+		t.Error("Unexpected event", evt)
+	case <-time.After(100 * time.Millisecond):
+		break
+	}
+}
+
+func TestTimer_002(t *testing.T) {
+	// Pause and resume a timeout
+	timer_ := openTimer(t)
+	defer timer_.Close()
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	handle, err := timer_.NewTimeout(50*time.Millisecond, "timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle.Pause()
+	if handle.Next().IsZero() == false {
+		t.Error("Expected zero next time when paused")
+	}
+	select {
+	case evt := <-events:
+		t.Error("Unexpected event", evt)
+	case <-time.After(100 * time.Millisecond):
+		break
+	}
+
+	// The remaining time is kept
+	handle.Resume()
+	if next := handle.Next(); next.After(time.Now().Add(50*time.Millisecond)) || next.Before(time.Now()) {
+		t.Error("Unexpected next time", next)
+	}
+	if evt := waitForEvent(t, events, time.Second); evt.UserInfo() != "timeout" {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+func TestTimer_003(t *testing.T) {
+	// Reset an interval
+	timer_ := openTimer(t)
+	defer timer_.Close()
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	handle, err := timer_.NewInterval(time.Hour, "interval", false)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := handle.Reset(0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := handle.Reset(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := uint(1); i <= 3; i++ {
+		if evt := waitForEvent(t, events, time.Second); evt.Counter() != i {
+			t.Error("Unexpected event", evt)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Error("Unexpected elapsed time", elapsed)
+	}
+	if next := handle.Next(); next.IsZero() || next.After(time.Now().Add(20*time.Millisecond)) {
+		t.Error("Unexpected next time", next)
+	}
+	handle.Cancel()
+	if str := timer_.(interface{ String() string }).String(); strings.Contains(str, "timers=0") == false {
+		t.Error("Expected no timers", str)
+	}
+}
+
+func TestTimer_004(t *testing.T) {
+	// Pause and resume a schedule, which fires at the next time
+	clock := newClock(time.Date(2019, 6, 1, 6, 29, 59, 900000000, time.UTC))
+	driver, err := gopi.Open(timer.Timer{Clock: clock}, openLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+
+	schedule, _ := timer.ParseCron("30 6 * * *", time.UTC)
+	handle, err := timer_.NewSchedule(schedule, "daily")
+	if err != nil {
+		t.Fatal(err)
+	} else if next := handle.Next(); next.Equal(time.Date(2019, 6, 1, 6, 30, 0, 0, time.UTC)) == false {
+		t.Error("Unexpected next time", next)
+	}
+	handle.Pause()
+	time.Sleep(200 * time.Millisecond)
+	handle.Resume()
+	if next := handle.Next(); next.Equal(time.Date(2019, 6, 2, 6, 30, 0, 0, time.UTC)) == false {
+		t.Error("Unexpected next time", next)
+	}
+}
+
+func TestTimer_005(t *testing.T) {
+	// Jitter delays timers by up to the jitter duration
+	driver, err := gopi.Open(timer.Timer{Jitter: 50 * time.Millisecond}, openLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if handle, err := timer_.NewTimeout(10*time.Millisecond, i); err != nil {
+			t.Fatal(err)
+		} else if next := handle.Next(); next.Before(start.Add(10*time.Millisecond)) || next.After(time.Now().Add(60*time.Millisecond)) {
+			t.Error("Unexpected next time", next)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		waitForEvent(t, events, time.Second)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Error("Unexpected elapsed time", elapsed)
+	}
+
+	// Negative durations are not allowed
+	if _, err := gopi.Open(timer.Timer{Jitter: -time.Second}, openLogger(t)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestTimer_006(t *testing.T) {
+	// Timers due within the coalescing window fire together
+	driver, err := gopi.Open(timer.Timer{Coalesce: 100 * time.Millisecond}, openLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	start := time.Now()
+	if _, err := timer_.NewTimeout(20*time.Millisecond, "first"); err != nil {
+		t.Fatal(err)
+	} else if _, err := timer_.NewTimeout(80*time.Millisecond, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if evt := waitForEvent(t, events, time.Second); evt.UserInfo() != "first" {
+		t.Error("Unexpected event", evt)
+	}
+	if evt := waitForEvent(t, events, time.Second); evt.UserInfo() != "second" {
+		t.Error("Unexpected event", evt)
+	}
+	if elapsed := time.Since(start); elapsed > 70*time.Millisecond {
+		t.Error("Expected timers to fire together, elapsed time", elapsed)
+	}
+}
+
+func TestTimer_007(t *testing.T) {
+	// Advancing a fake clock fires events synchronously
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := fakeclock.NewFake(start)
+	driver, err := gopi.Open(timer.Timer{Clock: fake}, openLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	timer_ := driver.(gopi.Timer)
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	if _, err := timer_.NewInterval(time.Second, "interval", false); err != nil {
+		t.Fatal(err)
+	} else if _, err := timer_.NewTimeout(1500*time.Millisecond, "timeout"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		fake.Advance(3 * time.Second)
+		close(done)
+	}()
+	expected := []struct {
+		userInfo string
+		ts       time.Duration
+	}{
+		{"interval", time.Second},
+		{"timeout", 1500 * time.Millisecond},
+		{"interval", 2 * time.Second},
+		{"interval", 3 * time.Second},
+	}
+	for _, expected := range expected {
+		if evt := waitForEvent(t, events, time.Second); evt.UserInfo() != expected.userInfo || evt.Timestamp().Equal(start.Add(expected.ts)) == false {
+			t.Error("Unexpected event", evt, evt.Timestamp())
+		}
+	}
+	<-done
+	select {
+	case evt := <-events:
+		t.Error("Unexpected event", evt)
+	default:
+		break
+	}
+}
+
+func TestTimer_008(t *testing.T) {
+	// Backoff with a multiplier and maximum number of attempts
+	fake := fakeclock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer_, events := openFakeTimer(t, fake)
+	defer timer_.Close()
+	defer timer_.Unsubscribe(events)
+
+	handle, evt := newBackoff(t, timer_, events, gopi.BackoffPolicy{
+		Duration:    time.Second,
+		MaxDuration: 5 * time.Second,
+		Multiplier:  3,
+		MaxAttempts: 4,
+	})
+	if evt.Attempt() != 1 || evt.Delay() != time.Second {
+		t.Error("Unexpected event", evt)
+	}
+	done := advance(fake, time.Minute)
+	for i, delay := range []time.Duration{3 * time.Second, 5 * time.Second, 0} {
+		if evt := waitForEvent(t, events, time.Second); evt.Attempt() != uint(i+2) || evt.Delay() != delay {
+			t.Error("Unexpected event", evt)
+		}
+	}
+	<-done
+	if handle.Next().IsZero() == false {
+		t.Error("Expected no more attempts")
+	} else if err := handle.Reset(0); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	}
+
+	// Bad policies
+	for _, policy := range []gopi.BackoffPolicy{
+		{Duration: 0, MaxDuration: time.Second},
+		{Duration: time.Second, MaxDuration: time.Millisecond},
+		{Duration: time.Second, MaxDuration: time.Minute, Multiplier: 0.5},
+		{Duration: time.Second, MaxDuration: time.Minute, Jitter: gopi.BackoffJitter(100)},
+	} {
+		if _, err := timer_.NewBackoffWithPolicy(policy, nil); err != gopi.ErrBadParameter {
+			t.Error("Expected ErrBadParameter, got", err)
+		}
+	}
+}
+
+func TestTimer_009(t *testing.T) {
+	// Reset a backoff after success, which starts the attempts again
+	fake := fakeclock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer_, events := openFakeTimer(t, fake)
+	defer timer_.Close()
+	defer timer_.Unsubscribe(events)
+
+	handle, _ := newBackoff(t, timer_, events, gopi.BackoffPolicy{
+		Duration:    time.Second,
+		MaxDuration: time.Minute,
+	})
+	done := advance(fake, 3*time.Second)
+	for _, attempt := range []uint{2, 3} {
+		if evt := waitForEvent(t, events, time.Second); evt.Attempt() != attempt || evt.Counter() != attempt {
+			t.Error("Unexpected event", evt)
+		}
+	}
+	<-done
+	if err := handle.Reset(0); err != nil {
+		t.Fatal(err)
+	} else if next := handle.Next(); next.Equal(fake.Now().Add(time.Second)) == false {
+		t.Error("Unexpected next time", next)
+	}
+	done = advance(fake, time.Second)
+	if evt := waitForEvent(t, events, time.Second); evt.Attempt() != 1 || evt.Counter() != 4 || evt.Delay() != 2*time.Second {
+		t.Error("Unexpected event", evt)
+	}
+	<-done
+}
+
+func TestTimer_010(t *testing.T) {
+	// Full and decorrelated jitter
+	fake := fakeclock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer_, events := openFakeTimer(t, fake)
+	defer timer_.Close()
+	defer timer_.Unsubscribe(events)
+
+	for _, jitter := range []gopi.BackoffJitter{gopi.BACKOFF_JITTER_FULL, gopi.BACKOFF_JITTER_DECORRELATED} {
+		policy := gopi.BackoffPolicy{
+			Duration:    time.Second,
+			MaxDuration: 10 * time.Second,
+			Jitter:      jitter,
+			MaxAttempts: 20,
+		}
+		_, evt := newBackoff(t, timer_, events, policy)
+		done := advance(fake, time.Hour)
+		for evt.Delay() != 0 {
+			min := time.Duration(0)
+			if jitter == gopi.BACKOFF_JITTER_DECORRELATED {
+				min = policy.Duration
+			}
+			if evt.Delay() < min || evt.Delay() > policy.MaxDuration {
+				t.Error(jitter, "Unexpected delay", evt)
+			}
+			evt = waitForEvent(t, events, time.Second)
+		}
+		if evt.Attempt() != policy.MaxAttempts {
+			t.Error(jitter, "Unexpected attempt", evt)
+		}
+		<-done
+	}
+}
+
+func TestTimer_011(t *testing.T) {
+	// A slow subscriber receives events in order, without the timer
+	// starting more goroutines
+	timer_ := openTimer(t)
+	defer timer_.Close()
+	events := timer_.Subscribe()
+	defer timer_.Unsubscribe(events)
+
+	goroutines := runtime.NumGoroutine()
+	handle, err := timer_.NewInterval(time.Millisecond, "interval", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint(1); i <= 30; i++ {
+		if evt := waitForEvent(t, events, time.Second); evt.Counter() != i {
+			t.Error("Unexpected event", evt)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if n := runtime.NumGoroutine(); n > goroutines+5 {
+			t.Fatal("Unexpected number of goroutines", n)
+		}
+	}
+
+	// Receive any event being emitted when cancelled
+	handle.Cancel()
+	select {
+	case <-events:
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// advance a fake clock in the background, since events are emitted
+// before returning, and return a channel which is closed on return
+func advance(fake *fakeclock.Fake, duration time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		fake.Advance(duration)
+		close(done)
+	}()
+	return done
+}
+
+func openFakeTimer(t *testing.T, clock gopi.Clock) (gopi.Timer, <-chan gopi.Event) {
+	t.Helper()
+	if driver, err := gopi.Open(timer.Timer{Clock: clock}, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil, nil
+	} else {
+		timer_ := driver.(gopi.Timer)
+		return timer_, timer_.Subscribe()
+	}
+}
+
+// newBackoff creates a backoff in the background, since the first
+// event is emitted before returning, and returns the first event
+func newBackoff(t *testing.T, timer_ gopi.Timer, events <-chan gopi.Event, policy gopi.BackoffPolicy) (gopi.TimerHandle, gopi.TimerEvent) {
+	t.Helper()
+	done := make(chan gopi.TimerHandle)
+	go func() {
+		handle, err := timer_.NewBackoffWithPolicy(policy, "backoff")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- handle
+	}()
+	evt := waitForEvent(t, events, time.Second)
+	return <-done, evt
+}
+
+func openTimer(t *testing.T) gopi.Timer {
+	t.Helper()
+	if driver, err := gopi.Open(timer.Timer{}, openLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.Timer)
+	}
+}
+
+func waitForEvent(t *testing.T, events <-chan gopi.Event, timeout time.Duration) gopi.TimerEvent {
+	t.Helper()
+	select {
+	case evt := <-events:
+		return evt.(gopi.TimerEvent)
+	case <-time.After(timeout):
+		t.Fatal("Timeout waiting for event")
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation https://gopi.mutablelogic.com/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package timer
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// unit is a timeout, interval, backoff or schedule, which is returned
+// as a handle. The state is protected by the timer mutex
+type unit struct {
+	parent    *timer
+	userInfo  interface{}
+	counter   uint
+	attempt   uint
+	duration  time.Duration
+	interval  bool
+	backoff   bool
+	policy    gopi.BackoffPolicy
+	delay     time.Duration
+	schedule  gopi.Schedule
+	next      time.Time
+	deadline  time.Time
+	jitter    time.Duration
+	index     int
+	remaining time.Duration
+	paused    bool
+	done      bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - HANDLE
+
+// Cancel the timer, and remove it from the timers
+func (this *unit) Cancel() {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+	if this.done == false {
+		this.done = true
+		this.parent.disarm(this)
+	}
+}
+
+// Reset the timer to fire after a duration
+func (this *unit) Reset(duration time.Duration) error {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+
+	if duration == 0 && this.backoff {
+		duration = this.policy.Duration
+	}
+	if duration <= 0 {
+		return gopi.ErrBadParameter
+	} else if this.done {
+		return gopi.ErrOutOfOrder
+	}
+	if this.interval {
+		this.duration = duration
+	} else if this.backoff {
+		this.attempt = 0
+		duration = this.parent.restartBackoff(this, duration)
+	}
+	if this.paused {
+		this.next = this.parent.clock.Now().Add(duration)
+		this.remaining = duration
+	} else {
+		this.parent.arm(this, this.parent.clock.Now().Add(duration))
+	}
+
+	// Success
+	return nil
+}
+
+// Pause the timer
+func (this *unit) Pause() {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+	if this.done || this.paused {
+		return
+	}
+	this.paused = true
+	this.parent.disarm(this)
+	if this.remaining = this.next.Sub(this.parent.clock.Now()); this.remaining < 0 {
+		this.remaining = 0
+	}
+}
+
+// Resume a paused timer
+func (this *unit) Resume() {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+	if this.done || this.paused == false {
+		return
+	}
+	this.paused = false
+	now := this.parent.clock.Now()
+	if this.parent.closed {
+		this.done = true
+	} else if this.schedule == nil {
+		this.parent.arm(this, now.Add(this.remaining))
+	} else if next := this.schedule.Next(now); next.IsZero() {
+		this.done = true
+	} else {
+		this.parent.arm(this, next)
+	}
+}
+
+// Next returns the time the timer fires next, including any jitter
+func (this *unit) Next() time.Time {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+	if this.done || this.paused {
+		return time.Time{}
+	} else {
+		return this.next.Add(this.jitter)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *unit) String() string {
+	return fmt.Sprintf("<sys.timer>{ userInfo=%v }", this.userInfo)
+}
@@ -21,11 +21,102 @@ type Timer interface {
 	Publisher
 
 	// Schedule a timeout (one shot)
-	NewTimeout(duration time.Duration, userInfo interface{}) error
+	NewTimeout(duration time.Duration, userInfo interface{}) (TimerHandle, error)
 
 	// Schedule an interval, which can fire immediately
-	NewInterval(duration time.Duration, userInfo interface{}, immediately bool) error
+	NewInterval(duration time.Duration, userInfo interface{}, immediately bool) (TimerHandle, error)
 
 	// Schedule a backoff timer with maximum backoff duration
-	NewBackoff(duration time.Duration, max_duration time.Duration, userInfo interface{}) error
+	NewBackoff(duration time.Duration, max_duration time.Duration, userInfo interface{}) (TimerHandle, error)
+
+	// Schedule a backoff timer with a policy for the multiplier, jitter
+	// and maximum number of attempts
+	NewBackoffWithPolicy(policy BackoffPolicy, userInfo interface{}) (TimerHandle, error)
+
+	// Schedule a timer which fires at the times returned by a schedule
+	NewSchedule(schedule Schedule, userInfo interface{}) (TimerHandle, error)
+
+	// Schedule a timer which fires at the times set by a cron expression
+	// such as "30 6 * * *", in the local time zone unless the expression
+	// is prefixed with a time zone such as "TZ=Europe/London"
+	NewCron(spec string, userInfo interface{}) (TimerHandle, error)
+}
+
+// TimerHandle controls a scheduled timer
+type TimerHandle interface {
+	// Cancel the timer, which then does not fire again
+	Cancel()
+
+	// Reset the timer to fire after a duration. For intervals this
+	// also sets the interval, and for backoff timers the backoff and
+	// attempts start again from the duration, or from the initial
+	// duration when zero, which is usually done after success
+	Reset(duration time.Duration) error
+
+	// Pause the timer, which does not fire until resumed
+	Pause()
+
+	// Resume a paused timer. The remaining time when paused is kept,
+	// except for schedules which fire at the next scheduled time
+	Resume()
+
+	// Next returns the time the timer fires next, or the zero time if
+	// the timer is paused, cancelled or has ended
+	Next() time.Time
+}
+
+// BackoffPolicy sets the delays between attempts for a backoff timer.
+// The first attempt fires immediately, and each delay after the first
+// is the previous one times the multiplier, up to the maximum duration
+type BackoffPolicy struct {
+	Duration    time.Duration // Initial delay
+	MaxDuration time.Duration // Maximum delay
+	Multiplier  float64       // Multiplier for each delay (default: 2)
+	Jitter      BackoffJitter // Randomization of delays (default: none)
+	MaxAttempts uint          // Maximum number of attempts, or zero for no maximum
+}
+
+// BackoffJitter randomizes backoff delays, so that timers created at
+// the same time don't fire in lockstep
+type BackoffJitter uint
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Delays are not randomized
+	BACKOFF_JITTER_NONE BackoffJitter = iota
+
+	// Delays are between zero and the delay without jitter
+	BACKOFF_JITTER_FULL
+
+	// Delays are between the initial duration and the previous delay
+	// times the multiplier
+	BACKOFF_JITTER_DECORRELATED
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// Schedule returns wall-clock times for a timer
+type Schedule interface {
+	// Next returns the first time after t, or the zero time
+	// if there are no more times
+	Next(t time.Time) time.Time
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (j BackoffJitter) String() string {
+	switch j {
+	case BACKOFF_JITTER_NONE:
+		return "BACKOFF_JITTER_NONE"
+	case BACKOFF_JITTER_FULL:
+		return "BACKOFF_JITTER_FULL"
+	case BACKOFF_JITTER_DECORRELATED:
+		return "BACKOFF_JITTER_DECORRELATED"
+	default:
+		return "[?? Invalid BackoffJitter value]"
+	}
 }
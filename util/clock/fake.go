@@ -0,0 +1,157 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Fake is a clock for tests, which only moves forward when advanced.
+// Functions are called synchronously by Advance when they are due
+type Fake struct {
+	now    time.Time
+	timers []*fakeTimer
+	mutex  sync.Mutex
+}
+
+type fakeTimer struct {
+	parent *Fake
+	when   time.Time
+	f      func()
+	active bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// NEW
+
+// NewFake returns a fake clock set to a time
+func NewFake(now time.Time) *Fake {
+	this := new(Fake)
+	this.now = now
+	this.timers = make([]*fakeTimer, 0)
+	return this
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - CLOCK
+
+// Now returns the time of the fake clock
+func (this *Fake) Now() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.now
+}
+
+// AfterFunc calls a function when the clock is advanced by a duration
+func (this *Fake) AfterFunc(duration time.Duration, f func()) gopi.ClockTimer {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	timer := &fakeTimer{this, this.now.Add(duration), f, true}
+	this.timers = append(this.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward by a duration, calling functions
+// which are due in time order. The clock is set to the time each function
+// is due before it is called, and functions are called on the goroutine
+// which advances the clock
+func (this *Fake) Advance(duration time.Duration) {
+	this.mutex.Lock()
+	until := this.now.Add(duration)
+	this.mutex.Unlock()
+
+	for {
+		this.mutex.Lock()
+		timer := this.due(until)
+		if timer == nil {
+			this.now = until
+			this.mutex.Unlock()
+			return
+		}
+		if timer.when.After(this.now) {
+			this.now = timer.when
+		}
+		this.remove(timer)
+		this.mutex.Unlock()
+
+		// Call the function without the lock, so it can use the clock
+		timer.f()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - CLOCK TIMER
+
+func (this *fakeTimer) Stop() bool {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+	active := this.active
+	this.parent.remove(this)
+	return active
+}
+
+func (this *fakeTimer) Reset(duration time.Duration) bool {
+	this.parent.mutex.Lock()
+	defer this.parent.mutex.Unlock()
+	active := this.active
+	if active == false {
+		this.active = true
+		this.parent.timers = append(this.parent.timers, this)
+	}
+	this.when = this.parent.now.Add(duration)
+	return active
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *Fake) String() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return fmt.Sprintf("<clock.Fake>{ now=%v }", this.now.Format(time.RFC3339Nano))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// due returns the timer which is due earliest, and no later than
+// a time, or nil. Called with the mutex held
+func (this *Fake) due(until time.Time) *fakeTimer {
+	var due *fakeTimer
+	for _, timer := range this.timers {
+		if timer.when.After(until) {
+			continue
+		} else if due == nil || timer.when.Before(due.when) {
+			due = timer
+		}
+	}
+	return due
+}
+
+// remove a timer when it is stopped or called. Called with the mutex held
+func (this *Fake) remove(timer *fakeTimer) {
+	if timer.active {
+		timer.active = false
+		for i, other := range this.timers {
+			if other == timer {
+				this.timers = append(this.timers[:i], this.timers[i+1:]...)
+				break
+			}
+		}
+	}
+}
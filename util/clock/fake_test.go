@@ -0,0 +1,82 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi/util/clock"
+)
+
+func TestFake_000(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	if fake.Now().Equal(start) == false {
+		t.Error("Unexpected time", fake.Now())
+	}
+	fake.Advance(time.Minute)
+	if fake.Now().Equal(start.Add(time.Minute)) == false {
+		t.Error("Unexpected time", fake.Now())
+	}
+}
+
+func TestFake_001(t *testing.T) {
+	// Functions are called in time order, with the clock set to the
+	// time each function is due
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	calls := make([]time.Duration, 0)
+	for _, duration := range []time.Duration{3 * time.Second, time.Second, 2 * time.Second} {
+		fake.AfterFunc(duration, func() {
+			calls = append(calls, fake.Now().Sub(start))
+		})
+	}
+	fake.Advance(2 * time.Second)
+	if len(calls) != 2 || calls[0] != time.Second || calls[1] != 2*time.Second {
+		t.Error("Unexpected calls", calls)
+	}
+	fake.Advance(time.Second)
+	if len(calls) != 3 || calls[2] != 3*time.Second {
+		t.Error("Unexpected calls", calls)
+	}
+}
+
+func TestFake_002(t *testing.T) {
+	// Stop and reset
+	fake := clock.NewFake(time.Now())
+	calls := 0
+	timer := fake.AfterFunc(time.Second, func() {
+		calls++
+	})
+	if timer.Stop() == false {
+		t.Error("Expected Stop to return true")
+	} else if timer.Stop() {
+		t.Error("Expected Stop to return false")
+	}
+	fake.Advance(time.Minute)
+	if calls != 0 {
+		t.Error("Unexpected calls", calls)
+	}
+	if timer.Reset(time.Second) {
+		t.Error("Expected Reset to return false")
+	}
+	fake.Advance(time.Second)
+	if calls != 1 {
+		t.Error("Unexpected calls", calls)
+	}
+}
+
+func TestFake_003(t *testing.T) {
+	// A function which resets its timer is called again
+	fake := clock.NewFake(time.Now())
+	calls := 0
+	var timer interface{ Reset(time.Duration) bool }
+	timer = fake.AfterFunc(time.Second, func() {
+		calls++
+		timer.Reset(time.Second)
+	})
+	fake.Advance(10 * time.Second)
+	if calls != 10 {
+		t.Error("Unexpected calls", calls)
+	}
+}
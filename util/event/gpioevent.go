@@ -0,0 +1,61 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2019
+	All Rights Reserved
+
+	Documentation https://gopi.mutablelogic.com/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package event
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type gpioEvent struct {
+	source    gopi.Driver
+	pin       gopi.GPIOPin
+	edge      gopi.GPIOEdge
+	timestamp time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENT INTERFACE
+
+// NewGPIOEvent returns an event for an edge on a GPIO pin, which is
+// emitted by GPIO drivers
+func NewGPIOEvent(source gopi.GPIO, pin gopi.GPIOPin, edge gopi.GPIOEdge, ts time.Time) gopi.GPIOEvent {
+	return &gpioEvent{source, pin, edge, ts}
+}
+
+func (this *gpioEvent) Name() string {
+	return "GPIOEvent"
+}
+
+func (this *gpioEvent) Source() gopi.Driver {
+	return this.source
+}
+
+func (this *gpioEvent) Pin() gopi.GPIOPin {
+	return this.pin
+}
+
+func (this *gpioEvent) Edge() gopi.GPIOEdge {
+	return this.edge
+}
+
+func (this *gpioEvent) Timestamp() time.Time {
+	return this.timestamp
+}
+
+func (this *gpioEvent) String() string {
+	return fmt.Sprintf("<gopi.GPIOEvent>{ pin=%v edge=%v ts=%v }", this.pin, this.edge, this.timestamp.Format(time.StampMicro))
+}
@@ -9,67 +9,181 @@ import (
 
 type Publisher struct {
 	sync.Mutex
-	channels []chan gopi.Event
+	subscribers []*subscriber
+
+	// emit serializes emitting events, so events are received in order
+	emit sync.Mutex
+}
+
+// subscriber is a channel with options and a count of dropped events.
+// Blocking sends happen without the mutex held, and end when done is
+// closed. A subscriber is disconnected when the channel is closed on
+// overflow, and the count of dropped events is kept until unsubscribed
+type subscriber struct {
+	channel      chan gopi.Event
+	options      gopi.SubscribeOptions
+	dropped      uint
+	disconnected bool
+	done         chan struct{}
+	wait         sync.WaitGroup
 }
 
 // Subscribe returns a new channel on which emitting events can occur
 func (this *Publisher) Subscribe() <-chan gopi.Event {
+	return this.subscribe(gopi.SubscribeOptions{})
+}
+
+// SubscribeWithOptions returns a new channel with a buffer size, and
+// a policy for when the buffer is full. Returns ErrBadParameter if the
+// overflow policy is invalid
+func (this *Publisher) SubscribeWithOptions(options gopi.SubscribeOptions) (<-chan gopi.Event, error) {
+	if options.Overflow > gopi.SUBSCRIBE_OVERFLOW_DISCONNECT {
+		return nil, gopi.ErrBadParameter
+	}
+	return this.subscribe(options), nil
+}
+
+// subscribe returns a new channel for valid options
+func (this *Publisher) subscribe(options gopi.SubscribeOptions) <-chan gopi.Event {
 	this.Lock()
 	defer this.Unlock()
 
-	// Create channels with a capacity of one
-	if this.channels == nil {
-		this.channels = make([]chan gopi.Event, 0, 1)
-	}
 	// Return a new channel
-	channel := make(chan gopi.Event)
-	this.channels = append(this.channels, channel)
-	return channel
+	subscriber := &subscriber{
+		channel: make(chan gopi.Event, options.Buffer),
+		options: options,
+		done:    make(chan struct{}),
+	}
+	this.subscribers = append(this.subscribers, subscriber)
+	return subscriber.channel
 }
 
 // Unsubscribe closes a channel and removes it from the list
-// of channels which emitting can happen on
+// of channels which emitting can happen on. An event being emitted
+// to the channel is abandoned
 func (this *Publisher) Unsubscribe(subscriber <-chan gopi.Event) {
+	this.Lock()
+	for i, other := range this.subscribers {
+		if other.channel == subscriber {
+			this.remove(i)
+			this.Unlock()
+			other.close()
+			return
+		}
+	}
+	this.Unlock()
+}
+
+// Dropped returns the number of events dropped for a subscriber,
+// or zero if the channel is not subscribed. The number is kept for
+// disconnected subscribers until they are unsubscribed
+func (this *Publisher) Dropped(subscriber <-chan gopi.Event) uint {
 	this.Lock()
 	defer this.Unlock()
 
-	if this.channels != nil {
-		for i := range this.channels {
-			if this.channels[i] == subscriber {
-				close(this.channels[i])
-				this.channels[i] = nil
-			}
+	for _, other := range this.subscribers {
+		if other.channel == subscriber {
+			return other.dropped
 		}
 	}
+	return 0
 }
 
-// Close will unsubscribe all remaining channels
+// Close will unsubscribe all remaining channels, and abandon any
+// events being emitted
 func (this *Publisher) Close() {
 	this.Lock()
-	defer this.Unlock()
+	subscribers := this.subscribers
+	this.subscribers = nil
+	this.Unlock()
 
-	if this.channels != nil {
-		for _, subscriber := range this.channels {
-			if subscriber != nil {
-				close(subscriber)
-			}
-		}
-		this.channels = nil
+	for _, subscriber := range subscribers {
+		subscriber.close()
 	}
 }
 
-// Emit an event onto all subscriber channels, this method
-// will block if the subscribers are not processing incoming
-// events
+// Emit an event onto all subscriber channels. This method will block
+// if subscribers with the SUBSCRIBE_OVERFLOW_BLOCK policy are not
+// processing incoming events, and otherwise events are dropped or
+// subscribers disconnected when their buffer is full. Events are
+// sent to other subscribers before blocking
 func (this *Publisher) Emit(evt gopi.Event) {
-	this.Lock()
-	defer this.Unlock()
+	this.emit.Lock()
+	defer this.emit.Unlock()
 
-	if this.channels != nil {
-		for _, channel := range this.channels {
-			if channel != nil {
-				channel <- evt
+	// Send to subscribers which don't block
+	this.Lock()
+	blocking := make([]*subscriber, 0, len(this.subscribers))
+	for _, subscriber := range this.subscribers {
+		if subscriber.disconnected {
+			continue
+		}
+		switch subscriber.options.Overflow {
+		case gopi.SUBSCRIBE_OVERFLOW_BLOCK:
+			subscriber.wait.Add(1)
+			blocking = append(blocking, subscriber)
+		case gopi.SUBSCRIBE_OVERFLOW_DROP_NEWEST:
+			if subscriber.send(evt) == false {
+				subscriber.dropped++
+			}
+		case gopi.SUBSCRIBE_OVERFLOW_DROP_OLDEST:
+			if subscriber.send(evt) {
+				break
 			}
+			// Drop the oldest event to make space, or else drop
+			// this event when there is no buffer
+			select {
+			case <-subscriber.channel:
+				subscriber.dropped++
+				if subscriber.send(evt) == false {
+					subscriber.dropped++
+				}
+			default:
+				subscriber.dropped++
+			}
+		case gopi.SUBSCRIBE_OVERFLOW_DISCONNECT:
+			if subscriber.send(evt) == false {
+				subscriber.dropped++
+				subscriber.disconnected = true
+				close(subscriber.channel)
+			}
+		}
+	}
+	this.Unlock()
+
+	// Send to subscribers which block without the mutex held, so
+	// subscribers can be added and removed while blocked
+	for _, subscriber := range blocking {
+		select {
+		case subscriber.channel <- evt:
+		case <-subscriber.done:
 		}
+		subscriber.wait.Done()
+	}
+}
+
+// remove a subscriber. Called with the mutex held
+func (this *Publisher) remove(i int) {
+	this.subscribers = append(this.subscribers[:i], this.subscribers[i+1:]...)
+}
+
+// close a subscriber which has been removed, after waiting for
+// blocking sends to end
+func (this *subscriber) close() {
+	close(this.done)
+	this.wait.Wait()
+	if this.disconnected == false {
+		close(this.channel)
+	}
+}
+
+// send an event without blocking, and return false if the
+// subscriber buffer is full
+func (this *subscriber) send(evt gopi.Event) bool {
+	select {
+	case this.channel <- evt:
+		return true
+	default:
+		return false
 	}
 }
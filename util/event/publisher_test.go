@@ -2,8 +2,10 @@ package event_test
 
 import (
 	"testing"
+	"time"
 
 	// Frameworks
+	"github.com/djthorpe/gopi"
 	"github.com/djthorpe/gopi/util/event"
 )
 
@@ -19,3 +21,162 @@ func TestPublisher_000(t *testing.T) {
 		publisher.Unsubscribe(ch)
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// SUBSCRIBE WITH OPTIONS
+
+func TestPublisher_001(t *testing.T) {
+	// The default subscriber is unbuffered and blocks
+	publisher := &event.Publisher{}
+	defer publisher.Close()
+	ch := publisher.Subscribe()
+	if cap(ch) != 0 {
+		t.Error("Expected unbuffered channel")
+	}
+	go publisher.Emit(event.NullEvent)
+	select {
+	case evt := <-ch:
+		if evt != event.NullEvent {
+			t.Error("Unexpected event", evt)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for event")
+	}
+	if _, err := publisher.SubscribeWithOptions(gopi.SubscribeOptions{Overflow: gopi.SubscribeOverflow(100)}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestPublisher_002(t *testing.T) {
+	// Drop the newest and oldest events, and count the dropped events
+	publisher := &event.Publisher{}
+	defer publisher.Close()
+	newest := subscribe(t, publisher, gopi.SubscribeOptions{Buffer: 2, Overflow: gopi.SUBSCRIBE_OVERFLOW_DROP_NEWEST})
+	oldest := subscribe(t, publisher, gopi.SubscribeOptions{Buffer: 2, Overflow: gopi.SUBSCRIBE_OVERFLOW_DROP_OLDEST})
+	events := []gopi.Event{newEvent("1"), newEvent("2"), newEvent("3"), newEvent("4")}
+	for _, evt := range events {
+		publisher.Emit(evt)
+	}
+	if dropped := publisher.Dropped(newest); dropped != 2 {
+		t.Error("Unexpected dropped", dropped)
+	} else if dropped := publisher.Dropped(oldest); dropped != 2 {
+		t.Error("Unexpected dropped", dropped)
+	}
+	if evt1, evt2 := <-newest, <-newest; evt1 != events[0] || evt2 != events[1] {
+		t.Error("Unexpected events", evt1, evt2)
+	}
+	if evt1, evt2 := <-oldest, <-oldest; evt1 != events[2] || evt2 != events[3] {
+		t.Error("Unexpected events", evt1, evt2)
+	}
+}
+
+func TestPublisher_003(t *testing.T) {
+	// Slow subscribers are disconnected, and don't block others
+	publisher := &event.Publisher{}
+	defer publisher.Close()
+	slow := subscribe(t, publisher, gopi.SubscribeOptions{Buffer: 1, Overflow: gopi.SUBSCRIBE_OVERFLOW_DISCONNECT})
+	other := subscribe(t, publisher, gopi.SubscribeOptions{Buffer: 10, Overflow: gopi.SUBSCRIBE_OVERFLOW_DROP_NEWEST})
+	for i := 0; i < 3; i++ {
+		publisher.Emit(event.NullEvent)
+	}
+	if _, ok := <-slow; ok == false {
+		t.Error("Expected buffered event")
+	} else if _, ok := <-slow; ok {
+		t.Error("Expected closed channel")
+	}
+	if len(other) != 3 || publisher.Dropped(other) != 0 {
+		t.Error("Unexpected events for other subscriber", len(other))
+	}
+
+	// The dropped count is kept for the disconnected channel until unsubscribed
+	if dropped := publisher.Dropped(slow); dropped != 1 {
+		t.Error("Unexpected dropped", dropped)
+	}
+	publisher.Unsubscribe(slow)
+	publisher.Unsubscribe(other)
+	if dropped := publisher.Dropped(slow); dropped != 0 {
+		t.Error("Unexpected dropped", dropped)
+	}
+}
+
+func TestPublisher_004(t *testing.T) {
+	// A subscriber which blocks does not stall other subscribers, and
+	// emitting ends when it is unsubscribed
+	publisher := &event.Publisher{}
+	defer publisher.Close()
+	blocked := publisher.Subscribe()
+	other := subscribe(t, publisher, gopi.SubscribeOptions{Buffer: 1, Overflow: gopi.SUBSCRIBE_OVERFLOW_DROP_NEWEST})
+	done := make(chan struct{})
+	go func() {
+		publisher.Emit(event.NullEvent)
+		close(done)
+	}()
+	select {
+	case <-other:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+	if dropped := publisher.Dropped(other); dropped != 0 {
+		t.Error("Unexpected dropped", dropped)
+	}
+	late := publisher.Subscribe()
+	publisher.Unsubscribe(late)
+	publisher.Unsubscribe(blocked)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for emit")
+	}
+	if _, ok := <-blocked; ok {
+		t.Error("Expected closed channel")
+	}
+}
+
+func TestPublisher_005(t *testing.T) {
+	// Closing ends emitting to a subscriber which never receives
+	publisher := &event.Publisher{}
+	publisher.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		publisher.Emit(event.NullEvent)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	publisher.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for emit")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+type testEvent struct {
+	name string
+}
+
+func newEvent(name string) gopi.Event {
+	return &testEvent{name}
+}
+
+func (this *testEvent) Source() gopi.Driver {
+	return nil
+}
+
+func (this *testEvent) Name() string {
+	return this.name
+}
+
+// subscribe returns a channel with options, and fails if the options
+// are invalid
+func subscribe(t *testing.T, publisher *event.Publisher, options gopi.SubscribeOptions) <-chan gopi.Event {
+	t.Helper()
+	if ch, err := publisher.SubscribeWithOptions(options); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return ch
+	}
+}
@@ -22,7 +22,6 @@ import (
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
-	"github.com/djthorpe/gopi/util/event"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -44,6 +43,13 @@ type Config interface {
 	Indent() bool
 }
 
+// ClockConfig can be implemented by a configuration to set the clock
+// used for delaying writes to disk
+type ClockConfig interface {
+	// Clock returns the clock, or nil for the system clock
+	Clock() gopi.Clock
+}
+
 // File implements filesystem persistence with JSON
 type File struct {
 	log              gopi.Logger
@@ -53,9 +59,11 @@ type File struct {
 	modified         bool
 	data             interface{}
 	indent           bool
+	clock            gopi.Clock
+	timer            gopi.ClockTimer
+	closed           bool
 
 	sync.Mutex
-	event.Tasks
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -87,6 +95,10 @@ func (this *File) Init(config Config, data interface{}, logger gopi.Logger) erro
 	this.log = logger
 	this.data = data
 	this.indent = config.Indent()
+	this.clock = gopi.SystemClock
+	if config_, ok := config.(ClockConfig); ok && config_.Clock() != nil {
+		this.clock = config_.Clock()
+	}
 
 	// Set default filename
 	if filename_default := config.DefaultFilename(); filename_default == "" {
@@ -102,8 +114,11 @@ func (this *File) Init(config Config, data interface{}, logger gopi.Logger) erro
 		}
 	}
 
-	// Start process to write occasionally to disk
-	this.Tasks.Start(this.writeTask)
+	// Write occasionally to disk. The mutex is held so that writeTick
+	// reads the timer after it is set
+	this.Lock()
+	this.timer = this.clock.AfterFunc(100*time.Millisecond, this.writeTick)
+	this.Unlock()
 
 	// Success
 	return nil
@@ -113,9 +128,20 @@ func (this *File) Init(config Config, data interface{}, logger gopi.Logger) erro
 func (this *File) Close() error {
 	this.log.Debug("<persistence.File>Close{ path=%v }", strconv.Quote(this.path))
 
-	// Stop all tasks
-	if err := this.Tasks.Close(); err != nil {
-		return err
+	// Stop writing occasionally. The mutex is held while writing, so this
+	// waits for a write in progress
+	this.Lock()
+	defer this.Unlock()
+	this.closed = true
+	this.timer.Stop()
+
+	// Try and write
+	if this.modified {
+		if this.path == "" {
+			// Do nothing
+		} else if err := this.writePath(this.path); err != nil {
+			this.log.Warn("Write: %v: %v", this.path, err)
+		}
 	}
 
 	// Success
@@ -215,11 +241,10 @@ func (this *File) Reader(fh io.Reader) error {
 ////////////////////////////////////////////////////////////////////////////////
 // WRITE
 
-// writePath writes the configuration file to disk
+// writePath writes the configuration file to disk. Called with the
+// mutex held
 func (this *File) writePath(path string) error {
 	this.log.Debug2("<persistence.File>WritePath{ path=%v }", strconv.Quote(path))
-	this.Lock()
-	defer this.Unlock()
 	if fh, err := os.Create(path); err != nil {
 		return err
 	} else {
@@ -267,30 +292,15 @@ func (this *File) isModified() bool {
 ////////////////////////////////////////////////////////////////////////////////
 // BACKGROUND TASKS
 
-func (this *File) writeTask(start chan<- event.Signal, stop <-chan event.Signal) error {
-	start <- gopi.DONE
-	ticker := time.NewTimer(100 * time.Millisecond)
-FOR_LOOP:
-	for {
-		select {
-		case <-ticker.C:
-			if this.isModified() {
-				if this.path == "" {
-					// Do nothing
-				} else if err := this.writePath(this.path); err != nil {
-					this.log.Warn("Write: %v: %v", this.path, err)
-				}
-			}
-			ticker.Reset(this.write_delta)
-		case <-stop:
-			break FOR_LOOP
-		}
+// writeTick is called by the clock to write to disk when the data has
+// been modified, and is then called again after the write delta. The
+// mutex is held while writing, so the file is not closed during a write
+func (this *File) writeTick() {
+	this.Lock()
+	defer this.Unlock()
+	if this.closed {
+		return
 	}
-
-	// Stop the ticker
-	ticker.Stop()
-
-	// Try and write
 	if this.modified {
 		if this.path == "" {
 			// Do nothing
@@ -298,7 +308,5 @@ FOR_LOOP:
 			this.log.Warn("Write: %v: %v", this.path, err)
 		}
 	}
-
-	// Success
-	return nil
+	this.timer.Reset(this.write_delta)
 }
@@ -2,12 +2,15 @@ package persistence_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/clock"
 	"github.com/djthorpe/gopi/util/persistence"
 
 	// Modules
@@ -40,6 +43,20 @@ func (tester_config) Indent() bool {
 	return true
 }
 
+type clock_config struct {
+	tester_config
+	path  string
+	clock gopi.Clock
+}
+
+func (this clock_config) Path() string {
+	return this.path
+}
+
+func (this clock_config) Clock() gopi.Clock {
+	return this.clock
+}
+
 func NewTester() (*tester, error) {
 	this := new(tester)
 	config := new(tester_config)
@@ -79,3 +96,97 @@ func TestFile_001(t *testing.T) {
 		store.SetModified()
 	}
 }
+
+func TestFile_002(t *testing.T) {
+	// Writes happen after the write delta of a fake clock
+	path, err := ioutil.TempDir("", "persistence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	log, err := gopi.Open(logger.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	fake := clock.NewFake(time.Now())
+	store := new(tester)
+	if err := store.File.Init(clock_config{path: path, clock: fake}, &store.string_data, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// The first write happens after 100ms
+	filename := filepath.Join(path, "tester_config.json")
+	store.Lock()
+	store.string_data = "Hello"
+	store.Unlock()
+	store.SetModified()
+	fake.Advance(100 * time.Millisecond)
+	if data, err := ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "\"Hello\"\n" {
+		t.Error("Unexpected data", string(data))
+	}
+
+	// The next write happens after the write delta
+	store.Lock()
+	store.string_data = "World"
+	store.Unlock()
+	store.SetModified()
+	fake.Advance(time.Second)
+	if data, _ := ioutil.ReadFile(filename); string(data) != "\"Hello\"\n" {
+		t.Error("Unexpected data", string(data))
+	}
+	fake.Advance(4 * time.Second)
+	if data, _ := ioutil.ReadFile(filename); string(data) != "\"World\"\n" {
+		t.Error("Unexpected data", string(data))
+	}
+}
+
+func TestFile_003(t *testing.T) {
+	// Close waits for a write in progress, and then writes no more
+	path, err := ioutil.TempDir("", "persistence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	log, err := gopi.Open(logger.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	fake := clock.NewFake(time.Now())
+	store := new(tester)
+	if err := store.File.Init(clock_config{path: path, clock: fake}, &store.string_data, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(path, "tester_config.json")
+	store.Lock()
+	store.string_data = "Hello"
+	store.Unlock()
+	store.SetModified()
+	done := make(chan struct{})
+	go func() {
+		fake.Advance(100 * time.Millisecond)
+		close(done)
+	}()
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	} else if data, _ := ioutil.ReadFile(filename); string(data) != "\"Hello\"\n" {
+		t.Error("Unexpected data", string(data))
+	}
+	<-done
+
+	store.Lock()
+	store.string_data = "World"
+	store.Unlock()
+	store.SetModified()
+	fake.Advance(10 * time.Second)
+	if data, _ := ioutil.ReadFile(filename); string(data) != "\"Hello\"\n" {
+		t.Error("Unexpected data", string(data))
+	}
+}